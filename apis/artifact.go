@@ -0,0 +1,71 @@
+package apis
+
+import (
+	"io/ioutil"
+
+	"github.com/go-ozzo/ozzo-routing"
+	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/errors"
+	"github.com/quantumew/listener/services"
+	"github.com/quantumew/listener/util"
+)
+
+type (
+	// artifactService specifies the interface for the job service needed by artifactResource.
+	artifactService interface {
+		CreateJobsFromNexus(rs app.RequestScope, payload []byte) (*services.HookResult, error)
+		CreateJobsFromArtifactory(rs app.RequestScope, payload []byte) (*services.HookResult, error)
+	}
+
+	// artifactResource defines the handlers for the webhooks of Maven repository managers.
+	artifactResource struct {
+		service              artifactService
+		nexusSecretList      []string
+		artifactoryTokenList []string
+	}
+)
+
+// ServeArtifactResource sets up the routing of the Nexus and Artifactory webhook endpoints and the corresponding
+// handlers. Nexus deliveries must be signed with one of the given secrets and Artifactory ones must carry one of the
+// given tokens.
+func ServeArtifactResource(rg *routing.RouteGroup, service artifactService, nexusSecretList []string, artifactoryTokenList []string) {
+	r := &artifactResource{service, nexusSecretList, artifactoryTokenList}
+	rg.Post("/hooks/nexus", r.nexus)
+	rg.Post("/hooks/artifactory", r.artifactory)
+}
+
+func (r *artifactResource) nexus(c *routing.Context) error {
+	payload, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := util.VerifySHA1Signature(r.nexusSecretList, c.Request.Header.Get("X-Nexus-Webhook-Signature"), payload); err != nil {
+		return errors.Unauthorized(err.Error())
+	}
+
+	response, err := r.service.CreateJobsFromNexus(app.GetRequestScope(c), payload)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response)
+}
+
+func (r *artifactResource) artifactory(c *routing.Context) error {
+	if err := util.VerifyToken(r.artifactoryTokenList, c.Request.Header.Get("X-JFrog-Event-Auth")); err != nil {
+		return errors.Unauthorized(err.Error())
+	}
+
+	payload, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+
+	response, err := r.service.CreateJobsFromArtifactory(app.GetRequestScope(c), payload)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response)
+}
@@ -0,0 +1,47 @@
+package apis
+
+import (
+	"io/ioutil"
+
+	"github.com/go-ozzo/ozzo-routing"
+	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/services"
+)
+
+type (
+	// gitHubService specifies the interface for the job service needed by gitHubResource.
+	gitHubService interface {
+		CreateJobsFromGitHub(rs app.RequestScope, event string, payload []byte) (*services.HookResult, error)
+	}
+
+	// gitHubResource defines the handlers for GitHub webhook deliveries.
+	gitHubResource struct {
+		service    gitHubService
+		secretList []string
+	}
+)
+
+// ServeGitHubResource sets up the routing of the GitHub webhook endpoint and the corresponding handler.
+// Deliveries must be signed with one of the given secrets.
+func ServeGitHubResource(rg *routing.RouteGroup, service gitHubService, secretList []string) {
+	r := &gitHubResource{service, secretList}
+	rg.Post("/hooks/github", r.create)
+}
+
+func (r *gitHubResource) create(c *routing.Context) error {
+	if err := verifySignedBody(c, "X-Hub-Signature-256", r.secretList); err != nil {
+		return err
+	}
+
+	payload, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+
+	response, err := r.service.CreateJobsFromGitHub(app.GetRequestScope(c), c.Request.Header.Get("X-GitHub-Event"), payload)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response)
+}
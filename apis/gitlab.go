@@ -0,0 +1,49 @@
+package apis
+
+import (
+	"io/ioutil"
+
+	"github.com/go-ozzo/ozzo-routing"
+	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/errors"
+	"github.com/quantumew/listener/services"
+	"github.com/quantumew/listener/util"
+)
+
+type (
+	// gitLabService specifies the interface for the job service needed by gitLabResource.
+	gitLabService interface {
+		CreateJobsFromGitLab(rs app.RequestScope, payload []byte) (*services.HookResult, error)
+	}
+
+	// gitLabResource defines the handlers for GitLab hooks.
+	gitLabResource struct {
+		service   gitLabService
+		tokenList []string
+	}
+)
+
+// ServeGitLabResource sets up the routing of the GitLab hook endpoint and the corresponding handler.
+// Hooks must carry one of the given secret tokens.
+func ServeGitLabResource(rg *routing.RouteGroup, service gitLabService, tokenList []string) {
+	r := &gitLabResource{service, tokenList}
+	rg.Post("/hooks/gitlab", r.create)
+}
+
+func (r *gitLabResource) create(c *routing.Context) error {
+	if err := util.VerifyToken(r.tokenList, c.Request.Header.Get("X-Gitlab-Token")); err != nil {
+		return errors.Unauthorized(err.Error())
+	}
+
+	payload, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+
+	response, err := r.service.CreateJobsFromGitLab(app.GetRequestScope(c), payload)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response)
+}
@@ -0,0 +1,64 @@
+package apis
+
+import (
+	"github.com/go-ozzo/ozzo-routing"
+	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/services"
+)
+
+type (
+	// graphService specifies the interface for the graph service needed by graphResource.
+	graphService interface {
+		Dependents(rs app.RequestScope, pkg string, depth int) ([]services.DependentRepository, error)
+		Dependencies(rs app.RequestScope, name string, depth int) ([]services.DependencyPackage, error)
+		Export(rs app.RequestScope) (*services.GraphExport, error)
+	}
+
+	// graphResource defines the handlers for the dependency graph APIs.
+	graphResource struct {
+		service graphService
+	}
+)
+
+// ServeGraphResource sets up the routing of dependency graph endpoints and the corresponding handlers.
+func ServeGraphResource(rg *routing.RouteGroup, service graphService) {
+	r := &graphResource{service}
+	rg.Get("/graph", r.export)
+	// Scoped npm packages contain a slash.
+	rg.Get("/graph/dependents/<package:.+>", r.dependents)
+	rg.Get("/graph/dependencies/<name>", r.dependencies)
+}
+
+func (r *graphResource) dependents(c *routing.Context) error {
+	response, err := r.service.Dependents(app.GetRequestScope(c), c.Param("package"), parseInt(c.Query("depth"), 0))
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response)
+}
+
+func (r *graphResource) dependencies(c *routing.Context) error {
+	response, err := r.service.Dependencies(app.GetRequestScope(c), c.Param("name"), parseInt(c.Query("depth"), 0))
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response)
+}
+
+func (r *graphResource) export(c *routing.Context) error {
+	response, err := r.service.Export(app.GetRequestScope(c))
+	if err != nil {
+		return err
+	}
+
+	if c.Query("format") != "dot" {
+		return c.Write(response)
+	}
+
+	c.Response.Header().Set("Content-Type", "text/vnd.graphviz; charset=UTF-8")
+	_, err = c.Response.Write([]byte(response.DOT()))
+
+	return err
+}
@@ -0,0 +1,47 @@
+package apis
+
+import (
+	"io/ioutil"
+
+	"github.com/go-ozzo/ozzo-routing"
+	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/services"
+)
+
+type (
+	// hookService specifies the interface for the job service needed by hookResource.
+	hookService interface {
+		CreateJobsFromPayload(rs app.RequestScope, ecosystem string, payload []byte) (*services.HookResult, error)
+	}
+
+	// hookResource defines the handlers for hook deliveries of any ecosystem.
+	hookResource struct {
+		service    hookService
+		secretList []string
+	}
+)
+
+// ServeHookResource sets up the routing of the hook endpoint every ecosystem can be published through and the
+// corresponding handler. Deliveries must be signed with one of the given secrets.
+func ServeHookResource(rg *routing.RouteGroup, service hookService, secretList []string) {
+	r := &hookResource{service, secretList}
+	rg.Post("/hooks/<ecosystem>", r.create)
+}
+
+func (r *hookResource) create(c *routing.Context) error {
+	if err := verifySignedBody(c, "X-Signature-256", r.secretList); err != nil {
+		return err
+	}
+
+	payload, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+
+	response, err := r.service.CreateJobsFromPayload(app.GetRequestScope(c), c.Param("ecosystem"), payload)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response)
+}
@@ -1,20 +1,37 @@
 package apis
 
 import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
 	"github.com/go-ozzo/ozzo-routing"
 	"github.com/quantumew/data-access/models"
 	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/errors"
+	"github.com/quantumew/listener/services"
 )
 
 type (
 	// jobService specifies the interface for the repository service needed by jobResource.
 	jobService interface {
 		Get(rs app.RequestScope, name string) (*models.Job, error)
+		QueryHistory(rs app.RequestScope, name string, offset, limit int) ([]*models.Job, error)
+		CountHistory(rs app.RequestScope, name string) (int64, error)
 		Query(rs app.RequestScope, offset, limit int) ([]*models.Job, error)
 		Count(rs app.RequestScope) (int64, error)
 		Create(rs app.RequestScope, model *models.Job) (*models.Job, error)
-		CreateJobsFromHook(rs app.RequestScope, hook *models.NpmHook) ([]*models.Job, error)
+		CreateJobsFromPayload(rs app.RequestScope, ecosystem string, payload []byte) (*services.HookResult, error)
+		Simulate(rs app.RequestScope, hook *models.NpmHook) (*services.SimulationResult, error)
 		Update(rs app.RequestScope, name string, model *models.Job) (*models.Job, error)
+		Transition(rs app.RequestScope, name string, id int64, to models.State, actor string) (*models.Job, error)
+		Claim(rs app.RequestScope, worker string) (*models.Job, error)
+		Heartbeat(rs app.RequestScope, name string, worker string) (*models.Job, error)
+		Complete(rs app.RequestScope, name string, worker string, publishedList []*models.PublishedDependency) (*models.Job, error)
+		Fail(rs app.RequestScope, name string, worker string, message string) (*models.Job, error)
+		QueryDeadLetters(rs app.RequestScope, offset, limit int) ([]*models.Job, error)
+		CountDeadLetters(rs app.RequestScope) (int64, error)
+		Requeue(rs app.RequestScope, id int64, actor string) (*models.Job, error)
 		Delete(rs app.RequestScope, name string) (*models.Job, error)
 	}
 
@@ -22,18 +39,45 @@ type (
 	jobResource struct {
 		service    jobService
 		repService repositoryService
+		secretList []string
+	}
+
+	// transitionRequest is the body of a job state transition. ID picks a job of the repository other than the one its
+	// name addresses.
+	transitionRequest struct {
+		ID    int64        `json:"id"`
+		State models.State `json:"state"`
+		Actor string       `json:"actor"`
+	}
+
+	// workerRequest is the body of the requests workers make about the jobs they lease.
+	workerRequest struct {
+		Worker    string                        `json:"worker"`
+		Error     string                        `json:"error"`
+		Published []*models.PublishedDependency `json:"published"`
 	}
 )
 
 // ServeJobResource sets up the routing of repository endpoints and the corresponding handlers.
-func ServeJobResource(rg *routing.RouteGroup, service jobService, repService repositoryService) {
-	r := &jobResource{service, repService}
+// Hook deliveries must be signed with one of the given secrets.
+func ServeJobResource(rg *routing.RouteGroup, service jobService, repService repositoryService, secretList []string) {
+	r := &jobResource{service, repService, secretList}
 	// Some of these routes are probably pointless but building it like a standard REST service
 	rg.Get("/jobs/<name>", r.get)
 	rg.Get("/jobs", r.query)
 	rg.Post("/jobs", r.create)
+	rg.Post("/jobs/claim", r.claim)
+	rg.Post("/jobs/simulate", r.simulate)
 	rg.Put("/jobs/<name>", r.update)
-	rg.Delete("/jobs/name>", r.delete)
+	rg.Get("/jobs/<name>/history", r.history)
+	rg.Get("/jobs/<name>/transitions", r.transitions)
+	rg.Post("/jobs/<name>/transitions", r.transition)
+	rg.Post("/jobs/<name>/heartbeat", r.heartbeat)
+	rg.Post("/jobs/<name>/complete", r.complete)
+	rg.Post("/jobs/<name>/fail", r.fail)
+	rg.Get("/dead-letters", r.queryDeadLetters)
+	rg.Post("/dead-letters/<id>/requeue", r.requeue)
+	rg.Delete("/jobs/<name>", r.delete)
 }
 
 func (r *jobResource) get(c *routing.Context) error {
@@ -60,14 +104,49 @@ func (r *jobResource) query(c *routing.Context) error {
 	return c.Write(paginatedList)
 }
 
+func (r *jobResource) history(c *routing.Context) error {
+	name := c.Param("name")
+	rs := app.GetRequestScope(c)
+	count, err := r.service.CountHistory(rs, name)
+	if err != nil {
+		return err
+	}
+	paginatedList := getPaginatedListFromRequest(c, count)
+	items, err := r.service.QueryHistory(rs, name, paginatedList.Offset(), paginatedList.Limit())
+	if err != nil {
+		return err
+	}
+	paginatedList.Items = items
+	return c.Write(paginatedList)
+}
+
 func (r *jobResource) create(c *routing.Context) error {
+	if err := verifySignedBody(c, "X-Npm-Signature", r.secretList); err != nil {
+		return err
+	}
+
+	payload, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+
+	response, err := r.service.CreateJobsFromPayload(app.GetRequestScope(c), services.EcosystemNpm, payload)
+
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response)
+}
+
+// simulate reports what a hook would do without changing any job. It is not a hook delivery so it is not signed.
+func (r *jobResource) simulate(c *routing.Context) error {
 	var model models.NpmHook
 	if err := c.Read(&model); err != nil {
 		return err
 	}
 
-	response, err := r.service.CreateJobsFromHook(app.GetRequestScope(c), &model)
-
+	response, err := r.service.Simulate(app.GetRequestScope(c), &model)
 	if err != nil {
 		return err
 	}
@@ -96,6 +175,125 @@ func (r *jobResource) update(c *routing.Context) error {
 	return c.Write(response)
 }
 
+func (r *jobResource) transitions(c *routing.Context) error {
+	response, err := r.service.Get(app.GetRequestScope(c), c.Param("name"))
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response.Transitions)
+}
+
+func (r *jobResource) transition(c *routing.Context) error {
+	var model transitionRequest
+	if err := c.Read(&model); err != nil {
+		return err
+	}
+
+	response, err := r.service.Transition(app.GetRequestScope(c), c.Param("name"), model.ID, model.State, model.Actor)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response)
+}
+
+func (r *jobResource) claim(c *routing.Context) error {
+	var model workerRequest
+	if err := c.Read(&model); err != nil {
+		return err
+	}
+
+	response, err := r.service.Claim(app.GetRequestScope(c), model.Worker)
+	if err != nil {
+		return err
+	}
+
+	// Nothing to work on, workers are expected to poll again later.
+	if response == nil {
+		c.Response.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	return c.Write(response)
+}
+
+func (r *jobResource) heartbeat(c *routing.Context) error {
+	var model workerRequest
+	if err := c.Read(&model); err != nil {
+		return err
+	}
+
+	response, err := r.service.Heartbeat(app.GetRequestScope(c), c.Param("name"), model.Worker)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response)
+}
+
+func (r *jobResource) complete(c *routing.Context) error {
+	var model workerRequest
+	if err := c.Read(&model); err != nil {
+		return err
+	}
+
+	response, err := r.service.Complete(app.GetRequestScope(c), c.Param("name"), model.Worker, model.Published)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response)
+}
+
+func (r *jobResource) fail(c *routing.Context) error {
+	var model workerRequest
+	if err := c.Read(&model); err != nil {
+		return err
+	}
+
+	response, err := r.service.Fail(app.GetRequestScope(c), c.Param("name"), model.Worker, model.Error)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response)
+}
+
+func (r *jobResource) queryDeadLetters(c *routing.Context) error {
+	rs := app.GetRequestScope(c)
+	count, err := r.service.CountDeadLetters(rs)
+	if err != nil {
+		return err
+	}
+	paginatedList := getPaginatedListFromRequest(c, count)
+	items, err := r.service.QueryDeadLetters(rs, paginatedList.Offset(), paginatedList.Limit())
+	if err != nil {
+		return err
+	}
+	paginatedList.Items = items
+	return c.Write(paginatedList)
+}
+
+func (r *jobResource) requeue(c *routing.Context) error {
+	var model transitionRequest
+	if err := c.Read(&model); err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return errors.NotFound("job " + c.Param("id"))
+	}
+
+	response, err := r.service.Requeue(app.GetRequestScope(c), id, model.Actor)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response)
+}
+
 func (r *jobResource) delete(c *routing.Context) error {
 	response, err := r.service.Delete(app.GetRequestScope(c), c.Param("name"))
 	if err != nil {
@@ -1,11 +1,25 @@
 package apis
 
 import (
+	errs "errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
 	"github.com/go-ozzo/ozzo-routing"
 	"github.com/quantumew/data-access/models"
 	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/errors"
+	"github.com/quantumew/listener/services"
 )
 
+// maxManifestSize is how much of a multipart manifest upload is kept in memory, the rest goes to temporary files.
+const maxManifestSize = 10 << 20
+
+// maxManifestBody is the largest manifest upload accepted.
+const maxManifestBody = 50 << 20
+
 type (
 	// repositoryService specifies the interface for the repository service needed by repositoryResource.
 	repositoryService interface {
@@ -16,6 +30,7 @@ type (
 		Update(rs app.RequestScope, name string, model *models.Repository) (*models.Repository, error)
 		Patch(rs app.RequestScope, modelList []*models.Repository) ([]*models.Repository, error)
 		Delete(rs app.RequestScope, name string) (*models.Repository, error)
+		RegisterManifest(rs app.RequestScope, name string, manifest *services.Manifest) (*services.ManifestResult, error)
 	}
 
 	// repositoryResource defines the handlers for the CRUD APIs.
@@ -34,6 +49,7 @@ func ServeRepositoryResource(rg *routing.RouteGroup, service repositoryService)
 	rg.Put("/repositories/<name>", r.update)
 	rg.Patch("/repositories", r.patch)
 	rg.Delete("/repositories/<name>", r.delete)
+	rg.Put("/repositories/<name>/manifest", r.manifest)
 }
 
 func (r *repositoryResource) get(c *routing.Context) error {
@@ -122,3 +138,84 @@ func (r *repositoryResource) delete(c *routing.Context) error {
 
 	return c.Write(response)
 }
+
+func (r *repositoryResource) manifest(c *routing.Context) error {
+	c.Request.Body = http.MaxBytesReader(c.Response, c.Request.Body, maxManifestBody)
+
+	manifest, err := readManifest(c.Request)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errs.As(err, &tooLarge) {
+			return errors.RequestTooLarge(tooLarge.Limit)
+		}
+		return err
+	}
+
+	response, err := r.service.RegisterManifest(app.GetRequestScope(c), c.Param("name"), manifest)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(response)
+}
+
+// readManifest reads a manifest upload. A multipart form carries the package.json, package-lock.json, yarn.lock and
+// pnpm-workspace.yaml files under their names, and the package.json of every workspace under its path such as
+// "packages/ui/package.json". Any other body is a raw package.json. The remote and branch of the repository are form
+// or query values.
+func readManifest(req *http.Request) (*services.Manifest, error) {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		query := req.URL.Query()
+		packageJSON, err := ioutil.ReadAll(req.Body)
+
+		return &services.Manifest{
+			PackageJSON: packageJSON,
+			Config:      models.Config{Remote: query.Get("remote"), Branch: query.Get("branch")},
+		}, err
+	}
+
+	if err := req.ParseMultipartForm(maxManifestSize); err != nil {
+		return nil, err
+	}
+
+	manifest := &services.Manifest{
+		Workspaces: map[string][]byte{},
+		Config: models.Config{
+			Remote: req.FormValue("remote"),
+			Branch: req.FormValue("branch"),
+		},
+	}
+
+	for field := range req.MultipartForm.File {
+		data, err := readFormFile(req, field)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case field == "package.json":
+			manifest.PackageJSON = data
+		case field == "package-lock.json":
+			manifest.PackageLock = data
+		case field == "yarn.lock":
+			manifest.YarnLock = data
+		case field == "pnpm-workspace.yaml":
+			manifest.PnpmWorkspace = data
+		case strings.HasSuffix(field, "/package.json"):
+			manifest.Workspaces[strings.TrimSuffix(field, "/package.json")] = data
+		}
+	}
+
+	return manifest, nil
+}
+
+func readFormFile(req *http.Request, field string) ([]byte, error) {
+	file, _, err := req.FormFile(field)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ioutil.ReadAll(file)
+}
@@ -1,10 +1,13 @@
 package apis
 
 import (
+	"bytes"
+	"io/ioutil"
 	"strconv"
 
-	"github.com/quantumew/listener/util"
 	"github.com/go-ozzo/ozzo-routing"
+	"github.com/quantumew/listener/errors"
+	"github.com/quantumew/listener/util"
 )
 
 const (
@@ -33,3 +36,19 @@ func parseInt(value string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// verifySignedBody checks the signature header of a hook delivery against the raw request body.
+// The body is restored afterwards so it can still be read by the handler.
+func verifySignedBody(c *routing.Context, header string, secretList []string) error {
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if err := util.VerifySignature(secretList, c.Request.Header.Get(header), body); err != nil {
+		return errors.Unauthorized(err.Error())
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/go-ozzo/ozzo-validation"
 	"github.com/spf13/viper"
@@ -12,9 +13,30 @@ var Config AppConfig
 
 // AppConfig configuration necessary for the listener API
 type AppConfig struct {
-	DB        dbConfig
-	ErrorFile string
-	Port      int32
+	Artifactory    artifactoryConfig
+	DB             dbConfig
+	Debounce       time.Duration
+	Digest         digestConfig
+	ErrorFile      string
+	GitHub         gitHubConfig
+	GitLab         gitLabConfig
+	Graph          graphConfig
+	Hooks          hooksConfig
+	Messaging      messagingConfig
+	Nexus          nexusConfig
+	Notifications  notificationsConfig
+	Npm            npmConfig
+	Port           int32
+	PullRequests   pullRequestsConfig
+	Retry          retryConfig
+	VersionControl []versionControlConfig
+	Worker         workerConfig
+}
+
+// artifactoryConfig Config representing the Artifactory webhook integration.
+type artifactoryConfig struct {
+	// Tokens Artifactory sends with webhooks.
+	Tokens []string
 }
 
 // DBConfig Config representing database info.
@@ -26,6 +48,205 @@ type dbConfig struct {
 	Username string
 }
 
+// digestConfig Config representing the email digest of pending and failed jobs and of repositories falling behind.
+type digestConfig struct {
+	// Schedule is "daily" or "weekly", no digest is sent when it is empty.
+	Schedule string
+	// Weekday is the day weekly digests are sent, e.g. "monday".
+	Weekday string
+	// Time is the time of day digests are sent at in the local time zone, e.g. "08:00".
+	Time string
+	// Text and HTML are the templates of the bodies of digests, the defaults are used when they are empty.
+	Text string
+	HTML string
+	SMTP smtpConfig
+	// Recipients get the digest of the repositories their route matches, or of every repository.
+	Recipients []recipientConfig
+}
+
+// gitHubConfig Config representing the GitHub webhook integration.
+type gitHubConfig struct {
+	// Secrets GitHub signs webhook deliveries with.
+	Secrets []string
+}
+
+// gitLabConfig Config representing the GitLab hook integration.
+type gitLabConfig struct {
+	// Tokens GitLab sends with hooks.
+	Tokens []string
+}
+
+// graphConfig Config representing how the dependency graph of the registered repositories is kept in memory.
+type graphConfig struct {
+	// CacheTTL is how long the graph is kept before it is read again, zero reads it for every request. Changes made
+	// through the listener drop it right away.
+	CacheTTL time.Duration
+}
+
+// hooksConfig Config representing the hook endpoint every ecosystem can be published through.
+type hooksConfig struct {
+	// Secrets the generic hooks are signed with.
+	Secrets []string
+}
+
+// messagingConfig Config representing the chat service job events are sent to, the messaging section of config.json.
+type messagingConfig struct {
+	// ClientID is the room notifications are sent to, by ID or name.
+	ClientID string
+	// ClientSecret is the notification token of the room.
+	ClientSecret string
+	// ServiceName is the kind of service, only "hipchat" is supported.
+	ServiceName string
+	// URL is the base URL of the service, e.g. https://hipchat.example.com.
+	URL string
+}
+
+// nexusConfig Config representing the Nexus Repository Manager webhook integration.
+type nexusConfig struct {
+	// Secrets Nexus signs webhook deliveries with.
+	Secrets []string
+}
+
+// channelConfig Config representing a chat channel job events are sent to.
+type channelConfig struct {
+	// Name is how routes and repositories refer to the channel.
+	Name string
+	// ServiceName is the kind of service, "hipchat", "slack" or "teams".
+	ServiceName string
+	// URL is the incoming webhook of the channel on Slack and Teams, the base URL of the server on HipChat.
+	URL string
+	// ClientID and ClientSecret are the room and its notification token on HipChat.
+	ClientID     string
+	ClientSecret string
+}
+
+// notificationsConfig Config representing how job events are delivered to chat services.
+type notificationsConfig struct {
+	// QueueSize is how many events wait to be routed, and to be delivered on each channel, before new ones are dropped.
+	QueueSize int
+	// MaxAttempts is how many times the delivery of an event is attempted.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry of a delivery, it doubles with every attempt.
+	BaseDelay time.Duration
+	// Channels are the chat channels events are sent to besides the messaging service, which is named after its service.
+	Channels []channelConfig
+	// Routes pick the channels of the events of repositories that do not list their own.
+	Routes []routeConfig
+	// DefaultChannels get the events of repositories no route matches, every channel does when it is empty.
+	DefaultChannels []string
+}
+
+// routeConfig Config representing the channels the events of some repositories are sent to.
+type routeConfig struct {
+	// Repositories are patterns of the names of repositories, such as "web-*".
+	Repositories []string
+	// Owners are the projects, organizations or groups of the remotes of repositories.
+	Owners []string
+	// Channels are the names of the channels.
+	Channels []string
+}
+
+// npmConfig Config representing the npm hook integration.
+type npmConfig struct {
+	// Secrets npm signs hook deliveries with.
+	Secrets []string
+}
+
+// pullRequestsConfig Config representing the pull requests opened for completed jobs.
+type pullRequestsConfig struct {
+	// BranchPrefix is prepended to the name of a repository to name the branch of its pull requests.
+	BranchPrefix string
+	// Title and Body are the templates of pull requests, the defaults are used when they are empty.
+	Title string
+	Body  string
+	// Labels are added to pull requests on services that have them.
+	Labels []string
+	// Changelogs are the templates of the links to the changelogs of published versions by ecosystem.
+	Changelogs map[string]string
+}
+
+// recipientConfig Config representing who gets the digest of some repositories.
+type recipientConfig struct {
+	// To are the email addresses of the recipient.
+	To []string
+	// Repositories are patterns of the names of repositories, such as "web-*".
+	Repositories []string
+	// Owners are the projects, organizations or groups of the remotes of repositories.
+	Owners []string
+}
+
+// retryConfig Config representing how failed jobs are retried.
+type retryConfig struct {
+	// MaxAttempts is how many times a job is attempted before it is dead-lettered.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, it doubles with every attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts.
+	MaxDelay time.Duration
+	// Jitter is the fraction of the delay, between 0 and 1, that is randomly taken off.
+	Jitter float64
+}
+
+// smtpConfig Config representing the SMTP server digests are sent through.
+type smtpConfig struct {
+	Host string
+	Port int
+	// Username and Password authenticate with the server, when there is a username.
+	Username string
+	Password string
+	// From is the address digests are sent from.
+	From string
+}
+
+// versionControlConfig Config representing a version control service, the versionControl section of config.json. The
+// section is either a single service or a list of them.
+type versionControlConfig struct {
+	// Name is how repositories refer to the service, the service name by default.
+	Name string
+	// ClientID is the user the listener authenticates as.
+	ClientID string
+	// ClientSecret is the password or access token of the user, GitHub and GitLab only needing the token.
+	ClientSecret string
+	// ServiceName is the kind of service, "stash", "github" or "gitlab".
+	ServiceName string
+	// URL is the base URL of the service, e.g. https://stash.example.com.
+	URL string
+}
+
+// workerConfig Config representing how jobs are leased to workers.
+type workerConfig struct {
+	// LeaseTTL is how long a worker holds a job without sending a heartbeat.
+	LeaseTTL time.Duration
+	// SweepInterval is how often expired leases are requeued and idle jobs are queued.
+	SweepInterval time.Duration
+}
+
+// Validate validates notificationsConfig. An event needs room in the queue and at least one delivery attempt.
+func (config notificationsConfig) Validate() error {
+	return validation.ValidateStruct(&config,
+		validation.Field(&config.QueueSize, validation.Required, validation.Min(1)),
+		validation.Field(&config.MaxAttempts, validation.Required, validation.Min(1)),
+	)
+}
+
+// Validate validates retryConfig. Jobs need at least one attempt, and a jitter above 1 would take off more than the
+// whole delay.
+func (config retryConfig) Validate() error {
+	return validation.ValidateStruct(&config,
+		validation.Field(&config.MaxAttempts, validation.Required, validation.Min(1)),
+		validation.Field(&config.Jitter, validation.Min(0.0), validation.Max(1.0)),
+	)
+}
+
+// Validate validates workerConfig. Leases and sweeps need a positive duration, a sweep interval of zero would never
+// requeue a job.
+func (config workerConfig) Validate() error {
+	return validation.ValidateStruct(&config,
+		validation.Field(&config.LeaseTTL, validation.Required, validation.Min(time.Duration(0)).Exclusive()),
+		validation.Field(&config.SweepInterval, validation.Required, validation.Min(time.Duration(0)).Exclusive()),
+	)
+}
+
 // Validate validates AppConfig, currently unused but keeping around in case it is needed
 func (config AppConfig) Validate() error {
 	return validation.ValidateStruct(&config,
@@ -42,6 +263,20 @@ func LoadConfig(configPaths ...string) error {
 	v.SetDefault("ErrorFile", "config/errors.yaml")
 	v.SetDefault("Port", 8080)
 	v.SetDefault("DB", dbConfig{Host: "localhost", Port: 27017, Name: "aufait"})
+	v.SetDefault("Digest.Weekday", "monday")
+	v.SetDefault("Digest.Time", "08:00")
+	v.SetDefault("Digest.SMTP.Port", 25)
+	v.SetDefault("Graph.CacheTTL", time.Minute)
+	v.SetDefault("Notifications.QueueSize", 1000)
+	v.SetDefault("Notifications.MaxAttempts", 5)
+	v.SetDefault("Notifications.BaseDelay", time.Second)
+	v.SetDefault("PullRequests.BranchPrefix", "aufait/")
+	v.SetDefault("Retry.MaxAttempts", 5)
+	v.SetDefault("Retry.BaseDelay", 30*time.Second)
+	v.SetDefault("Retry.MaxDelay", 30*time.Minute)
+	v.SetDefault("Retry.Jitter", 0.2)
+	v.SetDefault("Worker.LeaseTTL", 5*time.Minute)
+	v.SetDefault("Worker.SweepInterval", 30*time.Second)
 
 	for _, path := range configPaths {
 		v.AddConfigPath(path)
@@ -55,5 +290,13 @@ func LoadConfig(configPaths ...string) error {
 		return err
 	}
 
+	if err := (validation.Errors{
+		"notifications": Config.Notifications.Validate(),
+		"retry":         Config.Retry.Validate(),
+		"worker":        Config.Worker.Validate(),
+	}).Filter(); err != nil {
+		return fmt.Errorf("Invalid configuration: %s", err)
+	}
+
 	return nil
 }
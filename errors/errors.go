@@ -27,6 +27,16 @@ func Unauthorized(err string) *APIError {
 	return NewAPIError(http.StatusUnauthorized, "UNAUTHORIZED", Params{"error": err})
 }
 
+// Conflict creates a new API error representing a request that conflicts with the state of a resource (HTTP 409)
+func Conflict(resource string, err string) *APIError {
+	return NewAPIError(http.StatusConflict, "CONFLICT", Params{"resource": resource, "error": err})
+}
+
+// RequestTooLarge creates a new API error representing a request body larger than the limit in bytes (HTTP 413)
+func RequestTooLarge(limit int64) *APIError {
+	return NewAPIError(http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE", Params{"limit": limit})
+}
+
 // InvalidData converts a data validation error into an API error (HTTP 400)
 func InvalidData(errs validation.Errors) *APIError {
 	result := []validationError{}
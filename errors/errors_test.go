@@ -17,6 +17,14 @@ func TestUnauthorized(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, Unauthorized("t").Status)
 }
 
+func TestConflict(t *testing.T) {
+	assert.Equal(t, http.StatusConflict, Conflict("abc", "t").Status)
+}
+
+func TestRequestTooLarge(t *testing.T) {
+	assert.Equal(t, http.StatusRequestEntityTooLarge, RequestTooLarge(10).Status)
+}
+
 func TestInvalidData(t *testing.T) {
 	err := InvalidData(validation.Errors{
 		"abc": errs.New("1"),
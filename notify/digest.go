@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/quantumew/data-access/models"
+)
+
+const (
+	// DefaultDigestText is the template of the text body of digests.
+	DefaultDigestText = `Dependency updates, {{.Period}} digest of {{.Time.Format "January 2, 2006"}}
+
+Pending jobs ({{len .Pending}}):
+{{range .Pending}}* {{.Name}}, {{state .State}}: {{dependencies .}}
+{{else}}None
+{{end}}
+Failed jobs ({{len .Failed}}):
+{{range .Failed}}* {{.Name}}, {{state .State}}: {{lastError .}}
+{{else}}None
+{{end}}
+Repositories behind on internal dependencies ({{len .Outdated}}):
+{{range .Outdated}}* {{.Repository}}: {{.Package}} {{.Installed}}, {{.Latest}} is out
+{{else}}None
+{{end}}`
+	// DefaultDigestHTML is the template of the HTML body of digests.
+	DefaultDigestHTML = `<html>
+<body>
+<h2>Dependency updates, {{.Period}} digest of {{.Time.Format "January 2, 2006"}}</h2>
+<h3>Pending jobs ({{len .Pending}})</h3>
+{{if .Pending}}<ul>
+{{range .Pending}}<li><b>{{.Name}}</b>, {{state .State}}: {{dependencies .}}</li>
+{{end}}</ul>{{else}}<p>None</p>{{end}}
+<h3>Failed jobs ({{len .Failed}})</h3>
+{{if .Failed}}<ul>
+{{range .Failed}}<li><b>{{.Name}}</b>, {{state .State}}: {{lastError .}}</li>
+{{end}}</ul>{{else}}<p>None</p>{{end}}
+<h3>Repositories behind on internal dependencies ({{len .Outdated}})</h3>
+{{if .Outdated}}<table>
+<tr><th>Repository</th><th>Package</th><th>Installed</th><th>Latest</th></tr>
+{{range .Outdated}}<tr><td>{{.Repository}}</td><td>{{.Package}}</td><td>{{.Installed}}</td><td>{{.Latest}}</td></tr>
+{{end}}</table>{{else}}<p>None</p>{{end}}
+</body>
+</html>
+`
+)
+
+// digestFuncs are the functions digest templates can use besides the builtin ones.
+var digestFuncs = map[string]interface{}{"state": StateName, "dependencies": Dependencies, "lastError": LastError}
+
+// Digest summarizes the jobs the listener is waiting on and the repositories falling behind.
+type Digest struct {
+	Period string
+	Time   time.Time
+	// Pending are the jobs that are not done yet, and Failed the ones that failed or ran out of attempts.
+	Pending []*models.Job
+	Failed  []*models.Job
+	// Outdated are the dependencies on packages published by registered repositories that are not installed at the
+	// latest version.
+	Outdated []OutdatedDependency
+	// Repositories are the registered repositories by name, which recipients are matched against.
+	Repositories map[string]*models.Repository
+}
+
+// OutdatedDependency is a dependency of a repository on a package another registered repository publishes, installed
+// at an older version than the latest one.
+type OutdatedDependency struct {
+	Repository string
+	Ecosystem  string
+	Package    string
+	Installed  string
+	Latest     string
+}
+
+// DigestTemplates render the bodies of digests.
+type DigestTemplates struct {
+	text *template.Template
+	html *htmltemplate.Template
+}
+
+// Recipient is who gets the digest of the repositories a route matches, or of every repository for a route that
+// matches none.
+type Recipient struct {
+	To    []string
+	Route Route
+}
+
+// DigestMailer emails digests to their recipients.
+type DigestMailer struct {
+	email         *Email
+	templates     *DigestTemplates
+	recipientList []Recipient
+}
+
+// NewDigestTemplates parses the templates of digests, the defaults being used for empty ones. They are executed with
+// the Digest.
+func NewDigestTemplates(text string, html string) (*DigestTemplates, error) {
+	var err error
+	templates := &DigestTemplates{}
+
+	if text == "" {
+		text = DefaultDigestText
+	}
+
+	if html == "" {
+		html = DefaultDigestHTML
+	}
+
+	if templates.text, err = template.New("text").Funcs(digestFuncs).Parse(text); err != nil {
+		return nil, err
+	}
+
+	if templates.html, err = htmltemplate.New("html").Funcs(digestFuncs).Parse(html); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// Render returns the text and HTML bodies of a digest.
+func (t *DigestTemplates) Render(digest *Digest) (string, string, error) {
+	var text, html bytes.Buffer
+
+	if err := t.text.Execute(&text, digest); err != nil {
+		return "", "", err
+	}
+
+	if err := t.html.Execute(&html, digest); err != nil {
+		return "", "", err
+	}
+
+	return text.String(), html.String(), nil
+}
+
+// Subject returns the subject of the email of a digest.
+func (d *Digest) Subject() string {
+	return fmt.Sprintf("Dependency updates: %d pending, %d failed, %d behind", len(d.Pending), len(d.Failed), len(d.Outdated))
+}
+
+// Empty returns whether there is nothing to report.
+func (d *Digest) Empty() bool {
+	return len(d.Pending) == 0 && len(d.Failed) == 0 && len(d.Outdated) == 0
+}
+
+// For returns the digest of the repositories a route matches, the whole digest for a route that matches none.
+func (d *Digest) For(route Route) *Digest {
+	if len(route.Repositories) == 0 && len(route.Owners) == 0 {
+		return d
+	}
+
+	matches := func(name string) bool {
+		rep, ok := d.Repositories[name]
+		return ok && route.Matches(rep)
+	}
+
+	filtered := &Digest{Period: d.Period, Time: d.Time, Repositories: d.Repositories}
+
+	for _, job := range d.Pending {
+		if matches(job.Name) {
+			filtered.Pending = append(filtered.Pending, job)
+		}
+	}
+
+	for _, job := range d.Failed {
+		if matches(job.Name) {
+			filtered.Failed = append(filtered.Failed, job)
+		}
+	}
+
+	for _, outdated := range d.Outdated {
+		if matches(outdated.Repository) {
+			filtered.Outdated = append(filtered.Outdated, outdated)
+		}
+	}
+
+	return filtered
+}
+
+// Sort sorts the jobs by repository, and the outdated dependencies by repository and package.
+func (d *Digest) Sort() {
+	sort.SliceStable(d.Pending, func(i, j int) bool { return d.Pending[i].Name < d.Pending[j].Name })
+	sort.SliceStable(d.Failed, func(i, j int) bool { return d.Failed[i].Name < d.Failed[j].Name })
+	sort.SliceStable(d.Outdated, func(i, j int) bool {
+		if d.Outdated[i].Repository != d.Outdated[j].Repository {
+			return d.Outdated[i].Repository < d.Outdated[j].Repository
+		}
+
+		return d.Outdated[i].Package < d.Outdated[j].Package
+	})
+}
+
+// NewDigestMailer creates a new DigestMailer sending digests rendered with the templates through an SMTP server.
+func NewDigestMailer(email *Email, templates *DigestTemplates, recipientList []Recipient) *DigestMailer {
+	return &DigestMailer{email, templates, recipientList}
+}
+
+// Send emails every recipient the digest of their repositories. Recipients with nothing to report get no email. The
+// remaining recipients still get theirs when an email cannot be sent, the last error being returned.
+func (m *DigestMailer) Send(digest *Digest) error {
+	var lastErr error
+
+	for _, recipient := range m.recipientList {
+		filtered := digest.For(recipient.Route)
+		if filtered.Empty() {
+			continue
+		}
+
+		text, html, err := m.templates.Render(filtered)
+		if err == nil {
+			err = m.email.Send(recipient.To, filtered.Subject(), text, html)
+		}
+
+		if err != nil {
+			lastErr = fmt.Errorf("sending the digest to %v: %s", recipient.To, err)
+		}
+	}
+
+	return lastErr
+}
@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDigest() *Digest {
+	return &Digest{
+		Period: Weekly,
+		Time:   time.Date(2018, 9, 3, 8, 30, 0, 0, time.UTC),
+		Pending: []*models.Job{
+			{Name: "web-app", State: models.Queued, Dependencies: []*models.PublishedDependency{{Name: "lib", Version: "1.2.0"}}},
+			{Name: "api", State: models.Locked, Dependencies: []*models.PublishedDependency{{Name: "lib", Version: "1.2.0"}}},
+		},
+		Failed: []*models.Job{
+			{Name: "web-admin", State: models.DeadLetter, Attempts: []*models.Attempt{{Error: "<npm> install failed"}}},
+		},
+		Outdated: []OutdatedDependency{
+			{Repository: "api", Ecosystem: "npm", Package: "lib", Installed: "1.0.0", Latest: "1.2.0"},
+		},
+		Repositories: map[string]*models.Repository{
+			"web-app":   {Name: "web-app"},
+			"web-admin": {Name: "web-admin"},
+			"api":       {Name: "api"},
+		},
+	}
+}
+
+func TestDigestTemplates_Render(t *testing.T) {
+	templates, err := NewDigestTemplates("", "")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	digest := newTestDigest()
+	digest.Sort()
+
+	text, html, err := templates.Render(digest)
+	assert.Nil(t, err)
+	assert.Equal(t, `Dependency updates, weekly digest of September 3, 2018
+
+Pending jobs (2):
+* api, locked: lib 1.2.0
+* web-app, queued: lib 1.2.0
+
+Failed jobs (1):
+* web-admin, dead-lettered: <npm> install failed
+
+Repositories behind on internal dependencies (1):
+* api: lib 1.0.0, 1.2.0 is out
+`, text)
+	assert.Contains(t, html, "<li><b>web-admin</b>, dead-lettered: &lt;npm&gt; install failed</li>")
+	assert.Contains(t, html, "<tr><td>api</td><td>lib</td><td>1.0.0</td><td>1.2.0</td></tr>")
+
+	text, html, err = templates.Render(&Digest{Period: Daily})
+	assert.Nil(t, err)
+	assert.Contains(t, text, "Failed jobs (0):\nNone\n")
+	assert.Contains(t, html, "<h3>Failed jobs (0)</h3>\n<p>None</p>")
+
+	templates, err = NewDigestTemplates("{{len .Pending}} pending", "<p>{{.Subject}}</p>")
+	if assert.Nil(t, err) {
+		text, html, err = templates.Render(digest)
+		assert.Nil(t, err)
+		assert.Equal(t, "2 pending", text)
+		assert.Equal(t, "<p>Dependency updates: 2 pending, 1 failed, 1 behind</p>", html)
+	}
+
+	_, err = NewDigestTemplates("{{.Pending", "")
+	assert.NotNil(t, err)
+}
+
+func TestDigest_For(t *testing.T) {
+	digest := newTestDigest()
+
+	assert.Equal(t, digest, digest.For(Route{}))
+
+	web := digest.For(Route{Repositories: []string{"web-*"}})
+	assert.Equal(t, 1, len(web.Pending))
+	assert.Equal(t, 1, len(web.Failed))
+	assert.Empty(t, web.Outdated)
+	assert.False(t, web.Empty())
+
+	assert.True(t, digest.For(Route{Repositories: []string{"tools"}}).Empty())
+}
+
+func TestDigestMailer_Send(t *testing.T) {
+	stub := newSMTPStub(t)
+	defer stub.listener.Close()
+
+	templates, _ := NewDigestTemplates("", "")
+	mailer := NewDigestMailer(NewEmail(stub.addr(), "", "", "aufait@example.com"), templates, []Recipient{
+		{To: []string{"web@example.com"}, Route: Route{Repositories: []string{"web-*"}}},
+		{To: []string{"tools@example.com"}, Route: Route{Repositories: []string{"tools"}}},
+		{To: []string{"everyone@example.com"}},
+	})
+
+	assert.Nil(t, mailer.Send(newTestDigest()))
+	if assert.Equal(t, 2, len(stub.messages), "nothing to report on tools") {
+		assert.Equal(t, []string{"web@example.com"}, stub.messages[0].to)
+		subject, partMap := parseEmail(t, stub.messages[0].data)
+		assert.Equal(t, "Dependency updates: 1 pending, 1 failed, 0 behind", subject)
+		assert.Contains(t, partMap["text/plain"], "* web-app, queued: lib 1.2.0")
+		assert.NotContains(t, partMap["text/plain"], "api")
+
+		assert.Equal(t, []string{"everyone@example.com"}, stub.messages[1].to)
+	}
+
+	stub.listener.Close()
+	assert.NotNil(t, mailer.Send(newTestDigest()))
+}
@@ -0,0 +1,186 @@
+package notify
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/quantumew/data-access/models"
+)
+
+// Logger reports the events that could not be delivered.
+type Logger interface {
+	Errorf(format string, args ...interface{})
+}
+
+// queuedEvent is an event waiting to be routed, with the lookup of its repository when it was dispatched without one.
+type queuedEvent struct {
+	Event
+	lookup func() (*models.Repository, error)
+}
+
+// Dispatcher delivers events to the notifiers of named channels in the background. Events are queued, then routed to
+// the queue of every channel they go to. Each channel has its own worker delivering its events in order, a failed
+// delivery being retried with a doubling delay, so a channel that is down only holds back its own events. Events are
+// dropped rather than waited for when a queue is full.
+type Dispatcher struct {
+	channelList []string
+	notifierMap map[string]Notifier
+	router      *Router
+	logger      Logger
+	queue       chan queuedEvent
+	queueSize   int
+	maxAttempts int
+	baseDelay   time.Duration
+	done        sync.WaitGroup
+}
+
+// NewDispatcher creates a new Dispatcher holding up to queueSize events, both before they are routed and in the queue
+// of each channel. Every delivery is tried up to maxAttempts times, waiting baseDelay before the first retry.
+func NewDispatcher(logger Logger, queueSize int, maxAttempts int, baseDelay time.Duration) *Dispatcher {
+	return &Dispatcher{
+		notifierMap: map[string]Notifier{},
+		logger:      logger,
+		queue:       make(chan queuedEvent, queueSize),
+		queueSize:   queueSize,
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+	}
+}
+
+// Add adds the notifier of a channel. Channels must be added before the dispatcher is started.
+func (d *Dispatcher) Add(channel string, notifier Notifier) {
+	if _, ok := d.notifierMap[channel]; !ok {
+		d.channelList = append(d.channelList, channel)
+	}
+
+	d.notifierMap[channel] = notifier
+}
+
+// Len returns the number of channels.
+func (d *Dispatcher) Len() int {
+	return len(d.channelList)
+}
+
+// Route makes the router pick the channels of every event. Events the router has no channel for, and every event
+// without a router, are delivered to every channel.
+func (d *Dispatcher) Route(router *Router) error {
+	for _, channel := range router.channelNames() {
+		if _, ok := d.notifierMap[channel]; !ok {
+			return fmt.Errorf("unknown channel %q", channel)
+		}
+	}
+
+	d.router = router
+
+	return nil
+}
+
+// Start starts routing the queued events and a worker per channel delivering them.
+func (d *Dispatcher) Start() {
+	queueMap := map[string]chan Event{}
+
+	for _, channel := range d.channelList {
+		queue := make(chan Event, d.queueSize)
+		queueMap[channel] = queue
+		d.done.Add(1)
+
+		go func(channel string, notifier Notifier) {
+			defer d.done.Done()
+
+			for event := range queue {
+				d.deliver(notifier, event)
+			}
+		}(channel, d.notifierMap[channel])
+	}
+
+	d.done.Add(1)
+
+	go func() {
+		defer d.done.Done()
+
+		for queued := range d.queue {
+			event := queued.Event
+
+			// Repositories that cannot be read are routed to the default channels.
+			if queued.lookup != nil {
+				if rep, err := queued.lookup(); err == nil {
+					event.Repository = rep
+				}
+			}
+
+			for _, channel := range d.channels(event) {
+				queue, ok := queueMap[channel]
+				if !ok {
+					d.logger.Errorf("delivering the %s event of %s: unknown channel %q", event.Type, event.Job.Name, channel)
+					continue
+				}
+
+				select {
+				case queue <- event:
+				default:
+					d.logger.Errorf("dropping the %s event of %s, the queue of %s is full", event.Type, event.Job.Name, channel)
+				}
+			}
+		}
+
+		for _, queue := range queueMap {
+			close(queue)
+		}
+	}()
+}
+
+// Dispatch queues an event without waiting for it to be delivered.
+func (d *Dispatcher) Dispatch(event Event) {
+	d.DispatchFor(event, nil)
+}
+
+// DispatchFor queues an event whose repository is looked up in the background, before the event is routed, without
+// waiting for it to be delivered.
+func (d *Dispatcher) DispatchFor(event Event, lookup func() (*models.Repository, error)) {
+	select {
+	case d.queue <- queuedEvent{event, lookup}:
+	default:
+		d.logger.Errorf("dropping the %s event of %s, the notification queue is full", event.Type, event.Job.Name)
+	}
+}
+
+// Close stops taking events and waits for the queued ones to be delivered.
+func (d *Dispatcher) Close() {
+	close(d.queue)
+	d.done.Wait()
+}
+
+// channels returns the channels an event is delivered to.
+func (d *Dispatcher) channels(event Event) []string {
+	if d.router == nil {
+		return d.channelList
+	}
+
+	if channelList := d.router.Channels(event.Repository); len(channelList) > 0 {
+		return channelList
+	}
+
+	return d.channelList
+}
+
+// deliver delivers an event to a notifier, retrying until it runs out of attempts. Only the worker of the channel of the
+// notifier waits between attempts.
+func (d *Dispatcher) deliver(notifier Notifier, event Event) {
+	delay := d.baseDelay
+
+	for attempt := 1; ; attempt++ {
+		err := notifier.Notify(event)
+		if err == nil {
+			return
+		}
+
+		if attempt >= d.maxAttempts {
+			d.logger.Errorf("delivering the %s event of %s: %v", event.Type, event.Job.Name, err)
+			return
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
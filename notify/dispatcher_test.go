@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingNotifier records the events it is notified of, failing the first deliveries.
+type recordingNotifier struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	events   []Event
+	// wait blocks deliveries until it is closed, when it is set.
+	wait chan struct{}
+	// delivered gets the events that were delivered, when it is set.
+	delivered chan Event
+}
+
+func (n *recordingNotifier) Notify(event Event) error {
+	if n.wait != nil {
+		<-n.wait
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.attempts++
+	if n.attempts <= n.failures {
+		return errors.New("unavailable")
+	}
+
+	n.events = append(n.events, event)
+	if n.delivered != nil {
+		n.delivered <- event
+	}
+
+	return nil
+}
+
+// recordingLogger records the errors it is given.
+type recordingLogger struct {
+	mu     sync.Mutex
+	errors []string
+}
+
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+func newEvent(eventType EventType, name string) Event {
+	return Event{Type: eventType, Job: &models.Job{Name: name}}
+}
+
+func TestDispatcher(t *testing.T) {
+	logger := &recordingLogger{}
+	flaky := &recordingNotifier{failures: 2}
+	down := &recordingNotifier{failures: 10}
+
+	d := NewDispatcher(logger, 10, 3, time.Millisecond)
+	d.Add("flaky", flaky)
+	d.Add("down", down)
+	assert.Equal(t, 2, d.Len())
+	d.Start()
+
+	d.Dispatch(newEvent(JobCreated, "app"))
+	d.Dispatch(newEvent(JobFailed, "app"))
+	d.Close()
+
+	if assert.Equal(t, 2, len(flaky.events)) {
+		assert.Equal(t, JobCreated, flaky.events[0].Type)
+		assert.Equal(t, JobFailed, flaky.events[1].Type)
+	}
+
+	assert.Empty(t, down.events)
+	assert.Equal(t, 6, down.attempts)
+	assert.Equal(t, []string{
+		"delivering the job.created event of app: unavailable",
+		"delivering the job.failed event of app: unavailable",
+	}, logger.errors)
+}
+
+func TestDispatcher_QueueFull(t *testing.T) {
+	logger := &recordingLogger{}
+	slow := &recordingNotifier{wait: make(chan struct{})}
+
+	d := NewDispatcher(logger, 1, 1, time.Millisecond)
+	d.Add("slow", slow)
+
+	// nothing is delivered yet, so the queue fills up without blocking
+	d.Dispatch(newEvent(JobCreated, "app"))
+	d.Dispatch(newEvent(JobCreated, "lib"))
+	assert.Equal(t, []string{"dropping the job.created event of lib, the notification queue is full"}, logger.errors)
+
+	d.Start()
+	close(slow.wait)
+	d.Close()
+
+	if assert.Equal(t, 1, len(slow.events)) {
+		assert.Equal(t, "app", slow.events[0].Job.Name)
+	}
+}
+
+func TestDispatcher_ChannelDown(t *testing.T) {
+	logger := &recordingLogger{}
+	down := &recordingNotifier{wait: make(chan struct{})}
+	up := &recordingNotifier{delivered: make(chan Event, 1)}
+
+	d := NewDispatcher(logger, 10, 1, time.Millisecond)
+	d.Add("down", down)
+	d.Add("up", up)
+	d.Start()
+
+	// the channel that is down does not hold back the other one
+	d.Dispatch(newEvent(JobCreated, "app"))
+	select {
+	case event := <-up.delivered:
+		assert.Equal(t, "app", event.Job.Name)
+	case <-time.After(time.Second):
+		t.Error("the event was not delivered")
+	}
+
+	close(down.wait)
+	d.Close()
+
+	assert.Equal(t, 1, len(down.events))
+	assert.Empty(t, logger.errors)
+}
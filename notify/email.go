@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Email sends emails through an SMTP server.
+type Email struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewEmail returns a client of the SMTP server at an address such as "smtp.example.com:587", sending emails from an
+// address. The server is authenticated with when a username is given, STARTTLS being used when the server offers it.
+func NewEmail(addr string, username string, password string, from string) *Email {
+	var auth smtp.Auth
+
+	if username != "" {
+		host := addr
+		if i := strings.LastIndex(addr, ":"); i >= 0 {
+			host = addr[:i]
+		}
+
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &Email{addr, auth, from}
+}
+
+// Send sends an email with a text and an HTML body, the clients that can show HTML picking the HTML one.
+func (e *Email) Send(toList []string, subject string, text string, html string) error {
+	var message bytes.Buffer
+	body := multipart.NewWriter(&message)
+
+	header := []string{
+		"From: " + e.from,
+		"To: " + strings.Join(toList, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		fmt.Sprintf("Content-Type: multipart/alternative; boundary=%q", body.Boundary()),
+	}
+	message.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct{ contentType, content string }{{"text/plain", text}, {"text/html", html}} {
+		writer, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return err
+		}
+
+		encoder := quotedprintable.NewWriter(writer)
+		if _, err := encoder.Write([]byte(part.content)); err != nil {
+			return err
+		}
+
+		if err := encoder.Close(); err != nil {
+			return err
+		}
+	}
+
+	if err := body.Close(); err != nil {
+		return err
+	}
+
+	return smtp.SendMail(e.addr, e.auth, e.from, toList, message.Bytes())
+}
@@ -0,0 +1,163 @@
+package notify
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// smtpStub is a local SMTP server keeping the messages it is sent. It authenticates listener with the password secret.
+type smtpStub struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []smtpMessage
+}
+
+type smtpMessage struct {
+	from string
+	to   []string
+	data []byte
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stub := &smtpStub{listener: listener}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go stub.serve(conn)
+		}
+	}()
+
+	return stub
+}
+
+func (s *smtpStub) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	tp := textproto.NewConn(conn)
+	defer tp.Close()
+
+	message := smtpMessage{}
+	tp.PrintfLine("220 localhost ESMTP stub")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		fieldList := strings.Fields(line)
+		if len(fieldList) == 0 {
+			tp.PrintfLine("500 empty command")
+			continue
+		}
+
+		switch strings.ToUpper(fieldList[0]) {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250-localhost")
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			credentials := []byte{}
+			if len(fieldList) == 3 {
+				credentials, _ = base64.StdEncoding.DecodeString(fieldList[2])
+			}
+
+			if string(credentials) == "\x00listener\x00secret" {
+				tp.PrintfLine("235 authenticated")
+			} else {
+				tp.PrintfLine("535 authentication failed")
+			}
+		case "MAIL":
+			message.from = strings.Trim(strings.TrimPrefix(line[5:], "FROM:"), "<>")
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			message.to = append(message.to, strings.Trim(strings.TrimPrefix(line[5:], "TO:"), "<>"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			message.data, _ = tp.ReadDotBytes()
+
+			s.mu.Lock()
+			s.messages = append(s.messages, message)
+			s.mu.Unlock()
+
+			message = smtpMessage{}
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("250 ok")
+		}
+	}
+}
+
+// parseEmail returns the subject of an email and its parts by content type.
+func parseEmail(t *testing.T, data []byte) (string, map[string]string) {
+	message, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject, _ := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	_, params, _ := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	partMap := map[string]string{}
+	reader := multipart.NewReader(message.Body, params["boundary"])
+
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+
+		content, _ := ioutil.ReadAll(part)
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		partMap[mediaType] = string(content)
+	}
+
+	return subject, partMap
+}
+
+func TestEmail_Send(t *testing.T) {
+	stub := newSMTPStub(t)
+	defer stub.listener.Close()
+
+	email := NewEmail(stub.addr(), "listener", "secret", "aufait@example.com")
+	html := "<p>" + strings.Repeat("a long line that quoted-printable has to wrap ", 5) + "</p>"
+	err := email.Send([]string{"jdoe@example.com", "ui@example.com"}, "Dependency updates: 1 pending", "1 pending job", html)
+	if !assert.Nil(t, err) || !assert.Equal(t, 1, len(stub.messages)) {
+		return
+	}
+
+	assert.Equal(t, "aufait@example.com", stub.messages[0].from)
+	assert.Equal(t, []string{"jdoe@example.com", "ui@example.com"}, stub.messages[0].to)
+
+	subject, partMap := parseEmail(t, stub.messages[0].data)
+	assert.Equal(t, "Dependency updates: 1 pending", subject)
+	assert.Equal(t, map[string]string{"text/plain": "1 pending job", "text/html": html}, partMap)
+
+	err = NewEmail(stub.addr(), "listener", "wrong", "aufait@example.com").Send([]string{"jdoe@example.com"}, "", "", "")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, len(stub.messages))
+}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HipChat sends room notifications through the v2 API of HipChat.
+type HipChat struct {
+	client  *http.Client
+	baseURL string
+	room    string
+	token   string
+}
+
+// HipChatError is an error response of the HipChat API.
+type HipChatError struct {
+	StatusCode int
+	Message    string
+}
+
+// eventColors are the colors of the notifications of every type of event, gray for the others.
+var eventColors = map[EventType]string{
+	JobLocked:         "yellow",
+	PullRequestOpened: "green",
+	JobFailed:         "red",
+	JobDeadLettered:   "red",
+}
+
+// NewHipChat returns a client notifying a room, given by ID or name, of the HipChat server at a base URL such as
+// "https://hipchat.example.com". The token is a room notification token.
+func NewHipChat(client *http.Client, baseURL string, room string, token string) *HipChat {
+	return &HipChat{client, strings.TrimSuffix(baseURL, "/"), room, token}
+}
+
+// Notify sends the message of an event to the room. Failures notify the members of the room.
+func (h *HipChat) Notify(event Event) error {
+	color, ok := eventColors[event.Type]
+	if !ok {
+		color = "gray"
+	}
+
+	payload := map[string]interface{}{
+		"message":        event.Message(),
+		"message_format": "text",
+		"color":          color,
+		"notify":         color == "red",
+	}
+	header := http.Header{"Authorization": {"Bearer " + h.token}}
+
+	return postJSON(h.client, h.baseURL+"/v2/room/"+url.PathEscape(h.room)+"/notification", header, payload, newHipChatError)
+}
+
+// newHipChatError reads the message of an error response.
+func newHipChatError(res *http.Response) error {
+	apiErr := &HipChatError{StatusCode: res.StatusCode}
+	errorBody := struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}{}
+
+	if json.NewDecoder(res.Body).Decode(&errorBody) == nil {
+		apiErr.Message = errorBody.Error.Message
+	}
+
+	return apiErr
+}
+
+func (e *HipChatError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("HipChat API responded with %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("HipChat API responded with %d: %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHipChat_Notify(t *testing.T) {
+	var notificationList []map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"code":401,"message":"Invalid OAuth session","type":"Unauthorized"}}`))
+			return
+		}
+
+		if r.Method != "POST" || r.URL.EscapedPath() != "/v2/room/Dependency%20Updates/notification" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		notification := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&notification)
+		notificationList = append(notificationList, notification)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	hipChat := NewHipChat(server.Client(), server.URL+"/", "Dependency Updates", "secret")
+
+	job := &models.Job{
+		Name:         "app",
+		Dependencies: []*models.PublishedDependency{{Name: "lib", Version: "1.2.0"}, {Name: "other", Version: "2.0.0"}},
+		Attempts:     []*models.Attempt{{Worker: "worker-1", Error: "npm install failed"}},
+	}
+
+	assert.Nil(t, hipChat.Notify(Event{Type: JobCreated, Job: job}))
+	assert.Nil(t, hipChat.Notify(Event{Type: JobDeadLettered, Job: job}))
+
+	assert.Equal(t, []map[string]interface{}{
+		{"message": "Job for app created: lib 1.2.0, other 2.0.0", "message_format": "text", "color": "gray", "notify": false},
+		{"message": "Job for app is out of attempts: npm install failed", "message_format": "text", "color": "red", "notify": true},
+	}, notificationList)
+
+	err := NewHipChat(server.Client(), server.URL, "Dependency Updates", "wrong").Notify(Event{Type: JobCreated, Job: job})
+	if assert.IsType(t, &HipChatError{}, err) {
+		assert.Equal(t, "HipChat API responded with 401: Invalid OAuth session", err.Error())
+	}
+
+	err = NewHipChat(server.Client(), server.URL, "Other", "secret").Notify(Event{Type: JobCreated, Job: job})
+	if assert.IsType(t, &HipChatError{}, err) {
+		assert.Equal(t, "HipChat API responded with 404", err.Error())
+	}
+}
+
+func TestEvent_Message(t *testing.T) {
+	job := &models.Job{
+		Name:         "app",
+		Dependencies: []*models.PublishedDependency{{Name: "lib", Version: "1.2.0"}},
+		BlockedBy:    []string{"lib", "core"},
+		PullRequest:  &models.PullRequest{URL: "https://stash.example.com/projects/PROJ/repos/app/pull-requests/1"},
+	}
+
+	tests := []struct {
+		eventType EventType
+		message   string
+	}{
+		{JobCreated, "Job for app created: lib 1.2.0"},
+		{JobLocked, "Job for app is waiting for lib, core"},
+		{PullRequestOpened, "Pull request for app opened: https://stash.example.com/projects/PROJ/repos/app/pull-requests/1"},
+		{JobFailed, "Job for app failed and will be retried: no error reported"},
+		{EventType("job.other"), "Job for app: job.other"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.message, Event{Type: test.eventType, Job: job}.Message())
+	}
+}
+
+func TestNew(t *testing.T) {
+	notifier, err := New("hipchat", "https://hipchat.example.com", "42", "secret")
+	if assert.Nil(t, err) {
+		assert.IsType(t, &HipChat{}, notifier)
+	}
+
+	notifier, err = New("slack", "https://hooks.slack.com/services/T0/B0/XXXX", "", "")
+	if assert.Nil(t, err) {
+		assert.IsType(t, &Slack{}, notifier)
+	}
+
+	notifier, err = New("teams", "https://example.webhook.office.com/webhookb2/XXXX", "", "")
+	if assert.Nil(t, err) {
+		assert.IsType(t, &Teams{}, notifier)
+	}
+
+	_, err = New("irc", "https://irc.example.com", "", "")
+	assert.NotNil(t, err)
+
+	_, err = New("hipchat", "", "42", "secret")
+	assert.NotNil(t, err)
+}
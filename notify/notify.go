@@ -0,0 +1,146 @@
+// Package notify tells chat rooms and channels about the lifecycle of update jobs, delivering events in the
+// background so that hooks and workers never wait on a chat server.
+package notify
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/quantumew/data-access/models"
+)
+
+// Service names of the supported messaging services.
+const (
+	// ServiceHipChat is HipChat or HipChat Server.
+	ServiceHipChat = "hipchat"
+	// ServiceSlack is a Slack incoming webhook.
+	ServiceSlack = "slack"
+	// ServiceTeams is a Microsoft Teams incoming webhook.
+	ServiceTeams = "teams"
+)
+
+// EventType is a step of the lifecycle of a job.
+type EventType string
+
+// Types of the events sent about jobs.
+const (
+	// JobCreated is sent when a published dependency creates a new job for a repository.
+	JobCreated EventType = "job.created"
+	// JobLocked is sent when a new job has to wait for the jobs of other repositories, or of its own, to finish.
+	JobLocked EventType = "job.locked"
+	// PullRequestOpened is sent when the pull request of a completed job is opened or updated.
+	PullRequestOpened EventType = "pullrequest.opened"
+	// JobFailed is sent when a worker fails a job that is retried later.
+	JobFailed EventType = "job.failed"
+	// JobDeadLettered is sent when a job fails for the last time and needs to be requeued by hand.
+	JobDeadLettered EventType = "job.deadlettered"
+)
+
+// stateNames are how the states of jobs are shown in messages.
+var stateNames = map[models.State]string{
+	models.Idle:       "idle",
+	models.Queued:     "queued",
+	models.InProgress: "in progress",
+	models.Locked:     "locked",
+	models.Succeeded:  "succeeded",
+	models.Failed:     "failed",
+	models.DeadLetter: "dead-lettered",
+}
+
+// Event is something that happened to a job. The repository of the job decides where the event is sent, it is nil when
+// the repository could not be read.
+type Event struct {
+	Type       EventType
+	Job        *models.Job
+	Repository *models.Repository
+	Time       time.Time
+}
+
+// Notifier delivers events to a messaging service.
+type Notifier interface {
+	// Notify delivers an event, returning an error when the delivery should be retried.
+	Notify(event Event) error
+}
+
+// Timeout is how long the notifiers New returns wait for a service to respond.
+const Timeout = 10 * time.Second
+
+// New returns a notifier of the named service, authenticated with the client ID and secret the service takes. The URL
+// of incoming webhooks is all they need.
+func New(serviceName string, baseURL string, clientID string, clientSecret string) (Notifier, error) {
+	if _, err := url.Parse(baseURL); err != nil || baseURL == "" {
+		return nil, fmt.Errorf("invalid %s URL %q", serviceName, baseURL)
+	}
+
+	client := &http.Client{Timeout: Timeout}
+
+	switch serviceName {
+	case ServiceHipChat:
+		return NewHipChat(client, baseURL, clientID, clientSecret), nil
+	case ServiceSlack:
+		return NewSlack(client, baseURL), nil
+	case ServiceTeams:
+		return NewTeams(client, baseURL), nil
+	}
+
+	return nil, fmt.Errorf("unknown messaging service %q", serviceName)
+}
+
+// Message returns a one line summary of the event, for services that take plain text.
+func (e Event) Message() string {
+	switch e.Type {
+	case JobCreated:
+		return fmt.Sprintf("Job for %s created: %s", e.Job.Name, Dependencies(e.Job))
+	case JobLocked:
+		return fmt.Sprintf("Job for %s is waiting for %s", e.Job.Name, strings.Join(e.Job.BlockedBy, ", "))
+	case PullRequestOpened:
+		return fmt.Sprintf("Pull request for %s opened: %s", e.Job.Name, e.Job.PullRequest.URL)
+	case JobFailed:
+		return fmt.Sprintf("Job for %s failed and will be retried: %s", e.Job.Name, LastError(e.Job))
+	case JobDeadLettered:
+		return fmt.Sprintf("Job for %s is out of attempts: %s", e.Job.Name, LastError(e.Job))
+	}
+
+	return fmt.Sprintf("Job for %s: %s", e.Job.Name, e.Type)
+}
+
+// Dependencies lists the dependencies of a job with the version they were queued with.
+func Dependencies(job *models.Job) string {
+	var depList []string
+
+	for _, dep := range job.Dependencies {
+		depList = append(depList, dep.Name+" "+dep.Version)
+	}
+
+	return strings.Join(depList, ", ")
+}
+
+// LastError returns the error the last attempt of a job failed with.
+func LastError(job *models.Job) string {
+	if len(job.Attempts) == 0 || job.Attempts[len(job.Attempts)-1].Error == "" {
+		return "no error reported"
+	}
+
+	return job.Attempts[len(job.Attempts)-1].Error
+}
+
+// StateName returns how the state of a job is shown in messages.
+func StateName(state models.State) string {
+	if name, ok := stateNames[state]; ok {
+		return name
+	}
+
+	return fmt.Sprint(state)
+}
+
+// pullRequestURL returns the link to the pull request of a job, empty when it has none.
+func pullRequestURL(job *models.Job) string {
+	if job.PullRequest == nil {
+		return ""
+	}
+
+	return job.PullRequest.URL
+}
@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/vcs"
+)
+
+// Route sends the events of the repositories it matches to channels. A route matches a repository when one of its
+// repository patterns or one of its owner groups does.
+type Route struct {
+	// Repositories are patterns of the names of repositories, such as "web-*".
+	Repositories []string
+	// Owners are owner groups, the project, organization or group repositories belong to on their code host. Groups
+	// include their subgroups.
+	Owners []string
+	// Channels are the names of the channels the events are sent to.
+	Channels []string
+}
+
+// Router picks the channels the events of a repository are sent to.
+type Router struct {
+	routeList   []Route
+	defaultList []string
+}
+
+// NewRouter creates a new Router sending the events of the repositories no route matches to the default channels.
+func NewRouter(routeList []Route, defaultList []string) (*Router, error) {
+	for _, route := range routeList {
+		for _, pattern := range route.Repositories {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid repository pattern %q", pattern)
+			}
+		}
+	}
+
+	return &Router{routeList, defaultList}, nil
+}
+
+// Channels returns the channels the events of a repository are sent to: the ones its config lists, or else the ones
+// of every route matching it, or else the default ones. Nil is returned when there are none.
+func (r *Router) Channels(rep *models.Repository) []string {
+	if rep == nil {
+		return r.defaultList
+	}
+
+	if len(rep.Config.Channels) > 0 {
+		return rep.Config.Channels
+	}
+
+	var channelList []string
+
+	for _, route := range r.routeList {
+		if !route.Matches(rep) {
+			continue
+		}
+
+		for _, channel := range route.Channels {
+			if !containsString(channelList, channel) {
+				channelList = append(channelList, channel)
+			}
+		}
+	}
+
+	if len(channelList) == 0 {
+		return r.defaultList
+	}
+
+	return channelList
+}
+
+// channelNames returns the names of the channels the router refers to.
+func (r *Router) channelNames() []string {
+	channelList := append([]string{}, r.defaultList...)
+
+	for _, route := range r.routeList {
+		channelList = append(channelList, route.Channels...)
+	}
+
+	return channelList
+}
+
+// Matches returns whether a route matches a repository, by its name or the owner of its remote.
+func (route Route) Matches(rep *models.Repository) bool {
+	for _, pattern := range route.Repositories {
+		if matched, _ := path.Match(pattern, rep.Name); matched {
+			return true
+		}
+	}
+
+	if len(route.Owners) == 0 {
+		return false
+	}
+
+	repo, err := vcs.ParseRemote(rep.Config.Remote)
+	if err != nil {
+		return false
+	}
+
+	for _, owner := range route.Owners {
+		owner = strings.Trim(owner, "/")
+		if strings.EqualFold(repo.Owner, owner) || strings.HasPrefix(strings.ToLower(repo.Owner), strings.ToLower(owner)+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_Channels(t *testing.T) {
+	router, err := NewRouter([]Route{
+		{Repositories: []string{"web-*"}, Channels: []string{"frontend"}},
+		{Owners: []string{"platform"}, Channels: []string{"platform", "frontend"}},
+		{Owners: []string{"PROJ"}, Repositories: []string{"tools"}, Channels: []string{"stash"}},
+	}, []string{"general"})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	tests := []struct {
+		rep      *models.Repository
+		channels []string
+	}{
+		{&models.Repository{Name: "web-app", Config: models.Config{Remote: "git@github.com:octo-org/web-app.git"}}, []string{"frontend"}},
+		{&models.Repository{Name: "widgets", Config: models.Config{Remote: "git@gitlab.example.com:platform/web/widgets.git"}}, []string{"platform", "frontend"}},
+		{&models.Repository{Name: "web-widgets", Config: models.Config{Remote: "https://gitlab.example.com/platform/web-widgets.git"}}, []string{"frontend", "platform"}},
+		{&models.Repository{Name: "api", Config: models.Config{Remote: "ssh://git@stash.example.com:7999/proj/api.git"}}, []string{"stash"}},
+		{&models.Repository{Name: "tools", Config: models.Config{Remote: "stuff"}}, []string{"stash"}},
+		{&models.Repository{Name: "api", Config: models.Config{Remote: "git@gitlab.example.com:platform-team/api.git"}}, []string{"general"}},
+		{&models.Repository{Name: "web-app", Config: models.Config{Channels: []string{"app-team"}}}, []string{"app-team"}},
+		{nil, []string{"general"}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.channels, router.Channels(test.rep), "%v", test.rep)
+	}
+
+	_, err = NewRouter([]Route{{Repositories: []string{"web-["}}}, nil)
+	assert.NotNil(t, err)
+}
+
+func TestDispatcher_Route(t *testing.T) {
+	logger := &recordingLogger{}
+	general := &recordingNotifier{}
+	frontend := &recordingNotifier{}
+
+	d := NewDispatcher(logger, 10, 1, time.Millisecond)
+	d.Add("general", general)
+	d.Add("frontend", frontend)
+
+	router, _ := NewRouter([]Route{{Repositories: []string{"web-*"}, Channels: []string{"frontend"}}}, nil)
+	assert.Nil(t, d.Route(router))
+
+	unknown, _ := NewRouter(nil, []string{"backend"})
+	assert.NotNil(t, d.Route(unknown))
+
+	d.Start()
+	d.DispatchFor(Event{Type: JobCreated, Job: &models.Job{Name: "web-app"}}, func() (*models.Repository, error) {
+		return &models.Repository{Name: "web-app"}, nil
+	})
+	d.Dispatch(Event{Type: JobCreated, Job: &models.Job{Name: "api"}, Repository: &models.Repository{Name: "api"}})
+	d.Dispatch(Event{Type: JobCreated, Job: &models.Job{Name: "lib"}, Repository: &models.Repository{
+		Name:   "lib",
+		Config: models.Config{Channels: []string{"backend"}},
+	}})
+	d.Close()
+
+	assert.Equal(t, 2, len(frontend.events), "routed, then to every channel")
+	if assert.Equal(t, 1, len(general.events)) {
+		assert.Equal(t, "api", general.events[0].Job.Name)
+	}
+
+	assert.Equal(t, []string{`delivering the job.created event of lib: unknown channel "backend"`}, logger.errors)
+}
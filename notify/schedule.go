@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Digest periods.
+const (
+	// Daily digests are sent every day.
+	Daily = "daily"
+	// Weekly digests are sent once a week.
+	Weekly = "weekly"
+)
+
+// Schedule is when digests are sent, every day or every week at a time of day.
+type Schedule struct {
+	Period  string
+	Weekday time.Weekday
+	// At is the time of day, from midnight.
+	At time.Duration
+}
+
+// ParseSchedule parses a schedule sending digests daily or weekly, on a weekday such as "monday", at a time of day
+// such as "08:00".
+func ParseSchedule(period string, weekday string, at string) (Schedule, error) {
+	schedule := Schedule{Period: period}
+
+	if period != Daily && period != Weekly {
+		return schedule, fmt.Errorf("invalid digest period %q, it must be %q or %q", period, Daily, Weekly)
+	}
+
+	timeOfDay, err := time.Parse("15:04", at)
+	if err != nil {
+		return schedule, fmt.Errorf("invalid digest time %q, it must be written as 15:04", at)
+	}
+
+	schedule.At = time.Duration(timeOfDay.Hour())*time.Hour + time.Duration(timeOfDay.Minute())*time.Minute
+
+	if period == Weekly {
+		found := false
+
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if strings.EqualFold(day.String(), weekday) {
+				schedule.Weekday, found = day, true
+			}
+		}
+
+		if !found {
+			return schedule, fmt.Errorf("invalid digest weekday %q", weekday)
+		}
+	}
+
+	return schedule, nil
+}
+
+// Next returns the first time digests are sent after a time, in the location of that time.
+func (s Schedule) Next(after time.Time) time.Time {
+	year, month, day := after.Date()
+	next := s.on(year, month, day, after.Location())
+
+	for !next.After(after) || (s.Period == Weekly && next.Weekday() != s.Weekday) {
+		day++
+		next = s.on(year, month, day, after.Location())
+	}
+
+	return next
+}
+
+// on returns the time of day of the schedule on a date, which stays the same across daylight saving time changes.
+func (s Schedule) on(year int, month time.Month, day int, location *time.Location) time.Time {
+	return time.Date(year, month, day, int(s.At/time.Hour), int(s.At%time.Hour/time.Minute), 0, 0, location)
+}
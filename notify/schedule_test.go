@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	daily, err := ParseSchedule("daily", "", "08:30")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	weekly, err := ParseSchedule("weekly", "Monday", "08:30")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// Saturday
+	now := time.Date(2018, 9, 1, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		schedule Schedule
+		after    time.Time
+		next     time.Time
+	}{
+		{daily, now, time.Date(2018, 9, 1, 8, 30, 0, 0, time.UTC)},
+		{daily, now.Add(30 * time.Minute), time.Date(2018, 9, 2, 8, 30, 0, 0, time.UTC)},
+		{daily, time.Date(2018, 12, 31, 23, 0, 0, 0, time.UTC), time.Date(2019, 1, 1, 8, 30, 0, 0, time.UTC)},
+		{weekly, now, time.Date(2018, 9, 3, 8, 30, 0, 0, time.UTC)},
+		{weekly, time.Date(2018, 9, 3, 8, 30, 0, 0, time.UTC), time.Date(2018, 9, 10, 8, 30, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.next, test.schedule.Next(test.after), "%s after %v", test.schedule.Period, test.after)
+	}
+
+	// the time of day stays the same when the clocks change
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err == nil {
+		assert.Equal(t, time.Date(2018, 10, 28, 8, 30, 0, 0, paris), daily.Next(time.Date(2018, 10, 27, 9, 0, 0, 0, paris)))
+	}
+
+	_, err = ParseSchedule("monthly", "", "08:30")
+	assert.NotNil(t, err)
+
+	_, err = ParseSchedule("daily", "", "8am")
+	assert.NotNil(t, err)
+
+	_, err = ParseSchedule("weekly", "someday", "08:30")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Slack posts messages laid out with Block Kit to the channel of a Slack incoming webhook.
+type Slack struct {
+	client     *http.Client
+	webhookURL string
+}
+
+// SlackError is an error response of a Slack incoming webhook, such as "invalid_payload" or "channel_not_found".
+type SlackError struct {
+	StatusCode int
+	Message    string
+}
+
+// slackText is a text object of Block Kit.
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// NewSlack returns a client of the incoming webhook at a URL such as "https://hooks.slack.com/services/T0/B0/XXXX".
+func NewSlack(client *http.Client, webhookURL string) *Slack {
+	return &Slack{client, webhookURL}
+}
+
+// Notify posts an event with the repository, the state and the dependencies of its job, and a button linking to its
+// pull request. The plain message is the fallback of clients that do not show blocks.
+func (s *Slack) Notify(event Event) error {
+	fieldList := []slackText{
+		{"mrkdwn", "*Repository*\n" + escapeSlack(event.Job.Name)},
+		{"mrkdwn", "*State*\n" + StateName(event.Job.State)},
+	}
+
+	var depList []string
+	for _, dep := range event.Job.Dependencies {
+		depList = append(depList, escapeSlack(dep.Name+" "+dep.Version))
+	}
+
+	if len(depList) > 0 {
+		fieldList = append(fieldList, slackText{"mrkdwn", "*Dependencies*\n" + strings.Join(depList, "\n")})
+	}
+
+	blockList := []map[string]interface{}{
+		{"type": "section", "text": slackText{"mrkdwn", "*" + escapeSlack(event.Message()) + "*"}},
+		{"type": "section", "fields": fieldList},
+	}
+
+	if url := pullRequestURL(event.Job); url != "" {
+		blockList = append(blockList, map[string]interface{}{
+			"type": "actions",
+			"elements": []map[string]interface{}{
+				{"type": "button", "text": slackText{"plain_text", "View pull request"}, "url": url},
+			},
+		})
+	}
+
+	return postJSON(s.client, s.webhookURL, nil, map[string]interface{}{"text": event.Message(), "blocks": blockList}, newSlackError)
+}
+
+// escapeSlack escapes the characters Slack reserves for links and mentions in mrkdwn text.
+func escapeSlack(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+func newSlackError(res *http.Response) error {
+	return &SlackError{StatusCode: res.StatusCode, Message: readErrorText(res)}
+}
+
+func (e *SlackError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Slack webhook responded with %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("Slack webhook responded with %d: %s", e.StatusCode, e.Message)
+}
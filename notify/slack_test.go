@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlack_Notify(t *testing.T) {
+	var payloadList []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/T0/B0/XXXX" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("no_service"))
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		payloadList = append(payloadList, string(body))
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	job := &models.Job{
+		Name:         "app",
+		State:        models.Succeeded,
+		Dependencies: []*models.PublishedDependency{{Name: "lib", Version: "1.2.0"}, {Name: "<other>", Version: "2.0.0"}},
+		PullRequest:  &models.PullRequest{URL: "https://github.com/octo-org/app/pull/1"},
+	}
+
+	slack := NewSlack(server.Client(), server.URL+"/services/T0/B0/XXXX")
+	assert.Nil(t, slack.Notify(Event{Type: PullRequestOpened, Job: job}))
+	assert.Nil(t, slack.Notify(Event{Type: JobLocked, Job: &models.Job{Name: "lib", State: models.Locked, BlockedBy: []string{"core"}}}))
+
+	if assert.Equal(t, 2, len(payloadList)) {
+		assert.JSONEq(t, `{
+			"text": "Pull request for app opened: https://github.com/octo-org/app/pull/1",
+			"blocks": [
+				{"type": "section", "text": {"type": "mrkdwn", "text": "*Pull request for app opened: https://github.com/octo-org/app/pull/1*"}},
+				{"type": "section", "fields": [
+					{"type": "mrkdwn", "text": "*Repository*\napp"},
+					{"type": "mrkdwn", "text": "*State*\nsucceeded"},
+					{"type": "mrkdwn", "text": "*Dependencies*\nlib 1.2.0\n&lt;other&gt; 2.0.0"}
+				]},
+				{"type": "actions", "elements": [
+					{"type": "button", "text": {"type": "plain_text", "text": "View pull request"}, "url": "https://github.com/octo-org/app/pull/1"}
+				]}
+			]
+		}`, payloadList[0])
+
+		blocks := struct {
+			Blocks []map[string]interface{} `json:"blocks"`
+		}{}
+		json.Unmarshal([]byte(payloadList[1]), &blocks)
+		assert.Equal(t, 2, len(blocks.Blocks), "no pull request to link")
+	}
+
+	err := NewSlack(server.Client(), server.URL+"/services/T0/B0/YYYY").Notify(Event{Type: JobCreated, Job: job})
+	if assert.IsType(t, &SlackError{}, err) {
+		assert.Equal(t, "Slack webhook responded with 404: no_service", err.Error())
+	}
+}
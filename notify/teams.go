@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Teams posts Adaptive Cards to the channel of a Microsoft Teams incoming webhook.
+type Teams struct {
+	client     *http.Client
+	webhookURL string
+}
+
+// TeamsError is an error response of a Teams incoming webhook.
+type TeamsError struct {
+	StatusCode int
+	Message    string
+}
+
+// teamsFact is a fact of a FactSet.
+type teamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// eventTextColors are the colors of the titles of the cards of every type of event, the default color for the others.
+var eventTextColors = map[EventType]string{
+	JobLocked:         "Warning",
+	PullRequestOpened: "Good",
+	JobFailed:         "Attention",
+	JobDeadLettered:   "Attention",
+}
+
+// NewTeams returns a client of the incoming webhook of a Teams channel at a URL.
+func NewTeams(client *http.Client, webhookURL string) *Teams {
+	return &Teams{client, webhookURL}
+}
+
+// Notify posts an event as an Adaptive Card with the repository, the state and the dependencies of its job as facts,
+// and an action opening its pull request.
+func (t *Teams) Notify(event Event) error {
+	factList := []teamsFact{
+		{"Repository", event.Job.Name},
+		{"State", StateName(event.Job.State)},
+	}
+
+	if deps := Dependencies(event.Job); deps != "" {
+		factList = append(factList, teamsFact{"Dependencies", deps})
+	}
+
+	title := map[string]interface{}{
+		"type":   "TextBlock",
+		"text":   event.Message(),
+		"size":   "Medium",
+		"weight": "Bolder",
+		"wrap":   true,
+	}
+
+	if color, ok := eventTextColors[event.Type]; ok {
+		title["color"] = color
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    []interface{}{title, map[string]interface{}{"type": "FactSet", "facts": factList}},
+	}
+
+	if url := pullRequestURL(event.Job); url != "" {
+		card["actions"] = []map[string]string{{"type": "Action.OpenUrl", "title": "View pull request", "url": url}}
+	}
+
+	payload := map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+
+	return postJSON(t.client, t.webhookURL, nil, payload, newTeamsError)
+}
+
+func newTeamsError(res *http.Response) error {
+	return &TeamsError{StatusCode: res.StatusCode, Message: readErrorText(res)}
+}
+
+func (e *TeamsError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Teams webhook responded with %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("Teams webhook responded with %d: %s", e.StatusCode, e.Message)
+}
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeams_Notify(t *testing.T) {
+	var payloadList []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Bad payload received by generic incoming webhook."))
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		payloadList = append(payloadList, string(body))
+		w.Write([]byte("1"))
+	}))
+	defer server.Close()
+
+	job := &models.Job{
+		Name:         "app",
+		State:        models.DeadLetter,
+		Dependencies: []*models.PublishedDependency{{Name: "lib", Version: "1.2.0"}, {Name: "other", Version: "2.0.0"}},
+		Attempts:     []*models.Attempt{{Worker: "worker-1", Error: "npm install failed"}},
+	}
+
+	teams := NewTeams(server.Client(), server.URL+"/webhookb2/XXXX")
+	assert.Nil(t, teams.Notify(Event{Type: JobDeadLettered, Job: job}))
+
+	job.State = models.Succeeded
+	job.PullRequest = &models.PullRequest{URL: "https://gitlab.example.com/web/app/-/merge_requests/1"}
+	assert.Nil(t, teams.Notify(Event{Type: JobCreated, Job: job}))
+
+	if assert.Equal(t, 2, len(payloadList)) {
+		assert.JSONEq(t, `{
+			"type": "message",
+			"attachments": [{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": {
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type": "AdaptiveCard",
+					"version": "1.4",
+					"body": [
+						{"type": "TextBlock", "text": "Job for app is out of attempts: npm install failed", "size": "Medium", "weight": "Bolder", "wrap": true, "color": "Attention"},
+						{"type": "FactSet", "facts": [
+							{"title": "Repository", "value": "app"},
+							{"title": "State", "value": "dead-lettered"},
+							{"title": "Dependencies", "value": "lib 1.2.0, other 2.0.0"}
+						]}
+					]
+				}
+			}]
+		}`, payloadList[0])
+		assert.Contains(t, payloadList[1], `"actions":[{"title":"View pull request","type":"Action.OpenUrl","url":"https://gitlab.example.com/web/app/-/merge_requests/1"}]`)
+		assert.NotContains(t, payloadList[1], `"color"`)
+	}
+
+	req, _ := http.NewRequest("POST", server.URL, nil)
+	res, _ := server.Client().Do(req)
+	err := newTeamsError(res)
+	assert.Equal(t, "Teams webhook responded with 400: Bad payload received by generic incoming webhook.", err.Error())
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// postJSON posts a JSON payload, reading the error of a response that is not successful with newError.
+func postJSON(client *http.Client, url string, header http.Header, payload interface{}, newError func(*http.Response) error) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	for name, valueList := range header {
+		req.Header[name] = valueList
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return newError(res)
+	}
+
+	return nil
+}
+
+// readErrorText reads the plain text body incoming webhooks respond to bad requests with.
+func readErrorText(res *http.Response) string {
+	body, _ := ioutil.ReadAll(res.Body)
+
+	return strings.TrimSpace(string(body))
+}
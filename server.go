@@ -6,17 +6,21 @@ import (
 	"fmt"
 	"github.com/mongodb/mongo-go-driver/mongo"
 	"net/http"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/docopt/docopt-go"
 	"github.com/go-ozzo/ozzo-routing"
 	"github.com/go-ozzo/ozzo-routing/content"
 	"github.com/go-ozzo/ozzo-routing/cors"
+	"github.com/quantumew/data-access"
 	"github.com/quantumew/data-access/daos"
 	"github.com/quantumew/listener/apis"
 	"github.com/quantumew/listener/app"
 	"github.com/quantumew/listener/errors"
+	"github.com/quantumew/listener/notify"
 	"github.com/quantumew/listener/services"
+	"github.com/quantumew/listener/vcs"
 )
 
 func main() {
@@ -56,8 +60,23 @@ Options:
 
 	db := client.Database(app.Config.DB.Name)
 
+	// Every service shares the cache so that repository writes drop the graph hooks are fanned out with.
+	repoDAO := services.NewGraphCache(daos.NewRepositoryDAO(), app.Config.Graph.CacheTTL)
+	jobService := services.NewJobService(daos.NewJobDAO(), repoDAO)
+	if hosts := buildHosts(app.Config); hosts.Len() > 0 {
+		jobService.EnablePullRequests(buildPullRequestService(app.Config, hosts, repoDAO))
+	}
+	if notifications := buildDispatcher(logger, app.Config); notifications.Len() > 0 {
+		notifications.Start()
+		jobService.EnableNotifications(notifications)
+	}
+	go sweepJobs(logger, jobService, db)
+	if app.Config.Digest.Schedule != "" {
+		go sendDigests(logger, buildDigestService(app.Config, repoDAO), db)
+	}
+
 	// wire up API routing
-	http.Handle("/", buildRouter(logger, db))
+	http.Handle("/", buildRouter(logger, db, jobService, repoDAO))
 
 	// start the server
 	address := fmt.Sprintf(":%v", app.Config.Port)
@@ -75,7 +94,139 @@ func buildDBHost(config app.AppConfig) string {
 	return fmt.Sprintf("mongodb://%s%s:%d", prefix, config.DB.Host, config.DB.Port)
 }
 
-func buildRouter(logger *logrus.Logger, db *mongo.Database) *routing.Router {
+// buildHosts builds the clients of the configured version control services.
+func buildHosts(config app.AppConfig) *vcs.Hosts {
+	hosts := &vcs.Hosts{}
+
+	for _, vcConfig := range config.VersionControl {
+		if vcConfig.URL == "" {
+			continue
+		}
+
+		vc, err := vcs.New(vcConfig.ServiceName, vcConfig.URL, vcConfig.ClientID, vcConfig.ClientSecret)
+		if err != nil {
+			panic(fmt.Errorf("Invalid version control configuration: %s", err))
+		}
+
+		name := vcConfig.Name
+		if name == "" {
+			name = vcConfig.ServiceName
+		}
+
+		hosts.Add(name, vcConfig.URL, vc)
+	}
+
+	return hosts
+}
+
+// buildPullRequestService builds the service opening the pull requests of completed jobs on the version control
+// services repositories are hosted on.
+func buildPullRequestService(config app.AppConfig, hosts *vcs.Hosts, repoDAO access.RepositoryDAO) *services.PullRequestService {
+	templates, err := services.NewPullRequestTemplates(config.PullRequests.Title, config.PullRequests.Body, config.PullRequests.Changelogs)
+	if err != nil {
+		panic(fmt.Errorf("Invalid pull request templates: %s", err))
+	}
+
+	return services.NewPullRequestService(daos.NewJobDAO(), repoDAO, hosts, templates, config.PullRequests.BranchPrefix, config.PullRequests.Labels)
+}
+
+// buildDispatcher builds the dispatcher delivering job events to the configured chat channels.
+func buildDispatcher(logger *logrus.Logger, config app.AppConfig) *notify.Dispatcher {
+	notifications := notify.NewDispatcher(
+		logger,
+		config.Notifications.QueueSize,
+		config.Notifications.MaxAttempts,
+		config.Notifications.BaseDelay,
+	)
+
+	if config.Messaging.URL != "" {
+		notifier, err := notify.New(
+			config.Messaging.ServiceName,
+			config.Messaging.URL,
+			config.Messaging.ClientID,
+			config.Messaging.ClientSecret,
+		)
+		if err != nil {
+			panic(fmt.Errorf("Invalid messaging configuration: %s", err))
+		}
+
+		notifications.Add(config.Messaging.ServiceName, notifier)
+	}
+
+	for _, channel := range config.Notifications.Channels {
+		notifier, err := notify.New(channel.ServiceName, channel.URL, channel.ClientID, channel.ClientSecret)
+		if err != nil {
+			panic(fmt.Errorf("Invalid notification channel %s: %s", channel.Name, err))
+		}
+
+		notifications.Add(channel.Name, notifier)
+	}
+
+	var routeList []notify.Route
+	for _, route := range config.Notifications.Routes {
+		routeList = append(routeList, notify.Route{Repositories: route.Repositories, Owners: route.Owners, Channels: route.Channels})
+	}
+
+	router, err := notify.NewRouter(routeList, config.Notifications.DefaultChannels)
+	if err == nil {
+		err = notifications.Route(router)
+	}
+
+	if err != nil {
+		panic(fmt.Errorf("Invalid notification routes: %s", err))
+	}
+
+	return notifications
+}
+
+// sweepJobs periodically requeues jobs whose worker lease expired and queues idle jobs.
+func sweepJobs(logger *logrus.Logger, jobService *services.JobService, db *mongo.Database) {
+	for now := range time.Tick(app.Config.Worker.SweepInterval) {
+		if err := jobService.Sweep(db, now); err != nil {
+			logger.Errorf("Failed to sweep jobs: %s", err)
+		}
+	}
+}
+
+// buildDigestService builds the service emailing the digests of jobs and repositories.
+func buildDigestService(config app.AppConfig, repoDAO access.RepositoryDAO) *services.DigestService {
+	schedule, err := notify.ParseSchedule(config.Digest.Schedule, config.Digest.Weekday, config.Digest.Time)
+	if err != nil {
+		panic(fmt.Errorf("Invalid digest configuration: %s", err))
+	}
+
+	templates, err := notify.NewDigestTemplates(config.Digest.Text, config.Digest.HTML)
+	if err != nil {
+		panic(fmt.Errorf("Invalid digest templates: %s", err))
+	}
+
+	var recipientList []notify.Recipient
+	for _, recipient := range config.Digest.Recipients {
+		recipientList = append(recipientList, notify.Recipient{
+			To:    recipient.To,
+			Route: notify.Route{Repositories: recipient.Repositories, Owners: recipient.Owners},
+		})
+	}
+
+	smtpConfig := config.Digest.SMTP
+	email := notify.NewEmail(fmt.Sprintf("%s:%d", smtpConfig.Host, smtpConfig.Port), smtpConfig.Username, smtpConfig.Password, smtpConfig.From)
+
+	return services.NewDigestService(daos.NewJobDAO(), repoDAO, notify.NewDigestMailer(email, templates, recipientList), schedule)
+}
+
+// sendDigests emails the digests of jobs and repositories on their schedule.
+func sendDigests(logger *logrus.Logger, digestService *services.DigestService, db *mongo.Database) {
+	for {
+		next := digestService.Schedule().Next(time.Now())
+		time.Sleep(time.Until(next))
+
+		if err := digestService.Send(db, next); err != nil {
+			logger.Errorf("Failed to send the digests: %s", err)
+		}
+	}
+}
+
+func buildRouter(logger *logrus.Logger, db *mongo.Database, jobService *services.JobService, repoDAO access.RepositoryDAO) *routing.Router {
 	router := routing.New()
 
 	router.To("GET,HEAD", "/heartbeat", func(c *routing.Context) error {
@@ -95,11 +246,14 @@ func buildRouter(logger *logrus.Logger, db *mongo.Database) *routing.Router {
 
 	rg := router.Group("/v1")
 
-	repoDAO := daos.NewRepositoryDAO()
 	repoService := services.NewRepositoryService(repoDAO)
 	apis.ServeRepositoryResource(rg, repoService)
-	jobDAO := daos.NewJobDAO()
-	apis.ServeJobResource(rg, services.NewJobService(jobDAO, repoDAO), repoService)
+	apis.ServeJobResource(rg, jobService, repoService, app.Config.Npm.Secrets)
+	apis.ServeGitHubResource(rg, jobService, app.Config.GitHub.Secrets)
+	apis.ServeGitLabResource(rg, jobService, app.Config.GitLab.Tokens)
+	apis.ServeHookResource(rg, jobService, app.Config.Hooks.Secrets)
+	apis.ServeArtifactResource(rg, jobService, app.Config.Nexus.Secrets, app.Config.Artifactory.Tokens)
+	apis.ServeGraphResource(rg, services.NewGraphService(repoDAO))
 
 	return router
 }
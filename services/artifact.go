@@ -0,0 +1,102 @@
+package services
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/app"
+)
+
+// snapshotSuffix ends the versions of Maven snapshots, which are rebuilt in place and never published to dependents.
+const snapshotSuffix = "-SNAPSHOT"
+
+// nexusEvent is the part of a Nexus Repository Manager component webhook delivery the listener reads.
+type nexusEvent struct {
+	Action    string `json:"action"`
+	Component *struct {
+		Format  string `json:"format"`
+		Group   string `json:"group"`
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"component"`
+}
+
+// artifactoryEvent is the part of an Artifactory artifact webhook delivery the listener reads.
+type artifactoryEvent struct {
+	Domain    string `json:"domain"`
+	EventType string `json:"event_type"`
+	Data      *struct {
+		RepoKey string `json:"repo_key"`
+		Path    string `json:"path"`
+	} `json:"data"`
+}
+
+// CreateJobsFromNexus creates or updates the jobs of every repository a Nexus component webhook publishes a new Maven
+// artifact for. Only created maven2 components that are not snapshots count as publishes.
+func (s *JobService) CreateJobsFromNexus(rs app.RequestScope, payload []byte) (*HookResult, error) {
+	var event nexusEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, validation.Errors{"payload": err}
+	}
+
+	var pubList []*models.PublishedDependency
+
+	if component := event.Component; event.Action == "CREATED" && component != nil && component.Format == "maven2" {
+		if pub := mavenPublish(component.Group, component.Name, component.Version); pub != nil {
+			pubList = append(pubList, pub)
+		}
+	}
+
+	return s.publishAll(rs, pubList)
+}
+
+// CreateJobsFromArtifactory creates or updates the jobs of every repository an Artifactory artifact webhook publishes
+// a new Maven artifact for. A deployment uploads several files per version, only the deployed POM counts as a publish.
+func (s *JobService) CreateJobsFromArtifactory(rs app.RequestScope, payload []byte) (*HookResult, error) {
+	var event artifactoryEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, validation.Errors{"payload": err}
+	}
+
+	var pubList []*models.PublishedDependency
+
+	if event.Domain == "artifact" && event.EventType == "deployed" && event.Data != nil {
+		if pub := ParseArtifactPath(event.Data.Path); pub != nil {
+			pubList = append(pubList, pub)
+		}
+	}
+
+	return s.publishAll(rs, pubList)
+}
+
+// ParseArtifactPath returns the Maven artifact a POM deployed under a Maven repository layout path publishes, such as
+// "org/example/lib/1.2.0/lib-1.2.0.pom", or nil if the path is not the POM of a release.
+func ParseArtifactPath(artifactPath string) *models.PublishedDependency {
+	partList := strings.Split(strings.Trim(artifactPath, "/"), "/")
+	if len(partList) < 4 {
+		return nil
+	}
+
+	n := len(partList)
+	file, version, artifactID := partList[n-1], partList[n-2], partList[n-3]
+
+	if path.Ext(file) != ".pom" || !strings.HasPrefix(file, artifactID+"-"+version) {
+		return nil
+	}
+
+	return mavenPublish(strings.Join(partList[:n-3], "."), artifactID, version)
+}
+
+// mavenPublish returns the published version of a Maven artifact, or nil if it is a snapshot or not a valid one.
+func mavenPublish(groupID string, artifactID string, version string) *models.PublishedDependency {
+	name := MavenCoordinates(groupID, artifactID)
+
+	if version == "" || strings.HasSuffix(version, snapshotSuffix) || (mavenEcosystem{}).CheckName(name) != nil {
+		return nil
+	}
+
+	return &models.PublishedDependency{Ecosystem: EcosystemMaven, Name: name, Version: version}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newArtifactJobService() (*JobService, *mockJobDAO) {
+	app := createRepository("app", "com.example:widgets", "[1.0,2.0)", "1.2.0")
+	app.Ecosystem = EcosystemMaven
+	jobDAO := &mockJobDAO{}
+	repDAO := &mockRepositoryDAO{records: []*models.Repository{app, createRepository("web", "widgets", "^1.0.0", "1.2.0")}}
+	return NewJobService(jobDAO, repDAO), jobDAO
+}
+
+func TestJobService_CreateJobsFromNexus(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		published bool
+	}{
+		{"nexus/component_created.json", true},
+		{"nexus/component_snapshot.json", false},
+		{"nexus/component_deleted.json", false},
+		{"nexus/component_npm.json", false},
+	}
+
+	for _, test := range tests {
+		s, jobDAO := newArtifactJobService()
+
+		result, err := s.CreateJobsFromNexus(new(MockRequestScope), readFixture(t, test.fixture))
+		if !assert.Nil(t, err, test.fixture) {
+			continue
+		}
+
+		if !test.published {
+			assert.Empty(t, result.Jobs, test.fixture)
+			continue
+		}
+
+		if assert.Equal(t, 1, len(result.Jobs), test.fixture) {
+			assert.Equal(t, "app", result.Jobs[0].Name)
+			assert.Equal(t, &models.PublishedDependency{Ecosystem: EcosystemMaven, Name: "com.example:widgets", Version: "1.4.0"}, jobDAO.records[0].Dependencies[0])
+		}
+	}
+
+	s, _ := newArtifactJobService()
+	_, err := s.CreateJobsFromNexus(new(MockRequestScope), []byte("{"))
+	assert.NotNil(t, err)
+}
+
+func TestJobService_CreateJobsFromArtifactory(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		published bool
+	}{
+		{"artifactory/deployed_pom.json", true},
+		{"artifactory/deployed_jar.json", false},
+		{"artifactory/deleted_pom.json", false},
+	}
+
+	for _, test := range tests {
+		s, jobDAO := newArtifactJobService()
+
+		result, err := s.CreateJobsFromArtifactory(new(MockRequestScope), readFixture(t, test.fixture))
+		if !assert.Nil(t, err, test.fixture) {
+			continue
+		}
+
+		if !test.published {
+			assert.Empty(t, result.Jobs, test.fixture)
+			continue
+		}
+
+		if assert.Equal(t, 1, len(result.Jobs), test.fixture) {
+			assert.Equal(t, "app", result.Jobs[0].Name)
+			assert.Equal(t, &models.PublishedDependency{Ecosystem: EcosystemMaven, Name: "com.example:widgets", Version: "1.4.0"}, jobDAO.records[0].Dependencies[0])
+		}
+	}
+}
+
+func TestParseArtifactPath(t *testing.T) {
+	assert.Equal(t, &models.PublishedDependency{Ecosystem: EcosystemMaven, Name: "org.example.tools:cli", Version: "2.0-beta-1"},
+		ParseArtifactPath("/org/example/tools/cli/2.0-beta-1/cli-2.0-beta-1.pom"))
+
+	for _, artifactPath := range []string{
+		"org/example/cli/1.0/cli-1.0.jar",
+		"org/example/cli/1.0-SNAPSHOT/cli-1.0-20190521.144110-1.pom",
+		"org/example/cli/maven-metadata.xml",
+		"org/example/cli/1.0/other-1.0.pom",
+		"cli/1.0/cli-1.0.pom",
+	} {
+		assert.Nil(t, ParseArtifactPath(artifactPath), artifactPath)
+	}
+}
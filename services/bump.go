@@ -0,0 +1,115 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// bumpedFields are the fields of a package.json whose ranges are bumped. Peer dependencies are left alone, they state
+// which versions a package works with rather than the one it uses.
+var bumpedFields = map[string]bool{"dependencies": true, "devDependencies": true, "optionalDependencies": true}
+
+// pinnedRangeRegexp matches the ranges pinning a version with an optional operator, such as "^1.2.0" or "1.2.0".
+var pinnedRangeRegexp = regexp.MustCompile(`^(\^|~|=|>=)?(\s*v?)\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?$`)
+
+// manifestEdit replaces the bytes of a manifest between two offsets.
+type manifestEdit struct {
+	start, end int64
+	value      string
+}
+
+// BumpRange returns the range a dependency declaring a range should declare to require a version. Ranges pinning a
+// version keep their operator, so "^1.0.0" becomes "^1.2.0" for 1.2.0. Other ranges such as ">=1.0.0 <2.0.0" already
+// allow the version, the dependency being queued only then, and are left as they are.
+func BumpRange(rangeStr string, version string) string {
+	match := pinnedRangeRegexp.FindStringSubmatch(rangeStr)
+	if match == nil {
+		return rangeStr
+	}
+
+	return match[1] + match[2] + version
+}
+
+// BumpPackageJSON bumps the range of a dependency in every field of a package.json declaring it, leaving the rest of
+// the file as it was written.
+func BumpPackageJSON(content []byte, name string, version string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	var editList []manifestEdit
+
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("package.json is not an object")
+	}
+
+	for dec.More() {
+		field, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		if !bumpedFields[fmt.Sprint(field)] {
+			if err := dec.Decode(&json.RawMessage{}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		fieldEditList, err := bumpDependencyField(dec, content, name, version)
+		if err != nil {
+			return nil, fmt.Errorf("%s of package.json: %s", field, err)
+		}
+
+		editList = append(editList, fieldEditList...)
+	}
+
+	// Edits are made from the end so the offsets of the others stay valid.
+	bumped := append([]byte{}, content...)
+	for i := len(editList) - 1; i >= 0; i-- {
+		edit := editList[i]
+		bumped = append(bumped[:edit.start], append([]byte(edit.value), bumped[edit.end:]...)...)
+	}
+
+	return bumped, nil
+}
+
+// bumpDependencyField returns the edits bumping the range of a dependency in the dependency field the decoder is at.
+func bumpDependencyField(dec *json.Decoder, content []byte, name string, version string) ([]manifestEdit, error) {
+	var editList []manifestEdit
+
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("not an object")
+	}
+
+	for dec.More() {
+		dependency, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		// The decoder stops right after the name, before the colon and the range.
+		start := dec.InputOffset()
+		for start < int64(len(content)) && bytes.IndexByte([]byte(" \t\r\n:"), content[start]) >= 0 {
+			start++
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+
+		var rangeStr string
+		if dependency != name || json.Unmarshal(raw, &rangeStr) != nil {
+			continue
+		}
+
+		if bumped := BumpRange(rangeStr, version); bumped != rangeStr {
+			editList = append(editList, manifestEdit{start, dec.InputOffset(), strconv.Quote(bumped)})
+		}
+	}
+
+	_, err := dec.Token()
+
+	return editList, err
+}
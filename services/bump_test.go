@@ -0,0 +1,75 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBumpRange(t *testing.T) {
+	tests := []struct {
+		rangeStr string
+		expected string
+	}{
+		{"^1.0.0", "^1.2.0"},
+		{"~1.1.3", "~1.2.0"},
+		{"1.1.0", "1.2.0"},
+		{"=1.1.0", "=1.2.0"},
+		{">=1.0.0", ">=1.2.0"},
+		{"^ v1.0.0-beta.1", "^ v1.2.0"},
+		{">=1.0.0 <2.0.0", ">=1.0.0 <2.0.0"},
+		{"1.x", "1.x"},
+		{"*", "*"},
+		{"", ""},
+		{"github:org/lib#v1.0.0", "github:org/lib#v1.0.0"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, BumpRange(test.rangeStr, "1.2.0"), test.rangeStr)
+	}
+}
+
+func TestBumpPackageJSON(t *testing.T) {
+	content := `{
+  "name": "app",
+  "scripts": {"lib": "echo \"lib\""},
+  "dependencies": {
+    "other": "^1.0.0",
+    "lib" :  "^1.0.0"
+  },
+  "devDependencies": {"lib": "~1.1.0", "nested": {"lib": "^1.0.0"}},
+  "peerDependencies": {
+    "lib": "^1.0.0"
+  }
+}
+`
+	expected := `{
+  "name": "app",
+  "scripts": {"lib": "echo \"lib\""},
+  "dependencies": {
+    "other": "^1.0.0",
+    "lib" :  "^1.2.0"
+  },
+  "devDependencies": {"lib": "~1.2.0", "nested": {"lib": "^1.0.0"}},
+  "peerDependencies": {
+    "lib": "^1.0.0"
+  }
+}
+`
+
+	bumped, err := BumpPackageJSON([]byte(content), "lib", "1.2.0")
+	if assert.Nil(t, err) {
+		assert.Equal(t, expected, string(bumped))
+	}
+
+	bumped, err = BumpPackageJSON([]byte(content), "missing", "1.2.0")
+	if assert.Nil(t, err) {
+		assert.Equal(t, content, string(bumped))
+	}
+
+	_, err = BumpPackageJSON([]byte(`[]`), "lib", "1.2.0")
+	assert.NotNil(t, err)
+
+	_, err = BumpPackageJSON([]byte(`{"dependencies": ["lib"]}`), "lib", "1.2.0")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/quantumew/data-access"
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/notify"
+)
+
+// digestPendingStateList are the states of the jobs digests list as pending.
+var digestPendingStateList = []models.State{models.Idle, models.Queued, models.Locked, models.InProgress}
+
+// digestFailedStateList are the states of the jobs digests list as failed.
+var digestFailedStateList = []models.State{models.Failed, models.DeadLetter}
+
+// DigestService builds the digests of pending and failed jobs and of repositories falling behind, and emails them.
+type DigestService struct {
+	dao      access.JobDAO
+	repDao   access.RepositoryDAO
+	mailer   *notify.DigestMailer
+	schedule notify.Schedule
+}
+
+// NewDigestService creates a new DigestService emailing digests with the mailer on the schedule.
+func NewDigestService(dao access.JobDAO, repDao access.RepositoryDAO, mailer *notify.DigestMailer, schedule notify.Schedule) *DigestService {
+	return &DigestService{dao, repDao, mailer, schedule}
+}
+
+// Schedule returns when digests are sent.
+func (s *DigestService) Schedule() notify.Schedule {
+	return s.schedule
+}
+
+// Send builds the digest and emails it to its recipients.
+func (s *DigestService) Send(db *mongo.Database, now time.Time) error {
+	digest, err := s.Build(db, now)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(digest)
+}
+
+// Build builds the digest of the jobs that are pending or failed, and of the dependencies on packages published by
+// registered repositories that are installed at an older version than the latest one.
+func (s *DigestService) Build(db *mongo.Database, now time.Time) (*notify.Digest, error) {
+	repList, err := queryAllRepositories(db, s.repDao)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := &notify.Digest{Period: s.schedule.Period, Time: now, Repositories: map[string]*models.Repository{}}
+	for _, rep := range repList {
+		digest.Repositories[rep.Name] = rep
+	}
+
+	if digest.Pending, err = s.queryByStates(db, digestPendingStateList); err != nil {
+		return nil, err
+	}
+
+	if digest.Failed, err = s.queryByStates(db, digestFailedStateList); err != nil {
+		return nil, err
+	}
+
+	// The distinct versions jobs were queued with are far fewer than the jobs themselves.
+	publishedList, err := s.dao.QueryPublished(db)
+	if err != nil {
+		return nil, err
+	}
+
+	digest.Outdated = OutdatedDependencies(NewGraph(repList), publishedList)
+	digest.Sort()
+
+	return digest, nil
+}
+
+// queryByStates returns every job in one of the states.
+func (s *DigestService) queryByStates(db *mongo.Database, stateList []models.State) ([]*models.Job, error) {
+	var jobList []*models.Job
+
+	for _, state := range stateList {
+		count, err := s.dao.CountByState(db, state)
+		if err != nil {
+			return nil, err
+		}
+
+		stateJobList, err := s.dao.QueryByState(db, state, 0, int(count))
+		if err != nil {
+			return nil, err
+		}
+
+		jobList = append(jobList, stateJobList...)
+	}
+
+	return jobList, nil
+}
+
+// OutdatedDependencies returns the dependencies of the repositories of a graph on the packages other repositories of
+// the graph publish that are installed at an older version than the latest one. The latest version of a package is
+// the newest one of the published versions jobs were queued with, or that a repository has installed.
+func OutdatedDependencies(g *Graph, publishedList []*models.PublishedDependency) []notify.OutdatedDependency {
+	latestMap := map[string]*models.PublishedDependency{}
+
+	addVersion := func(candidate *models.PublishedDependency) {
+		key := PackageKey(candidate.Ecosystem, candidate.Name)
+		if _, ok := g.Publisher(key); !ok || !isValidVersion(candidate) {
+			return
+		}
+
+		if latest, ok := latestMap[key]; !ok || isNewerVersion(candidate, latest.Version) {
+			latestMap[key] = candidate
+		}
+	}
+
+	for _, published := range publishedList {
+		addVersion(published)
+	}
+
+	for _, name := range g.Repositories() {
+		rep, _ := g.Repository(name)
+		for _, dep := range rep.Dependencies {
+			addVersion(&models.PublishedDependency{Ecosystem: DependencyEcosystem(rep, dep), Name: dep.Name, Version: dep.Installed})
+		}
+	}
+
+	var outdatedList []notify.OutdatedDependency
+
+	for _, name := range g.Repositories() {
+		rep, _ := g.Repository(name)
+
+		for _, dep := range rep.Dependencies {
+			installed := &models.PublishedDependency{Ecosystem: DependencyEcosystem(rep, dep), Name: dep.Name, Version: dep.Installed}
+			key := PackageKey(installed.Ecosystem, installed.Name)
+
+			// Repositories depending on their own packages are updated by their own releases.
+			if publisher, _ := g.Publisher(key); publisher == name {
+				continue
+			}
+
+			latest, ok := latestMap[key]
+			if !ok || !isValidVersion(installed) || !isNewerVersion(latest, installed.Version) {
+				continue
+			}
+
+			outdatedList = append(outdatedList, notify.OutdatedDependency{
+				Repository: name,
+				Ecosystem:  normalizeEcosystem(installed.Ecosystem),
+				Package:    dep.Name,
+				Installed:  dep.Installed,
+				Latest:     latest.Version,
+			})
+		}
+	}
+
+	return outdatedList
+}
+
+// isValidVersion returns whether the version of a dependency can be compared with the other versions of its
+// ecosystem.
+func isValidVersion(dep *models.PublishedDependency) bool {
+	ecosystem, err := LookupEcosystem(dep.Ecosystem)
+	if err != nil {
+		return false
+	}
+
+	_, err = ecosystem.ParseVersion(dep.Version)
+
+	return err == nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/notify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutdatedDependencies(t *testing.T) {
+	lib := createRepository("lib", "core", "^1.0.0", "1.1.0")
+	lib.Packages = []string{"lib"}
+	core := createRepository("core", "lib", "^1.0.0", "garbage")
+	core.Packages = []string{"core"}
+	consumer := createRepository("app", "lib", "^1.0.0", "1.0.0")
+	consumer.Dependencies = append(consumer.Dependencies,
+		models.Dependency{Name: "core", Semver: "^1.0.0", Installed: "1.1.0"},
+		models.Dependency{Name: "left-pad", Semver: "^1.0.0", Installed: "1.0.0"},
+	)
+	web := createRepository("web", "lib", "^1.0.0", "1.0.5")
+	web.Dependencies = append(web.Dependencies, models.Dependency{Name: "left-pad", Semver: "^1.0.0", Installed: "1.3.0"})
+
+	publishedList := []*models.PublishedDependency{
+		{Name: "lib", Version: "1.2.0"},
+		{Name: "lib", Version: "not a version"},
+	}
+
+	outdatedList := OutdatedDependencies(NewGraph([]*models.Repository{lib, core, consumer, web}), publishedList)
+
+	assert.Equal(t, []notify.OutdatedDependency{
+		{Repository: "app", Ecosystem: EcosystemNpm, Package: "lib", Installed: "1.0.0", Latest: "1.2.0"},
+		{Repository: "web", Ecosystem: EcosystemNpm, Package: "lib", Installed: "1.0.5", Latest: "1.2.0"},
+	}, outdatedList)
+}
+
+func TestDigestService_Build(t *testing.T) {
+	jobDAO := newMockJobDAO().(*mockJobDAO)
+	jobDAO.records[0].State = models.Failed
+	jobDAO.records[1].State = models.Locked
+	jobDAO.records[2].State = models.Succeeded
+	dead := createJob("ddd", "test", "1.0.0")
+	dead.State = models.DeadLetter
+	jobDAO.records = append(jobDAO.records, dead)
+
+	repDAO := newMockRepositoryDAO().(*mockRepositoryDAO)
+	publisher := createRepository("ddd", "other", "^1.0.0", "1.0.0")
+	publisher.Packages = []string{"test"}
+	repDAO.records = append(repDAO.records, publisher)
+
+	s := NewDigestService(jobDAO, repDAO, nil, notify.Schedule{Period: notify.Daily})
+	rs := new(MockRequestScope)
+
+	digest, err := s.Build(rs.DB(), rs.Now())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, notify.Daily, digest.Period)
+	assert.Equal(t, rs.Now(), digest.Time)
+	assert.Equal(t, 4, len(digest.Repositories))
+
+	if assert.Equal(t, 1, len(digest.Pending)) {
+		assert.Equal(t, "bbb", digest.Pending[0].Name)
+	}
+
+	if assert.Equal(t, 2, len(digest.Failed)) {
+		assert.Equal(t, "aaa", digest.Failed[0].Name)
+		assert.Equal(t, "ddd", digest.Failed[1].Name)
+	}
+
+	// the ccc job queued test 3.2.3
+	assert.Equal(t, []notify.OutdatedDependency{
+		{Repository: "aaa", Ecosystem: EcosystemNpm, Package: "test", Installed: "1.0.0", Latest: "3.2.3"},
+		{Repository: "bbb", Ecosystem: EcosystemNpm, Package: "test", Installed: "2.2.3", Latest: "3.2.3"},
+	}, digest.Outdated)
+}
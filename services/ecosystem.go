@@ -0,0 +1,169 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/quantumew/data-access/models"
+)
+
+// Ecosystem is a package ecosystem such as npm. It knows how the registry of the ecosystem announces published
+// versions, and how the versions and the ranges dependencies declare are written and ordered.
+type Ecosystem interface {
+	// Name returns the tag repositories and dependencies use to refer to the ecosystem.
+	Name() string
+	// ParseHook returns the versions a hook delivery from a registry of the ecosystem publishes.
+	ParseHook(payload []byte) ([]*models.PublishedDependency, error)
+	// ParseVersion parses a published or installed version.
+	ParseVersion(version string) (Version, error)
+	// ParseRange parses the range of versions a dependency declares.
+	ParseRange(rangeStr string) (VersionRange, error)
+}
+
+// nameChecker is implemented by ecosystems whose package names follow a format, such as the coordinates of Maven.
+type nameChecker interface {
+	// CheckName returns an error describing why a name is not a valid package name of the ecosystem.
+	CheckName(name string) error
+}
+
+// Version is a parsed version of a package.
+type Version interface {
+	String() string
+	// Compare returns a negative number, zero or a positive number when the version is lower, equal or higher than
+	// another version of the same ecosystem.
+	Compare(other Version) int
+	// Release returns the leading numbers of the version, e.g. the major, minor and patch numbers of a semantic
+	// version. Update policies compare them to tell how big of a jump an update is.
+	Release() []uint64
+}
+
+// VersionRange is a parsed range of versions a dependency accepts.
+type VersionRange interface {
+	String() string
+	// Contains returns whether a version of the same ecosystem is in the range.
+	Contains(version Version) bool
+}
+
+// compareForeign orders a version against a version of another ecosystem, which only happens when a repository mixes
+// the tags of its dependencies up. Their strings are compared so that sorting stays consistent.
+func compareForeign(v Version, other Version) int {
+	return strings.Compare(v.String(), other.String())
+}
+
+// EcosystemNpm is the tag of the npm ecosystem, the one repositories and dependencies without a tag belong to.
+const EcosystemNpm = "npm"
+
+// ecosystems are the registered ecosystems by name.
+var ecosystems = map[string]Ecosystem{}
+
+// RegisterEcosystem makes an ecosystem available to the repositories and dependencies tagged with its name.
+func RegisterEcosystem(ecosystem Ecosystem) {
+	ecosystems[ecosystem.Name()] = ecosystem
+}
+
+// LookupEcosystem returns the ecosystem registered under a name, an empty name standing for npm.
+func LookupEcosystem(name string) (Ecosystem, error) {
+	if name == "" {
+		name = EcosystemNpm
+	}
+
+	ecosystem, ok := ecosystems[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown ecosystem %q", name)
+	}
+
+	return ecosystem, nil
+}
+
+// EcosystemNames returns the names of the registered ecosystems, sorted.
+func EcosystemNames() []string {
+	var nameList []string
+
+	for name := range ecosystems {
+		nameList = append(nameList, name)
+	}
+
+	sort.Strings(nameList)
+
+	return nameList
+}
+
+// CheckPackageName returns an error when a name is not a valid package name of an ecosystem. Names of ecosystems
+// without a format are always valid.
+func CheckPackageName(ecosystem Ecosystem, name string) error {
+	if checker, ok := ecosystem.(nameChecker); ok {
+		return checker.CheckName(name)
+	}
+
+	return nil
+}
+
+// RepositoryEcosystem returns the name of the ecosystem the packages of a repository belong to.
+func RepositoryEcosystem(rep *models.Repository) string {
+	return normalizeEcosystem(rep.Ecosystem)
+}
+
+// DependencyEcosystem returns the name of the ecosystem of a dependency, falling back on the one of the repository.
+func DependencyEcosystem(rep *models.Repository, dep models.Dependency) string {
+	if dep.Ecosystem != "" {
+		return dep.Ecosystem
+	}
+
+	return RepositoryEcosystem(rep)
+}
+
+// PackageKey identifies a package across ecosystems. npm packages are identified by their name alone, so the keys
+// predating ecosystems keep working, and packages of other ecosystems by their name prefixed with the ecosystem.
+func PackageKey(ecosystem string, name string) string {
+	ecosystem = normalizeEcosystem(ecosystem)
+	if ecosystem == EcosystemNpm {
+		return name
+	}
+
+	return ecosystem + ":" + name
+}
+
+// ParseGenericHook reads a hook delivery in the generic format ecosystems without a registry hook of their own use.
+// The payload is a published version such as {"name": "lib", "version": "1.2.0"} or a list of them.
+func ParseGenericHook(ecosystem string, payload []byte) ([]*models.PublishedDependency, error) {
+	var pubList []*models.PublishedDependency
+
+	payload = bytes.TrimSpace(payload)
+	if bytes.HasPrefix(payload, []byte("[")) {
+		if err := json.Unmarshal(payload, &pubList); err != nil {
+			return nil, validation.Errors{"payload": err}
+		}
+	} else {
+		pub := &models.PublishedDependency{}
+		if err := json.Unmarshal(payload, pub); err != nil {
+			return nil, validation.Errors{"payload": err}
+		}
+
+		pubList = append(pubList, pub)
+	}
+
+	for i, pub := range pubList {
+		if err := validation.ValidateStruct(pub,
+			validation.Field(&pub.Name, validation.Required),
+			validation.Field(&pub.Version, validation.Required),
+		); err != nil {
+			return nil, validation.Errors{fmt.Sprintf("payload[%d]", i): err}
+		}
+
+		pub.Ecosystem = ecosystem
+	}
+
+	return pubList, nil
+}
+
+func normalizeEcosystem(name string) string {
+	if name == "" {
+		return EcosystemNpm
+	}
+
+	return name
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// counterEcosystem is an ecosystem whose versions are plain numbers and whose ranges are minimum versions like ">=2".
+type counterEcosystem struct{}
+
+type counterVersion uint64
+
+type counterRange counterVersion
+
+func (counterEcosystem) Name() string {
+	return "counter"
+}
+
+func (counterEcosystem) ParseHook(payload []byte) ([]*models.PublishedDependency, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (counterEcosystem) ParseVersion(version string) (Version, error) {
+	v, err := strconv.ParseUint(version, 10, 64)
+	return counterVersion(v), err
+}
+
+func (e counterEcosystem) ParseRange(rangeStr string) (VersionRange, error) {
+	v, err := e.ParseVersion(strings.TrimPrefix(rangeStr, ">="))
+	if err != nil {
+		return nil, err
+	}
+	return counterRange(v.(counterVersion)), nil
+}
+
+func (v counterVersion) String() string {
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+func (v counterVersion) Compare(other Version) int {
+	return int(v) - int(other.(counterVersion))
+}
+
+func (v counterVersion) Release() []uint64 {
+	return []uint64{uint64(v)}
+}
+
+func (r counterRange) String() string {
+	return ">=" + counterVersion(r).String()
+}
+
+func (r counterRange) Contains(version Version) bool {
+	return version.(counterVersion) >= counterVersion(r)
+}
+
+func TestLookupEcosystem(t *testing.T) {
+	npm, err := LookupEcosystem("")
+	if assert.Nil(t, err) {
+		assert.Equal(t, EcosystemNpm, npm.Name())
+	}
+
+	_, err = LookupEcosystem("unknown")
+	assert.NotNil(t, err)
+
+	assert.Contains(t, EcosystemNames(), EcosystemNpm)
+}
+
+func TestPackageKey(t *testing.T) {
+	assert.Equal(t, "lib", PackageKey("", "lib"))
+	assert.Equal(t, "lib", PackageKey(EcosystemNpm, "lib"))
+	assert.Equal(t, "counter:lib", PackageKey("counter", "lib"))
+}
+
+func TestVersion_MixedEcosystems(t *testing.T) {
+	nameList := []string{EcosystemNpm, "go", "maven", "pypi"}
+	versionList := []string{"1.2.0", "v1.2.0", "1.2.0", "1.2.0"}
+	rangeList := []string{">=1.0.0", "v1.0.0", "[1.0,)", ">=1.0"}
+
+	for i, name := range nameList {
+		ecosystem, err := LookupEcosystem(name)
+		if !assert.Nil(t, err, name) {
+			continue
+		}
+
+		for j, other := range nameList {
+			if i == j {
+				continue
+			}
+
+			otherEcosystem, _ := LookupEcosystem(other)
+			v, _ := ecosystem.ParseVersion(versionList[i])
+			o, _ := otherEcosystem.ParseVersion(versionList[j])
+			r, _ := ecosystem.ParseRange(rangeList[i])
+
+			assert.Equal(t, -v.Compare(o), o.Compare(v), name+" and "+other)
+			assert.False(t, r.Contains(o), name+" and "+other)
+		}
+	}
+}
+
+func TestFilterByVersion_Ecosystems(t *testing.T) {
+	RegisterEcosystem(counterEcosystem{})
+	defer delete(ecosystems, "counter")
+
+	counter := createRepository("bbb", "lib", ">=2", "2")
+	counter.Ecosystem = "counter"
+	mixed := createRepository("ccc", "other", "^1.0.0", "1.0.0")
+	mixed.Dependencies = append(mixed.Dependencies, models.Dependency{Ecosystem: "counter", Name: "lib", Semver: ">=4", Installed: "4"})
+	repList := []*models.Repository{createRepository("aaa", "lib", "^1.0.0", "1.0.0"), counter, mixed}
+
+	filteredList, skippedList := FilterByVersion(repList, &models.PublishedDependency{Ecosystem: "counter", Name: "lib", Version: "3"})
+	if assert.Equal(t, 1, len(filteredList)) {
+		assert.Equal(t, "bbb", filteredList[0].Name)
+	}
+	assert.Equal(t, 2, len(skippedList))
+
+	filteredList, _ = FilterByVersion(repList, &models.PublishedDependency{Name: "lib", Version: "1.1.0"})
+	if assert.Equal(t, 1, len(filteredList)) {
+		assert.Equal(t, "aaa", filteredList[0].Name)
+	}
+
+	assert.NotNil(t, validateRepository(&models.Repository{Name: "ddd", Ecosystem: "unknown"}))
+	assert.Nil(t, validateRepository(counter))
+}
+
+func TestJobService_CreateJobsFromPayload(t *testing.T) {
+	jobDAO := &mockJobDAO{}
+	repDAO := &mockRepositoryDAO{records: []*models.Repository{createRepository("aaa", "lib", "^1.0.0", "1.0.0")}}
+	s := NewJobService(jobDAO, repDAO)
+
+	result, err := s.CreateJobsFromPayload(new(MockRequestScope), EcosystemNpm, []byte(`{"event":"package:publish","name":"lib","version":"1.1.0"}`))
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(result.Jobs)) {
+		assert.Equal(t, "aaa", result.Jobs[0].Name)
+	}
+
+	_, err = s.CreateJobsFromPayload(new(MockRequestScope), EcosystemNpm, []byte("{"))
+	assert.NotNil(t, err)
+
+	_, err = s.CreateJobsFromPayload(new(MockRequestScope), "unknown", nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/app"
+)
+
+// gitHubEvent is the part of a GitHub webhook delivery the listener reads.
+type gitHubEvent struct {
+	Action          string            `json:"action"`
+	Ref             string            `json:"ref"`
+	Deleted         bool              `json:"deleted"`
+	Package         *gitHubPackage    `json:"package"`
+	RegistryPackage *gitHubPackage    `json:"registry_package"`
+	Release         *gitHubRelease    `json:"release"`
+	Repository      *gitHubRepository `json:"repository"`
+}
+
+// gitHubPackage is a package published to GitHub Packages.
+type gitHubPackage struct {
+	Name           string `json:"name"`
+	Namespace      string `json:"namespace"`
+	Ecosystem      string `json:"ecosystem"`
+	PackageType    string `json:"package_type"`
+	PackageVersion *struct {
+		Version string `json:"version"`
+	} `json:"package_version"`
+}
+
+// gitHubRelease is a release of a GitHub repository.
+type gitHubRelease struct {
+	TagName string `json:"tag_name"`
+	Draft   bool   `json:"draft"`
+}
+
+// gitHubRepository is the GitHub repository a delivery is about.
+type gitHubRepository struct {
+	Name     string `json:"name"`
+	FullName string `json:"full_name"`
+}
+
+// CreateJobsFromGitHub creates or updates the jobs of every repository a GitHub webhook delivery publishes a new
+// dependency for. Packages published to GitHub Packages, and pushed tags and releases of registered repositories count
+// as publishes, every other event is ignored.
+func (s *JobService) CreateJobsFromGitHub(rs app.RequestScope, event string, payload []byte) (*HookResult, error) {
+	var ghEvent gitHubEvent
+	if err := json.Unmarshal(payload, &ghEvent); err != nil {
+		return nil, validation.Errors{"payload": err}
+	}
+
+	var pubList []*models.PublishedDependency
+
+	switch event {
+	case "package", "registry_package":
+		pubList = gitHubPackagePublishes(&ghEvent)
+	case "push", "release":
+		tagPubList, err := s.gitHubTagPublishes(rs, event, &ghEvent)
+		if err != nil {
+			return nil, err
+		}
+
+		pubList = tagPubList
+	}
+
+	return s.publishAll(rs, pubList)
+}
+
+// gitHubPackagePublishes returns the version of a package published to GitHub Packages. Packages of other ecosystems
+// are not handled yet.
+func gitHubPackagePublishes(event *gitHubEvent) []*models.PublishedDependency {
+	pkg := event.Package
+	if pkg == nil {
+		pkg = event.RegistryPackage
+	}
+
+	if event.Action != "published" || pkg == nil || pkg.PackageVersion == nil {
+		return nil
+	}
+
+	ecosystem := pkg.Ecosystem
+	if ecosystem == "" {
+		ecosystem = pkg.PackageType
+	}
+
+	if !strings.EqualFold(ecosystem, "npm") {
+		return nil
+	}
+
+	// npm packages on GitHub Packages are always scoped to the account owning them.
+	name := pkg.Name
+	if !strings.HasPrefix(name, "@") && pkg.Namespace != "" {
+		name = "@" + strings.ToLower(pkg.Namespace) + "/" + name
+	}
+
+	return []*models.PublishedDependency{{Ecosystem: EcosystemNpm, Name: name, Version: pkg.PackageVersion.Version}}
+}
+
+// gitHubTagPublishes returns the versions a pushed tag or a published release publishes. Pushes of branches and of
+// deleted tags publish nothing.
+func (s *JobService) gitHubTagPublishes(rs app.RequestScope, eventType string, event *gitHubEvent) ([]*models.PublishedDependency, error) {
+	var tag string
+
+	switch {
+	case eventType == "push" && strings.HasPrefix(event.Ref, "refs/tags/") && !event.Deleted:
+		tag = event.Ref
+	case eventType == "release" && event.Action == "published" && event.Release != nil && !event.Release.Draft:
+		tag = event.Release.TagName
+	}
+
+	if tag == "" || event.Repository == nil {
+		return nil, nil
+	}
+
+	return s.tagPublishes(rs, event.Repository.FullName, tag)
+}
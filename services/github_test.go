@@ -0,0 +1,71 @@
+package services
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	payload, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func newGitHubJobService() (*JobService, *mockJobDAO) {
+	widgets := createPublisher("widgets", "@octo-org/widgets")
+	widgets.Config.Remote = "git@github.com:Octo-Org/widgets.git"
+	jobDAO := &mockJobDAO{}
+	repDAO := &mockRepositoryDAO{records: []*models.Repository{
+		createPublisher("app", "@octo-org/app", "@octo-org/widgets"),
+		widgets,
+	}}
+	return NewJobService(jobDAO, repDAO), jobDAO
+}
+
+func TestJobService_CreateJobsFromGitHub(t *testing.T) {
+	tests := []struct {
+		fixture string
+		event   string
+		version string
+		skipped int
+	}{
+		{"github/registry_package_published.json", "registry_package", "1.4.0", 0},
+		{"github/package_published.json", "package", "", 1},
+		{"github/package_published_container.json", "package", "", 0},
+		{"github/release_published.json", "release", "1.6.0", 0},
+		{"github/push.json", "push", "1.7.0", 0},
+		{"github/push_branch.json", "push", "", 0},
+		{"github/push_deleted.json", "push", "", 0},
+	}
+
+	for _, test := range tests {
+		s, jobDAO := newGitHubJobService()
+
+		result, err := s.CreateJobsFromGitHub(new(MockRequestScope), test.event, readFixture(t, test.fixture))
+		if !assert.Nil(t, err, test.fixture) {
+			continue
+		}
+
+		assert.Equal(t, test.skipped, len(result.Skipped), test.fixture)
+
+		if test.version == "" {
+			assert.Empty(t, result.Jobs, test.fixture)
+			continue
+		}
+
+		if assert.Equal(t, 1, len(result.Jobs), test.fixture) {
+			assert.Equal(t, "app", result.Jobs[0].Name)
+			assert.Equal(t, &models.PublishedDependency{Ecosystem: EcosystemNpm, Name: "@octo-org/widgets", Version: test.version}, jobDAO.records[0].Dependencies[0])
+		}
+	}
+
+	s, _ := newGitHubJobService()
+	_, err := s.CreateJobsFromGitHub(new(MockRequestScope), "package", []byte("{"))
+	assert.NotNil(t, err)
+}
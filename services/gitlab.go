@@ -0,0 +1,53 @@
+package services
+
+import (
+	"encoding/json"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/quantumew/listener/app"
+)
+
+// deletedRef is the commit a tag push hook points a deleted tag to.
+const deletedRef = "0000000000000000000000000000000000000000"
+
+// gitLabEvent is the part of a GitLab tag push or release hook the listener reads.
+type gitLabEvent struct {
+	ObjectKind string `json:"object_kind"`
+	Action     string `json:"action"`
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Tag        string `json:"tag"`
+	Project    *struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+}
+
+// CreateJobsFromGitLab creates or updates the jobs of every repository a GitLab hook publishes a new dependency for.
+// Pushed tags and created releases publish the packages of the project at the version of the tag, every other hook
+// is ignored.
+func (s *JobService) CreateJobsFromGitLab(rs app.RequestScope, payload []byte) (*HookResult, error) {
+	var event gitLabEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, validation.Errors{"payload": err}
+	}
+
+	var tag string
+
+	switch {
+	case event.ObjectKind == "tag_push" && event.After != deletedRef:
+		tag = event.Ref
+	case event.ObjectKind == "release" && event.Action == "create":
+		tag = event.Tag
+	}
+
+	if tag == "" || event.Project == nil {
+		return &HookResult{}, nil
+	}
+
+	pubList, err := s.tagPublishes(rs, event.Project.PathWithNamespace, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.publishAll(rs, pubList)
+}
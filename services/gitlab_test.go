@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newGitLabJobService() (*JobService, *mockJobDAO) {
+	widgets := createPublisher("widgets", "@octo-org/widgets")
+	widgets.Config.Remote = "git@gitlab.example.com:octo-org/widgets.git"
+	frontend := createPublisher("frontend", "@octo-org/buttons")
+	frontend.Projects = []models.ProjectMapping{
+		{Project: "octo-org/frontend", Packages: []string{"@octo-org/buttons", "@octo-org/dials"}},
+	}
+	jobDAO := &mockJobDAO{}
+	repDAO := &mockRepositoryDAO{records: []*models.Repository{
+		createPublisher("app", "@octo-org/app", "@octo-org/widgets", "@octo-org/dials", "@octo-org/buttons"),
+		frontend,
+		widgets,
+	}}
+	return NewJobService(jobDAO, repDAO), jobDAO
+}
+
+func TestJobService_CreateJobsFromGitLab(t *testing.T) {
+	tests := []struct {
+		fixture string
+		pkg     string
+		version string
+	}{
+		{"gitlab/tag_push.json", "@octo-org/widgets", "1.4.0"},
+		{"gitlab/tag_push_deleted.json", "", ""},
+		{"gitlab/tag_push_monorepo.json", "@octo-org/dials", "1.2.0"},
+		{"gitlab/release.json", "@octo-org/widgets", "1.5.0"},
+		{"gitlab/release_update.json", "", ""},
+		{"gitlab/push.json", "", ""},
+	}
+
+	for _, test := range tests {
+		s, jobDAO := newGitLabJobService()
+
+		result, err := s.CreateJobsFromGitLab(new(MockRequestScope), readFixture(t, test.fixture))
+		if !assert.Nil(t, err, test.fixture) {
+			continue
+		}
+
+		if test.pkg == "" {
+			assert.Empty(t, result.Jobs, test.fixture)
+			continue
+		}
+
+		if assert.Equal(t, 1, len(result.Jobs), test.fixture) && assert.Equal(t, 1, len(jobDAO.records), test.fixture) {
+			assert.Equal(t, "app", result.Jobs[0].Name)
+			assert.Equal(t, &models.PublishedDependency{Ecosystem: EcosystemNpm, Name: test.pkg, Version: test.version}, jobDAO.records[0].Dependencies[0])
+		}
+	}
+
+	s, _ := newGitLabJobService()
+	_, err := s.CreateJobsFromGitLab(new(MockRequestScope), []byte("{"))
+	assert.NotNil(t, err)
+}
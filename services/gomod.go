@@ -0,0 +1,193 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/quantumew/data-access/models"
+)
+
+func init() {
+	RegisterEcosystem(goEcosystem{})
+}
+
+// EcosystemGo is the tag of the Go modules ecosystem.
+const EcosystemGo = "go"
+
+// incompatible is the build suffix of versions at major 2 or above of modules that predate modules and have no go.mod.
+const incompatible = "+incompatible"
+
+var (
+	// goPathMajorRegexp matches the major version suffix of a module path, "/v2" or "gopkg.in/yaml.v2".
+	goPathMajorRegexp = regexp.MustCompile(`(?:/|^gopkg\.in/.*\.)v(\d+)$`)
+	// pseudoVersionRegexp matches the pre-release of a pseudo-version, which ends with a UTC timestamp and a commit hash.
+	pseudoVersionRegexp = regexp.MustCompile(`(?:^|[.-])\d{14}-[0-9a-f]{12}$`)
+)
+
+// goEcosystem is the Go modules ecosystem. Versions are canonical semantic versions with a "v" prefix, including
+// pseudo-versions and +incompatible ones. The range of a dependency is the version its go.mod requires. Minimal
+// version selection never moves a requirement down, so only newer versions update it.
+type goEcosystem struct{}
+
+// goVersion is a version of a Go module.
+type goVersion struct {
+	version semver.Version
+}
+
+// goRequirement is the minimum version of a module a go.mod requires.
+type goRequirement struct {
+	required *goVersion
+}
+
+func (goEcosystem) Name() string {
+	return EcosystemGo
+}
+
+// ParseHook reads a generic hook delivery. The version of every module must be allowed by the major version suffix of
+// its path.
+func (goEcosystem) ParseHook(payload []byte) ([]*models.PublishedDependency, error) {
+	pubList, err := ParseGenericHook(EcosystemGo, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, pub := range pubList {
+		version, err := ParseGoVersion(pub.Version)
+		if err == nil {
+			err = CheckModulePath(pub.Name, version)
+		}
+
+		if err != nil {
+			return nil, validation.Errors{fmt.Sprintf("payload[%d]", i): err}
+		}
+	}
+
+	return pubList, nil
+}
+
+func (goEcosystem) ParseVersion(version string) (Version, error) {
+	v, err := ParseGoVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// ParseRange parses the version a go.mod require line lists. An empty range accepts any version.
+func (goEcosystem) ParseRange(rangeStr string) (VersionRange, error) {
+	rangeStr = strings.TrimSpace(rangeStr)
+	if rangeStr == "" {
+		return goRequirement{}, nil
+	}
+
+	required, err := ParseGoVersion(rangeStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return goRequirement{required}, nil
+}
+
+// ParseGoVersion parses a canonical Go module version such as "v1.2.3", "v2.0.0+incompatible" or the pseudo-version
+// "v0.0.0-20190101120000-abcdef123456".
+func ParseGoVersion(version string) (*goVersion, error) {
+	if !strings.HasPrefix(version, "v") {
+		return nil, fmt.Errorf("Go version %q must start with \"v\"", version)
+	}
+
+	v, err := semver.Parse(version[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid Go version %q: %s", version, err)
+	}
+
+	if len(v.Build) > 0 && (len(v.Build) != 1 || v.Build[0] != "incompatible") {
+		return nil, fmt.Errorf("invalid Go version %q: only +incompatible build metadata is allowed", version)
+	}
+
+	if v.Major < 2 && len(v.Build) > 0 {
+		return nil, fmt.Errorf("invalid Go version %q: +incompatible needs a major version of 2 or above", version)
+	}
+
+	return &goVersion{v}, nil
+}
+
+// CheckModulePath returns an error when the version cannot be a version of the module. Modules whose path ends with a
+// major version suffix such as "/v2" only have versions of that major version, while modules without one only have
+// v0 and v1 versions, or +incompatible ones.
+func CheckModulePath(path string, version *goVersion) error {
+	match := goPathMajorRegexp.FindStringSubmatch(path)
+
+	if match == nil {
+		if version.version.Major >= 2 && !version.IsIncompatible() {
+			return fmt.Errorf("module %s has no /v%d suffix, version %s must be +incompatible", path, version.version.Major, version)
+		}
+
+		return nil
+	}
+
+	major, _ := strconv.ParseUint(match[1], 10, 64)
+
+	if version.IsIncompatible() || version.version.Major != major {
+		return fmt.Errorf("module %s only has v%d versions, not %s", path, major, version)
+	}
+
+	return nil
+}
+
+func (v *goVersion) String() string {
+	return "v" + v.version.String()
+}
+
+// Compare orders versions by semantic version precedence. Build metadata, +incompatible, does not count, and
+// pseudo-versions sort by the timestamp they carry.
+func (v *goVersion) Compare(other Version) int {
+	o, ok := other.(*goVersion)
+	if !ok {
+		return compareForeign(v, other)
+	}
+
+	return v.version.Compare(o.version)
+}
+
+func (v *goVersion) Release() []uint64 {
+	return []uint64{v.version.Major, v.version.Minor, v.version.Patch}
+}
+
+// IsIncompatible returns whether the version is a +incompatible version.
+func (v *goVersion) IsIncompatible() bool {
+	return len(v.version.Build) > 0
+}
+
+// IsPseudo returns whether the version is a pseudo-version pointing at a commit rather than a tag.
+func (v *goVersion) IsPseudo() bool {
+	pre := make([]string, len(v.version.Pre))
+
+	for i, part := range v.version.Pre {
+		pre[i] = part.String()
+	}
+
+	return pseudoVersionRegexp.MatchString(strings.Join(pre, "."))
+}
+
+func (r goRequirement) String() string {
+	if r.required == nil {
+		return ""
+	}
+
+	return r.required.String()
+}
+
+// Contains returns whether a version is newer than the required one, which is the only way minimal version selection
+// would pick it.
+func (r goRequirement) Contains(version Version) bool {
+	if _, ok := version.(*goVersion); !ok {
+		return false
+	}
+
+	return r.required == nil || version.Compare(r.required) > 0
+}
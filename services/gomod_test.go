@@ -0,0 +1,141 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGoVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		valid   bool
+	}{
+		{"v1.2.3", true},
+		{"v1.2.3-pre.1", true},
+		{"v2.0.0+incompatible", true},
+		{"v0.0.0-20190101120000-abcdef123456", true},
+		{"v1.2.4-0.20190101120000-abcdef123456", true},
+		{"1.2.3", false},
+		{"v1.2", false},
+		{"v1.2.3+build", false},
+		{"v1.0.0+incompatible", false},
+		{"latest", false},
+	}
+
+	for _, test := range tests {
+		_, err := ParseGoVersion(test.version)
+		assert.Equal(t, test.valid, err == nil, test.version)
+	}
+}
+
+func TestGoVersion_Compare(t *testing.T) {
+	// in ascending order
+	versionList := []string{
+		"v0.0.0-20180101120000-abcdef123456",
+		"v0.0.0-20190101120000-abcdef123456",
+		"v0.1.0",
+		"v1.2.3",
+		"v1.2.4-0.20190101120000-abcdef123456",
+		"v1.2.4-pre",
+		"v1.2.4",
+		"v2.0.0+incompatible",
+		"v2.1.0+incompatible",
+	}
+
+	for i := 1; i < len(versionList); i++ {
+		lower, _ := ParseGoVersion(versionList[i-1])
+		higher, _ := ParseGoVersion(versionList[i])
+		assert.True(t, lower.Compare(higher) < 0, "%s < %s", lower, higher)
+		assert.True(t, higher.Compare(lower) > 0, "%s > %s", higher, lower)
+	}
+
+	incompatible, _ := ParseGoVersion("v2.0.0+incompatible")
+	assert.Equal(t, "v2.0.0+incompatible", incompatible.String())
+	assert.True(t, incompatible.IsIncompatible())
+
+	pseudo, _ := ParseGoVersion("v1.2.4-0.20190101120000-abcdef123456")
+	assert.True(t, pseudo.IsPseudo())
+	assert.False(t, incompatible.IsPseudo())
+}
+
+func TestCheckModulePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		version string
+		valid   bool
+	}{
+		{"example.com/lib", "v1.2.3", true},
+		{"example.com/lib", "v0.0.0-20190101120000-abcdef123456", true},
+		{"example.com/lib", "v2.0.0+incompatible", true},
+		{"example.com/lib", "v2.0.0", false},
+		{"example.com/lib/v2", "v2.1.0", true},
+		{"example.com/lib/v2", "v3.0.0", false},
+		{"example.com/lib/v2", "v1.0.0", false},
+		{"example.com/lib/v2", "v2.0.0+incompatible", false},
+		{"gopkg.in/yaml.v2", "v2.4.0", true},
+		{"gopkg.in/yaml.v2", "v3.0.0", false},
+	}
+
+	for _, test := range tests {
+		version, err := ParseGoVersion(test.version)
+		if assert.Nil(t, err, test.version) {
+			assert.Equal(t, test.valid, CheckModulePath(test.path, version) == nil, "%s@%s", test.path, test.version)
+		}
+	}
+}
+
+func TestGoEcosystem_Range(t *testing.T) {
+	ecosystem, _ := LookupEcosystem(EcosystemGo)
+
+	required, err := ecosystem.ParseRange("v1.2.3")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	for version, contained := range map[string]bool{
+		"v1.2.2":                               false,
+		"v1.2.3":                               false,
+		"v1.2.4-0.20190101120000-abcdef123456": true,
+		"v1.3.0":                               true,
+	} {
+		v, err := ecosystem.ParseVersion(version)
+		if assert.Nil(t, err, version) {
+			assert.Equal(t, contained, required.Contains(v), version)
+		}
+	}
+
+	_, err = ecosystem.ParseRange("^1.2.3")
+	assert.NotNil(t, err)
+}
+
+func TestGoEcosystem_ParseHook(t *testing.T) {
+	ecosystem, _ := LookupEcosystem(EcosystemGo)
+
+	pubList, err := ecosystem.ParseHook([]byte(`[{"name":"example.com/lib/v2","version":"v2.1.0"},{"name":"example.com/old","version":"v3.0.0+incompatible"}]`))
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(pubList)) {
+		assert.Equal(t, &models.PublishedDependency{Ecosystem: EcosystemGo, Name: "example.com/lib/v2", Version: "v2.1.0"}, pubList[0])
+	}
+
+	_, err = ecosystem.ParseHook([]byte(`{"name":"example.com/lib","version":"v2.1.0"}`))
+	assert.NotNil(t, err)
+
+	_, err = ecosystem.ParseHook([]byte(`{"name":"example.com/lib"}`))
+	assert.NotNil(t, err)
+}
+
+func TestJobService_CreateJobsFromPayload_Go(t *testing.T) {
+	jobDAO := &mockJobDAO{}
+	older := createRepository("aaa", "example.com/lib", "v1.2.3", "v1.2.3")
+	older.Ecosystem = EcosystemGo
+	newer := createRepository("bbb", "example.com/lib", "v1.4.0", "v1.4.0")
+	newer.Ecosystem = EcosystemGo
+	s := NewJobService(jobDAO, &mockRepositoryDAO{records: []*models.Repository{older, newer}})
+
+	result, err := s.CreateJobsFromPayload(new(MockRequestScope), EcosystemGo, []byte(`{"name":"example.com/lib","version":"v1.3.0"}`))
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(result.Jobs)) {
+		assert.Equal(t, "aaa", result.Jobs[0].Name)
+		assert.Equal(t, "bbb", result.Skipped[0].Name)
+	}
+}
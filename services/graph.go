@@ -0,0 +1,504 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/quantumew/data-access"
+	"github.com/quantumew/data-access/models"
+)
+
+// Graph is the dependency graph between registered repositories. A repository depends on another when it depends on
+// one of the packages the other publishes. Packages are identified by their PackageKey.
+type Graph struct {
+	repositories map[string]*models.Repository
+	nameList     []string
+	publishers   map[string]string
+	consumers    map[string][]string
+	dependents   map[string][]string
+	dependencies map[string][]string
+	components   map[string]int
+	componentLen []int
+	levels       []int
+	selfLoops    map[string]bool
+}
+
+// NewGraph builds the dependency graph of the given repositories.
+func NewGraph(repList []*models.Repository) *Graph {
+	g := &Graph{
+		repositories: map[string]*models.Repository{},
+		publishers:   map[string]string{},
+		consumers:    map[string][]string{},
+		dependents:   map[string][]string{},
+		dependencies: map[string][]string{},
+		selfLoops:    map[string]bool{},
+	}
+
+	for _, rep := range repList {
+		g.repositories[rep.Name] = rep
+		g.nameList = append(g.nameList, rep.Name)
+
+		for _, pkg := range rep.Packages {
+			g.publishers[PackageKey(rep.Ecosystem, pkg)] = rep.Name
+		}
+	}
+
+	sort.Strings(g.nameList)
+
+	for _, name := range g.nameList {
+		rep := g.repositories[name]
+
+		for _, dep := range rep.Dependencies {
+			key := PackageKey(DependencyEcosystem(rep, dep), dep.Name)
+
+			if !containsString(g.consumers[key], name) {
+				g.consumers[key] = append(g.consumers[key], name)
+			}
+
+			publisher, ok := g.publishers[key]
+
+			if !ok || containsString(g.dependencies[name], publisher) {
+				continue
+			}
+
+			if publisher == name {
+				g.selfLoops[name] = true
+			}
+
+			g.dependencies[name] = append(g.dependencies[name], publisher)
+			g.dependents[publisher] = append(g.dependents[publisher], name)
+		}
+	}
+
+	for name := range g.dependencies {
+		sort.Strings(g.dependencies[name])
+	}
+
+	g.findComponents()
+
+	return g
+}
+
+// Repository returns the registered repository with the specified name.
+func (g *Graph) Repository(name string) (*models.Repository, bool) {
+	rep, ok := g.repositories[name]
+	return rep, ok
+}
+
+// Repositories returns the names of every repository in the graph, sorted.
+func (g *Graph) Repositories() []string {
+	return g.nameList
+}
+
+// Publisher returns the name of the repository publishing the package, if it is registered.
+func (g *Graph) Publisher(pkg string) (string, bool) {
+	name, ok := g.publishers[pkg]
+	return name, ok
+}
+
+// Dependents returns the repositories that directly depend on a repository, sorted.
+func (g *Graph) Dependents(name string) []string {
+	return g.dependents[name]
+}
+
+// Dependencies returns the repositories a repository directly depends on, sorted.
+func (g *Graph) Dependencies(name string) []string {
+	return g.dependencies[name]
+}
+
+// Consumers returns the repositories that directly depend on a package, sorted.
+func (g *Graph) Consumers(pkg string) []string {
+	return g.consumers[pkg]
+}
+
+// DependsOn returns whether a repository depends on another, directly or transitively.
+func (g *Graph) DependsOn(name string, upstream string) bool {
+	visited := map[string]bool{}
+	queue := []string{upstream}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, dependent := range g.dependents[current] {
+			if dependent == name {
+				return true
+			}
+
+			if !visited[dependent] {
+				visited[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	return false
+}
+
+// InCycle returns whether two repositories, or a repository with itself, are part of the same dependency cycle.
+func (g *Graph) InCycle(name string, other string) bool {
+	component, ok := g.components[name]
+	if !ok || component != g.components[other] {
+		return false
+	}
+
+	return g.componentLen[component] > 1 || g.selfLoops[name]
+}
+
+// Cycles returns the dependency cycles of the graph in dependency order, each as a sorted list of repository names.
+func (g *Graph) Cycles() [][]string {
+	cycles := map[int][]string{}
+
+	for _, name := range g.nameList {
+		if g.InCycle(name, name) {
+			component := g.components[name]
+			cycles[component] = append(cycles[component], name)
+		}
+	}
+
+	var cycleList [][]string
+
+	for component := len(g.componentLen) - 1; component >= 0; component-- {
+		if cycle, ok := cycles[component]; ok {
+			cycleList = append(cycleList, cycle)
+		}
+	}
+
+	return cycleList
+}
+
+// Sort orders repositories so each one comes after the repositories it depends on. Repositories that do not depend on
+// each other, including the ones in a dependency cycle, keep their order.
+func (g *Graph) Sort(nameList []string) []string {
+	sorted := append([]string{}, nameList...)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return g.level(sorted[i]) < g.level(sorted[j])
+	})
+
+	return sorted
+}
+
+// level returns the length of the longest chain of repositories upstream of a repository, counting a cycle as one.
+func (g *Graph) level(name string) int {
+	if component, ok := g.components[name]; ok {
+		return g.levels[component]
+	}
+
+	return 0
+}
+
+// DependentRepository is a repository affected by a package, found walking the graph down from it.
+type DependentRepository struct {
+	Repository string `json:"repository"`
+	// Package is the dependency of the repository that leads back to the package that was queried.
+	Package string `json:"package"`
+	Semver  string `json:"semver"`
+	Depth   int    `json:"depth"`
+}
+
+// DependencyPackage is a package a repository needs, found walking the graph up from it.
+type DependencyPackage struct {
+	Package string `json:"package"`
+	Semver  string `json:"semver"`
+	// Dependent is the repository that declares the dependency.
+	Dependent string `json:"dependent"`
+	// Repository is the registered repository publishing the package, empty for packages published elsewhere.
+	Repository string `json:"repository,omitempty"`
+	Depth      int    `json:"depth"`
+}
+
+// WalkDependents returns the repositories depending on a package, directly or through the packages of other
+// repositories, nearest first. A max depth of zero or less walks the whole graph.
+func (g *Graph) WalkDependents(pkg string, maxDepth int) []DependentRepository {
+	dependentList := []DependentRepository{}
+	visited := map[string]bool{}
+	pkgList := []string{pkg}
+
+	for depth := 1; len(pkgList) > 0 && (maxDepth <= 0 || depth <= maxDepth); depth++ {
+		var nextList []string
+
+		for _, current := range pkgList {
+			for _, name := range g.consumers[current] {
+				if visited[name] {
+					continue
+				}
+
+				visited[name] = true
+				dependentList = append(dependentList, DependentRepository{name, current, g.semver(name, current), depth})
+				nextList = append(nextList, g.packageKeys(name)...)
+			}
+		}
+
+		pkgList = nextList
+	}
+
+	return dependentList
+}
+
+// WalkDependencies returns the packages a repository depends on, directly or through the registered repositories
+// publishing them, nearest first. A max depth of zero or less walks the whole graph.
+func (g *Graph) WalkDependencies(name string, maxDepth int) []DependencyPackage {
+	dependencyList := []DependencyPackage{}
+	visited := map[string]bool{name: true}
+	seen := map[string]bool{}
+	nameList := []string{name}
+
+	for depth := 1; len(nameList) > 0 && (maxDepth <= 0 || depth <= maxDepth); depth++ {
+		var nextList []string
+
+		for _, current := range nameList {
+			rep := g.repositories[current]
+
+			for _, dep := range rep.Dependencies {
+				key := PackageKey(DependencyEcosystem(rep, dep), dep.Name)
+				if seen[key] {
+					continue
+				}
+
+				seen[key] = true
+				publisher := g.publishers[key]
+				dependencyList = append(dependencyList, DependencyPackage{key, dep.Semver, current, publisher, depth})
+
+				if publisher != "" && !visited[publisher] {
+					visited[publisher] = true
+					nextList = append(nextList, publisher)
+				}
+			}
+		}
+
+		nameList = nextList
+	}
+
+	return dependencyList
+}
+
+// GraphExport is the whole dependency graph, ready to be serialized.
+type GraphExport struct {
+	Repositories []GraphRepository `json:"repositories"`
+	Edges        []GraphEdge       `json:"edges"`
+	Cycles       [][]string        `json:"cycles"`
+}
+
+// GraphRepository is a registered repository and the packages it publishes.
+type GraphRepository struct {
+	Name     string   `json:"name"`
+	Packages []string `json:"packages"`
+}
+
+// GraphEdge is a dependency of a repository on a package, and on the repository publishing it when it is registered.
+type GraphEdge struct {
+	From    string `json:"from"`
+	Package string `json:"package"`
+	Semver  string `json:"semver"`
+	To      string `json:"to,omitempty"`
+}
+
+// Export returns every repository of the graph along with its dependencies and the dependency cycles.
+func (g *Graph) Export() *GraphExport {
+	export := &GraphExport{Repositories: []GraphRepository{}, Edges: []GraphEdge{}, Cycles: g.Cycles()}
+
+	for _, name := range g.nameList {
+		rep := g.repositories[name]
+		export.Repositories = append(export.Repositories, GraphRepository{name, g.packageKeys(name)})
+
+		for _, dep := range rep.Dependencies {
+			key := PackageKey(DependencyEcosystem(rep, dep), dep.Name)
+			export.Edges = append(export.Edges, GraphEdge{name, key, dep.Semver, g.publishers[key]})
+		}
+	}
+
+	if export.Cycles == nil {
+		export.Cycles = [][]string{}
+	}
+
+	return export
+}
+
+// DOT renders the graph in the Graphviz DOT language. Edges go from a repository to what it depends on, and packages
+// published outside of the registered repositories are drawn as boxes.
+func (e *GraphExport) DOT() string {
+	var b strings.Builder
+	external := map[string]bool{}
+
+	b.WriteString("digraph dependencies {\n")
+
+	for _, rep := range e.Repositories {
+		fmt.Fprintf(&b, "\t%s;\n", strconv.Quote(rep.Name))
+	}
+
+	for _, edge := range e.Edges {
+		if edge.To == "" && !external[edge.Package] {
+			external[edge.Package] = true
+			fmt.Fprintf(&b, "\t%s [shape=box];\n", strconv.Quote(edge.Package))
+		}
+	}
+
+	for _, edge := range e.Edges {
+		if edge.To == "" {
+			fmt.Fprintf(&b, "\t%s -> %s [label=%s];\n", strconv.Quote(edge.From), strconv.Quote(edge.Package), strconv.Quote(edge.Semver))
+		} else {
+			label := edge.Package + " " + edge.Semver
+			fmt.Fprintf(&b, "\t%s -> %s [label=%s];\n", strconv.Quote(edge.From), strconv.Quote(edge.To), strconv.Quote(label))
+		}
+	}
+
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// semver returns the range a repository declares for a package.
+func (g *Graph) semver(name string, key string) string {
+	rep := g.repositories[name]
+
+	for _, dep := range rep.Dependencies {
+		if PackageKey(DependencyEcosystem(rep, dep), dep.Name) == key {
+			return dep.Semver
+		}
+	}
+
+	return ""
+}
+
+// packageKeys returns the keys of the packages a repository publishes.
+func (g *Graph) packageKeys(name string) []string {
+	rep := g.repositories[name]
+	keyList := []string{}
+
+	for _, pkg := range rep.Packages {
+		keyList = append(keyList, PackageKey(rep.Ecosystem, pkg))
+	}
+
+	return keyList
+}
+
+// findComponents finds the strongly connected components of the graph with Tarjan's algorithm. Components are
+// numbered in the order they are completed, which puts every component after the ones depending on it.
+func (g *Graph) findComponents() {
+	index := 0
+	indexes := map[string]int{}
+	lowLinks := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+
+	g.components = map[string]int{}
+
+	var connect func(name string)
+	connect = func(name string) {
+		indexes[name] = index
+		lowLinks[name] = index
+		index++
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, dependent := range g.dependents[name] {
+			if _, ok := indexes[dependent]; !ok {
+				connect(dependent)
+				lowLinks[name] = minInt(lowLinks[name], lowLinks[dependent])
+			} else if onStack[dependent] {
+				lowLinks[name] = minInt(lowLinks[name], indexes[dependent])
+			}
+		}
+
+		if lowLinks[name] != indexes[name] {
+			return
+		}
+
+		component := len(g.componentLen)
+		g.componentLen = append(g.componentLen, 0)
+
+		for {
+			member := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[member] = false
+			g.components[member] = component
+			g.componentLen[component]++
+
+			if member == name {
+				break
+			}
+		}
+	}
+
+	for _, name := range g.nameList {
+		if _, ok := indexes[name]; !ok {
+			connect(name)
+		}
+	}
+
+	// Every component upstream of another has a higher number, so going down each level is final once it is reached.
+	g.levels = make([]int, len(g.componentLen))
+	memberList := make([][]string, len(g.componentLen))
+
+	for _, name := range g.nameList {
+		memberList[g.components[name]] = append(memberList[g.components[name]], name)
+	}
+
+	for component := len(g.componentLen) - 1; component >= 0; component-- {
+		for _, name := range memberList[component] {
+			for _, dependent := range g.dependents[name] {
+				if other := g.components[dependent]; other != component {
+					g.levels[other] = maxInt(g.levels[other], g.levels[component]+1)
+				}
+			}
+		}
+	}
+}
+
+// loadGraph loads the dependency graph of every registered repository, from the cache when the DAO is a GraphCache.
+func loadGraph(db *mongo.Database, repDao access.RepositoryDAO) (*Graph, error) {
+	if cache, ok := repDao.(*GraphCache); ok {
+		return cache.Graph(db)
+	}
+
+	repList, err := queryAllRepositories(db, repDao)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewGraph(repList), nil
+}
+
+// queryAllRepositories returns every registered repository, from the cache when the DAO is a GraphCache.
+func queryAllRepositories(db *mongo.Database, repDao access.RepositoryDAO) ([]*models.Repository, error) {
+	if cache, ok := repDao.(*GraphCache); ok {
+		return cache.Repositories(db)
+	}
+
+	count, err := repDao.Count(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return repDao.Query(db, 0, int(count))
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
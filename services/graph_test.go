@@ -0,0 +1,122 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// createPublisher creates a repository publishing a package and depending on the given packages.
+func createPublisher(name string, pkg string, depList ...string) *models.Repository {
+	rep := &models.Repository{Name: name, Packages: []string{pkg}}
+
+	for _, dep := range depList {
+		rep.Dependencies = append(rep.Dependencies, models.Dependency{Name: dep, Semver: "^1.0.0", Installed: "1.0.0"})
+	}
+
+	return rep
+}
+
+func newTestGraph() *Graph {
+	return NewGraph([]*models.Repository{
+		createPublisher("app", "@org/app", "@org/ui", "@org/core", "lodash"),
+		createPublisher("ui", "@org/ui", "@org/core"),
+		createPublisher("core", "@org/core"),
+		createPublisher("left", "@org/left", "@org/right"),
+		createPublisher("right", "@org/right", "@org/left", "@org/core"),
+		createPublisher("self", "@org/self", "@org/self"),
+	})
+}
+
+func TestGraph_Edges(t *testing.T) {
+	g := newTestGraph()
+
+	publisher, ok := g.Publisher("@org/ui")
+	assert.True(t, ok)
+	assert.Equal(t, "ui", publisher)
+
+	_, ok = g.Publisher("lodash")
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{"app", "right", "ui"}, g.Dependents("core"))
+	assert.Equal(t, []string{"core", "ui"}, g.Dependencies("app"))
+	assert.Empty(t, g.Dependencies("core"))
+}
+
+func TestGraph_DependsOn(t *testing.T) {
+	g := newTestGraph()
+
+	assert.True(t, g.DependsOn("app", "core"))
+	assert.True(t, g.DependsOn("left", "core"))
+	assert.True(t, g.DependsOn("left", "right"))
+	assert.True(t, g.DependsOn("right", "left"))
+	assert.False(t, g.DependsOn("core", "app"))
+	assert.False(t, g.DependsOn("app", "left"))
+}
+
+func TestGraph_Cycles(t *testing.T) {
+	g := newTestGraph()
+
+	assert.True(t, g.InCycle("left", "right"))
+	assert.True(t, g.InCycle("self", "self"))
+	assert.False(t, g.InCycle("app", "app"))
+	assert.False(t, g.InCycle("app", "ui"))
+	assert.False(t, g.InCycle("unknown", "unknown"))
+
+	assert.Equal(t, [][]string{{"self"}, {"left", "right"}}, g.Cycles())
+}
+
+func TestGraph_Sort(t *testing.T) {
+	g := newTestGraph()
+
+	assert.Equal(t, []string{"core", "ui", "app"}, g.Sort([]string{"app", "ui", "core"}))
+	assert.Equal(t, []string{"self", "core", "right", "left", "ui"}, g.Sort([]string{"self", "right", "left", "ui", "core"}))
+}
+
+func TestGraph_WalkDependents(t *testing.T) {
+	g := newTestGraph()
+
+	dependentList := g.WalkDependents("@org/core", 0)
+	if assert.Equal(t, 4, len(dependentList)) {
+		assert.Equal(t, DependentRepository{"app", "@org/core", "^1.0.0", 1}, dependentList[0])
+		assert.Equal(t, DependentRepository{"left", "@org/right", "^1.0.0", 2}, dependentList[3])
+	}
+
+	assert.Equal(t, 3, len(g.WalkDependents("@org/core", 1)))
+	assert.Empty(t, g.WalkDependents("unknown", 0))
+}
+
+func TestGraph_WalkDependencies(t *testing.T) {
+	g := newTestGraph()
+
+	dependencyList := g.WalkDependencies("left", 0)
+	if assert.Equal(t, 3, len(dependencyList)) {
+		assert.Equal(t, DependencyPackage{"@org/right", "^1.0.0", "left", "right", 1}, dependencyList[0])
+		assert.Equal(t, DependencyPackage{"@org/core", "^1.0.0", "right", "core", 2}, dependencyList[2])
+	}
+
+	assert.Equal(t, 1, len(g.WalkDependencies("left", 1)))
+	assert.Equal(t, "", g.WalkDependencies("app", 0)[2].Repository)
+}
+
+func TestGraph_Export(t *testing.T) {
+	g := NewGraph([]*models.Repository{
+		createPublisher("app", "@org/app", "@org/core", "lodash"),
+		createPublisher("core", "@org/core"),
+	})
+
+	export := g.Export()
+	assert.Equal(t, 2, len(export.Repositories))
+	assert.Equal(t, []GraphEdge{{"app", "@org/core", "^1.0.0", "core"}, {"app", "lodash", "^1.0.0", ""}}, export.Edges)
+	assert.Empty(t, export.Cycles)
+
+	assert.Equal(t, `digraph dependencies {
+	"app";
+	"core";
+	"lodash" [shape=box];
+	"app" -> "core" [label="@org/core ^1.0.0"];
+	"app" -> "lodash" [label="^1.0.0"];
+}
+`, export.DOT())
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/quantumew/data-access"
+	"github.com/quantumew/data-access/models"
+)
+
+// GraphCache is a repository DAO that keeps every registered repository and their dependency graph between requests,
+// which hooks and cascades would otherwise read in full every time. Writes through the cache drop what it keeps, and
+// it is reloaded at the latest after its TTL so that the writes of other listener instances show up too. A TTL of zero
+// disables caching.
+type GraphCache struct {
+	access.RepositoryDAO
+	ttl time.Duration
+
+	mutex    sync.Mutex
+	repList  []*models.Repository
+	graph    *Graph
+	loadedAt time.Time
+}
+
+// NewGraphCache creates a new GraphCache in front of the given repository DAO.
+func NewGraphCache(dao access.RepositoryDAO, ttl time.Duration) *GraphCache {
+	return &GraphCache{RepositoryDAO: dao, ttl: ttl}
+}
+
+// Graph returns the dependency graph of every registered repository.
+func (c *GraphCache) Graph(db *mongo.Database) (*Graph, error) {
+	graph, _, err := c.load(db)
+	return graph, err
+}
+
+// Repositories returns every registered repository. The repositories are shared and must not be modified.
+func (c *GraphCache) Repositories(db *mongo.Database) ([]*models.Repository, error) {
+	_, repList, err := c.load(db)
+	return repList, err
+}
+
+// Create creates a repository and drops the cached graph.
+func (c *GraphCache) Create(db *mongo.Database, repository *models.Repository) error {
+	defer c.invalidate()
+	return c.RepositoryDAO.Create(db, repository)
+}
+
+// Update updates a repository and drops the cached graph.
+func (c *GraphCache) Update(db *mongo.Database, name string, repository *models.Repository) error {
+	defer c.invalidate()
+	return c.RepositoryDAO.Update(db, name, repository)
+}
+
+// Patch updates several repositories and drops the cached graph.
+func (c *GraphCache) Patch(db *mongo.Database, repoList []*models.Repository) []error {
+	defer c.invalidate()
+	return c.RepositoryDAO.Patch(db, repoList)
+}
+
+// Delete deletes a repository and drops the cached graph.
+func (c *GraphCache) Delete(db *mongo.Database, name string) error {
+	defer c.invalidate()
+	return c.RepositoryDAO.Delete(db, name)
+}
+
+// load returns the cached repositories and graph, reloading them when they were dropped or are too old. Loads hold
+// the lock, so concurrent requests wait for a single load and a write cannot be missed by a load running next to it.
+func (c *GraphCache) load(db *mongo.Database) (*Graph, []*models.Repository, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.graph != nil && time.Since(c.loadedAt) < c.ttl {
+		return c.graph, c.repList, nil
+	}
+
+	repList, err := queryAllRepositories(db, c.RepositoryDAO)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.repList, c.graph, c.loadedAt = repList, NewGraph(repList), time.Now()
+
+	return c.graph, c.repList, nil
+}
+
+// invalidate drops the cached repositories and graph.
+func (c *GraphCache) invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.repList, c.graph = nil, nil
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingRepositoryDAO counts how many times every repository is read.
+type countingRepositoryDAO struct {
+	*mockRepositoryDAO
+	queries int
+}
+
+func (m *countingRepositoryDAO) Query(db *mongo.Database, offset, limit int) ([]*models.Repository, error) {
+	m.queries++
+	return m.mockRepositoryDAO.Query(db, offset, limit)
+}
+
+func TestGraphCache(t *testing.T) {
+	repDAO := &countingRepositoryDAO{mockRepositoryDAO: &mockRepositoryDAO{records: []*models.Repository{
+		createPublisher("ui", "@org/ui", "@org/core"),
+		createPublisher("core", "@org/core"),
+	}}}
+	cache := NewGraphCache(repDAO, time.Hour)
+
+	graph, err := loadGraph(nil, cache)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"ui"}, graph.Dependents("core"))
+	}
+
+	repList, err := queryAllRepositories(nil, cache)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(repList))
+	assert.Equal(t, 1, repDAO.queries)
+
+	// writes drop the cached graph
+	assert.Nil(t, cache.Create(nil, createPublisher("app", "@org/app", "@org/ui")))
+	graph, err = loadGraph(nil, cache)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"app"}, graph.Dependents("ui"))
+	}
+	assert.Equal(t, 2, repDAO.queries)
+
+	// without a TTL nothing is cached
+	cache = NewGraphCache(repDAO, 0)
+	loadGraph(nil, cache)
+	loadGraph(nil, cache)
+	assert.Equal(t, 4, repDAO.queries)
+}
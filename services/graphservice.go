@@ -0,0 +1,51 @@
+package services
+
+import (
+	"github.com/quantumew/data-access"
+	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/errors"
+)
+
+// GraphService provides services related with the dependency graph of the registered repositories.
+type GraphService struct {
+	repDao access.RepositoryDAO
+}
+
+// NewGraphService creates a new GraphService with the given repository DAO.
+func NewGraphService(repDao access.RepositoryDAO) *GraphService {
+	return &GraphService{repDao}
+}
+
+// Dependents returns the repositories affected by a new version of the package, up to the specified depth.
+func (s *GraphService) Dependents(rs app.RequestScope, pkg string, depth int) ([]DependentRepository, error) {
+	graph, err := loadGraph(rs.DB(), s.repDao)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.WalkDependents(pkg, depth), nil
+}
+
+// Dependencies returns the packages the repository with the specified name needs, up to the specified depth.
+func (s *GraphService) Dependencies(rs app.RequestScope, name string, depth int) ([]DependencyPackage, error) {
+	graph, err := loadGraph(rs.DB(), s.repDao)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := graph.Repository(name); !ok {
+		return nil, errors.NotFound("repository")
+	}
+
+	return graph.WalkDependencies(name, depth), nil
+}
+
+// Export returns the whole dependency graph.
+func (s *GraphService) Export(rs app.RequestScope) (*GraphExport, error) {
+	graph, err := loadGraph(rs.DB(), s.repDao)
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.Export(), nil
+}
@@ -0,0 +1,20 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphService_Dependencies(t *testing.T) {
+	s := NewGraphService(&mockRepositoryDAO{records: []*models.Repository{createPublisher("app", "@org/app", "lodash")}})
+
+	dependencyList, err := s.Dependencies(new(MockRequestScope), "app", 0)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 1, len(dependencyList))
+	}
+
+	_, err = s.Dependencies(new(MockRequestScope), "zzz", 0)
+	assert.NotNil(t, err)
+}
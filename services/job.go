@@ -1,65 +1,421 @@
 package services
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/mongo"
 	"github.com/quantumew/data-access"
 	"github.com/quantumew/data-access/models"
 	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/errors"
+	"github.com/quantumew/listener/notify"
 )
 
+// maxQueueAttempts is how many times a dependency is retried against concurrent hooks creating the same job.
+const maxQueueAttempts = 3
+
+// pendingStateList are the states of jobs that have not been picked up yet. Those that were never claimed by a worker
+// can still take new dependencies, a job requeued after a failure or an expired lease retries what it started with.
+var pendingStateList = []models.State{models.Idle, models.Queued, models.Locked}
+
+// claimedStateList are the states of the unfinished jobs of a repository that a worker has claimed, the job in
+// progress and the job waiting for its next attempt. At most one such job exists per repository, and the pending job
+// created while it exists stays locked until it is finished.
+var claimedStateList = []models.State{models.InProgress, models.Queued, models.Failed}
+
+// unfinishedStateList are the states of the jobs that are not done for good.
+var unfinishedStateList = append(append([]models.State{}, pendingStateList...), claimedStateList...)
+
+// resolveStateList is the order the states of the jobs of a repository are looked up in when a job is addressed by
+// the repository name.
+var resolveStateList = []models.State{models.InProgress, models.Queued, models.Failed, models.Idle, models.Locked}
+
 // JobService provides services related with repositories.
 type JobService struct {
-	dao    access.JobDAO
-	repDao access.RepositoryDAO
+	dao           access.JobDAO
+	repDao        access.RepositoryDAO
+	pullRequests  *PullRequestService
+	notifications *notify.Dispatcher
 }
 
 // NewJobService creates a new JobService with the given job DAO.
 func NewJobService(dao access.JobDAO, repDao access.RepositoryDAO) *JobService {
-	return &JobService{dao, repDao}
+	return &JobService{dao: dao, repDao: repDao}
+}
+
+// EnablePullRequests makes the service open a pull request for every job that completes.
+func (s *JobService) EnablePullRequests(pullRequests *PullRequestService) {
+	s.pullRequests = pullRequests
 }
 
-// Get returns the job with the specified the job ID.
+// Get returns the job a repository name addresses, see resolve.
 func (s *JobService) Get(rs app.RequestScope, name string) (*models.Job, error) {
-	return s.dao.Get(rs.DB(), name)
+	return s.resolve(rs.DB(), name)
 }
 
-// CreateJobsFromHook creates a list of jobs from a NPM Hook dependency
-func (s *JobService) CreateJobsFromHook(rs app.RequestScope, hook *models.NpmHook) ([]*models.Job, error) {
-	var jobList []*models.Job
+// resolve returns the job a repository name addresses. A repository may have several jobs at once, a job locked behind
+// the one in progress and the finished ones, so the job being worked on is picked first, then the one waiting to be,
+// then the latest one.
+func (s *JobService) resolve(db *mongo.Database, name string) (*models.Job, error) {
+	for _, state := range resolveStateList {
+		job, err := s.dao.GetByState(db, name, state)
+		if err != nil || job != nil {
+			return job, err
+		}
+	}
+
+	jobList, err := s.dao.QueryByName(db, name, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(jobList) == 0 {
+		return nil, errors.NotFound("job " + name)
+	}
 
-	repList, err := s.repDao.QueryByDependency(rs.DB(), hook.Name)
+	return jobList[0], nil
+}
 
+// resolveID returns the job of a repository with the ID, or the job the repository name addresses when the ID is zero.
+func (s *JobService) resolveID(db *mongo.Database, name string, id int64) (*models.Job, error) {
+	if id == 0 {
+		return s.resolve(db, name)
+	}
+
+	job, err := s.dao.GetByID(db, id)
+	if err != nil || job == nil || job.Name != name {
+		return nil, errors.NotFound(fmt.Sprintf("job %s %d", name, id))
+	}
+
+	return job, nil
+}
+
+// CountHistory returns the number of jobs of the repository.
+func (s *JobService) CountHistory(rs app.RequestScope, name string) (int64, error) {
+	return s.dao.CountByName(rs.DB(), name)
+}
+
+// QueryHistory returns the jobs of the repository, latest first, with the specified offset and limit.
+func (s *JobService) QueryHistory(rs app.RequestScope, name string, offset, limit int) ([]*models.Job, error) {
+	return s.dao.QueryByName(rs.DB(), name, offset, limit)
+}
+
+// HookResult is the outcome of a hook, the jobs it created or updated and the repositories it skipped.
+type HookResult struct {
+	Jobs    []*models.Job       `json:"jobs"`
+	Skipped []SkippedRepository `json:"skipped"`
+}
+
+// CreateJobsFromHook creates or updates the jobs of every repository the dependency an npm hook publishes should be
+// updated in.
+func (s *JobService) CreateJobsFromHook(rs app.RequestScope, hook *models.NpmHook) (*HookResult, error) {
+	return s.Publish(rs, NpmPublish(hook))
+}
+
+// CreateJobsFromPayload creates or updates the jobs of every repository the dependencies a hook delivery from a
+// registry of the ecosystem publishes should be updated in.
+func (s *JobService) CreateJobsFromPayload(rs app.RequestScope, ecosystemName string, payload []byte) (*HookResult, error) {
+	ecosystem, err := LookupEcosystem(ecosystemName)
 	if err != nil {
-		return jobList, err
+		return nil, errors.NotFound("ecosystem " + ecosystemName)
 	}
 
-	filterRepList := FilterByVersion(repList, hook)
+	pubList, err := ecosystem.ParseHook(payload)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, rep := range filterRepList {
-		existingJob, err := s.dao.GetByName(rs.DB(), rep.Name)
-		job := existingJob
+	return s.publishAll(rs, pubList)
+}
+
+// Publish creates or updates the jobs of every repository the published dependency should be updated in.
+func (s *JobService) Publish(rs app.RequestScope, pub *models.PublishedDependency) (*HookResult, error) {
+	return s.fanOut(rs, pub, false)
+}
+
+// publishAll publishes several dependencies and merges the outcomes.
+func (s *JobService) publishAll(rs app.RequestScope, pubList []*models.PublishedDependency) (*HookResult, error) {
+	result := &HookResult{}
 
+	for _, pub := range pubList {
+		pubResult, err := s.Publish(rs, pub)
 		if err != nil {
-			return jobList, err
+			return nil, err
 		}
 
-		publishedDep := models.PublishedDependency{Name: hook.Name, Version: hook.Version}
+		result.Jobs = append(result.Jobs, pubResult.Jobs...)
+		result.Skipped = append(result.Skipped, pubResult.Skipped...)
+	}
+
+	return result, nil
+}
+
+// hookTarget is a repository a hook queues a published dependency on, with the repositories its job waits for and
+// the workspaces of the repository to update.
+type hookTarget struct {
+	rep           *models.Repository
+	blockerList   []string
+	workspaceList []string
+}
+
+// fanOut queues a published dependency on every repository that should be updated. Targets are written one at a
+// time, so an error leaves the earlier ones queued. Queueing the same dependency twice is a no-op, which lets a
+// redelivered hook finish the fan-out without duplicating the dependency on the jobs it already reached.
+func (s *JobService) fanOut(rs app.RequestScope, pub *models.PublishedDependency, cascade bool) (*HookResult, error) {
+	targetList, skippedList, err := s.planHook(rs, pub, cascade)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &HookResult{Skipped: skippedList}
 
-		if existingJob.Name != rep.Name || existingJob.State == models.InProgress {
-			publishedDepList := []*models.PublishedDependency{&publishedDep}
-			job = models.NewJobFromRepository(rep, publishedDepList)
+	for _, target := range targetList {
+		job, err := s.queueDependency(rs, target.rep, target.publish(pub), target.blockerList)
 
-			// Jobs in progress that get new dependencies, get a new job that is locked until it is complete.
-			if existingJob.State == models.InProgress {
-				existingJob.State = models.Locked
+		if err != nil {
+			return nil, err
+		}
+
+		result.Jobs = append(result.Jobs, job)
+	}
+
+	return result, nil
+}
+
+// planHook returns the repositories a published dependency should be queued on and the ones it skips. Repositories
+// are returned in dependency order, and each one lists the other repositories it depends on so its job only runs once
+// everything upstream of it is updated. Publishes cascaded from a completed job are not sent around a dependency
+// cycle, which would otherwise update the same repositories forever.
+func (s *JobService) planHook(rs app.RequestScope, pub *models.PublishedDependency, cascade bool) ([]hookTarget, []SkippedRepository, error) {
+	repList, err := s.repDao.QueryByDependency(rs.DB(), pub.Name)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	graph, err := loadGraph(rs.DB(), s.repDao)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filterRepList, skippedList := FilterByVersion(repList, pub)
+	publisher, _ := graph.Publisher(PackageKey(pub.Ecosystem, pub.Name))
+	repMap := map[string]*models.Repository{}
+	var nameList []string
+
+	for _, rep := range filterRepList {
+		if cascade && graph.InCycle(publisher, rep.Name) {
+			skippedList = append(skippedList, SkippedRepository{rep.Name, "dependency cycle with " + publisher})
+			continue
+		}
+
+		repMap[rep.Name] = rep
+		nameList = append(nameList, rep.Name)
+	}
+
+	var targetList []hookTarget
+
+	for _, name := range graph.Sort(nameList) {
+		target := hookTarget{rep: repMap[name]}
+		target.workspaceList, _ = MatchWorkspaces(target.rep, pub)
+
+		for _, upstream := range targetList {
+			if graph.DependsOn(name, upstream.rep.Name) && !graph.InCycle(name, upstream.rep.Name) {
+				target.blockerList = append(target.blockerList, upstream.rep.Name)
 			}
-		} else {
-			job.Dependencies = append(job.Dependencies, &publishedDep)
 		}
 
-		jobList = append(jobList, job)
+		targetList = append(targetList, target)
 	}
 
-	return jobList, nil
+	return targetList, skippedList, nil
+}
+
+// publish returns the copy of a published dependency queued on the target. Repositories split into workspaces are
+// told which workspaces to update.
+func (t hookTarget) publish(pub *models.PublishedDependency) *models.PublishedDependency {
+	publishedDep := *pub
+	publishedDep.Workspaces = nil
+
+	if len(t.rep.Workspaces) > 0 {
+		publishedDep.Workspaces = t.workspaceList
+	}
+
+	return &publishedDep
+}
+
+// cascade queues the packages a completed job published on the repositories depending on them.
+func (s *JobService) cascade(rs app.RequestScope, publishedList []*models.PublishedDependency) error {
+	for _, pub := range publishedList {
+		if _, err := s.fanOut(rs, pub, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queueDependency adds a published dependency to the pending job of a repository, creating the job if there is none.
+// Every write is a single atomic document update, so concurrent hooks for the same repository never lose a dependency.
+// A dependency the job already lists with the same ecosystem, name and version is not appended again. The job, new or
+// existing, is locked until the repositories in the blocker list have no unfinished job left.
+func (s *JobService) queueDependency(rs app.RequestScope, rep *models.Repository, dep *models.PublishedDependency, blockerList []string) (*models.Job, error) {
+	var err error
+	db := rs.DB()
+	lock := &models.Transition{To: models.Locked, Actor: SystemActor, Time: rs.Now()}
+
+	for attempt := 0; attempt < maxQueueAttempts; attempt++ {
+		var job *models.Job
+		var active bool
+
+		if job, err = s.dao.AppendDependency(db, rep.Name, dep, blockerList, lock, pendingStateList); err != nil {
+			return nil, err
+		}
+
+		// Blockers that finished before they were merged into the job are dropped again.
+		if job != nil {
+			if job.State == models.Locked && len(blockerList) > 0 {
+				return s.unlockIfUnblocked(rs, job)
+			}
+
+			return job, nil
+		}
+
+		if active, err = s.isBlocking(rs, rep.Name, rep.Name); err != nil {
+			return nil, err
+		}
+
+		job = newPendingJob(rs, rep, dep, blockerList, active)
+
+		// Only one pending job that was never claimed may exist per repository. When a concurrent hook created it
+		// first the insert is rejected and the dependency is appended to that job on the next attempt.
+		if err = s.dao.Create(db, job); err != nil {
+			continue
+		}
+
+		s.notify(rs, notify.JobCreated, job)
+
+		if job.State == models.Locked {
+			if job, err = s.unlockIfUnblocked(rs, job); err == nil && job.State == models.Locked {
+				s.notify(rs, notify.JobLocked, job)
+			}
+
+			return job, err
+		}
+
+		return job, nil
+	}
+
+	return nil, err
+}
+
+// listsDependency returns whether a job already lists a published dependency, with the same ecosystem, name and version.
+func listsDependency(job *models.Job, dep *models.PublishedDependency) bool {
+	for _, other := range job.Dependencies {
+		if other.Ecosystem == dep.Ecosystem && other.Name == dep.Name && other.Version == dep.Version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newPendingJob builds the job queueing a published dependency on a repository that has no pending job.
+func newPendingJob(rs app.RequestScope, rep *models.Repository, dep *models.PublishedDependency, blockerList []string, active bool) *models.Job {
+	job := models.NewJobFromRepository(rep, []*models.PublishedDependency{dep})
+
+	// Dependencies published within the debounce window are appended to this job before it can be picked up.
+	job.NotBefore = rs.Now().Add(DebounceWindow(rep))
+	job.BlockedBy = append([]string{}, blockerList...)
+
+	// Jobs in progress or waiting to be retried that get new dependencies, get a new job that is locked until they
+	// are finished.
+	if active {
+		job.BlockedBy = append(job.BlockedBy, rep.Name)
+	}
+
+	if len(job.BlockedBy) > 0 {
+		job.State = models.Locked
+	}
+
+	return job
+}
+
+// wasClaimed returns whether a worker has claimed the job before.
+func wasClaimed(job *models.Job) bool {
+	for _, transition := range job.Transitions {
+		if transition.To == models.InProgress {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DebounceWindow returns how long a new job of the repository waits for more published dependencies before it is queued.
+func DebounceWindow(rep *models.Repository) time.Duration {
+	if window, err := time.ParseDuration(rep.Config.Debounce); err == nil {
+		return window
+	}
+
+	return app.Config.Debounce
+}
+
+// unlockIfUnblocked drops the blockers of a locked job that have already finished, which happens when they complete
+// between the lookup and the write of the locked job. The job moves back to idle once no blocker is left.
+func (s *JobService) unlockIfUnblocked(rs app.RequestScope, job *models.Job) (*models.Job, error) {
+	var blockedList []string
+
+	for _, blocker := range job.BlockedBy {
+		blocked, err := s.isBlocking(rs, job.Name, blocker)
+		if err != nil {
+			return job, err
+		}
+
+		if blocked {
+			blockedList = append(blockedList, blocker)
+			continue
+		}
+
+		if _, err := s.dao.UnblockJob(rs.DB(), job.Name, blocker, &models.Transition{
+			From:  models.Locked,
+			To:    models.Idle,
+			Actor: SystemActor,
+			Time:  rs.Now(),
+		}); err != nil {
+			return job, err
+		}
+	}
+
+	job.BlockedBy = blockedList
+	if len(blockedList) == 0 {
+		job.State = models.Idle
+	}
+
+	return job, nil
+}
+
+// isBlocking returns whether a repository still has a job the job of another repository has to wait for. A job is
+// blocked by the claimed job of its own repository, or by any unfinished job of another.
+func (s *JobService) isBlocking(rs app.RequestScope, name string, blocker string) (bool, error) {
+	if blocker == name {
+		return s.hasJobIn(rs.DB(), blocker, claimedStateList)
+	}
+
+	return s.hasJobIn(rs.DB(), blocker, unfinishedStateList)
+}
+
+// hasJobIn returns whether the repository has a job in one of the states.
+func (s *JobService) hasJobIn(db *mongo.Database, name string, stateList []models.State) (bool, error) {
+	for _, state := range stateList {
+		job, err := s.dao.GetByState(db, name, state)
+		if err != nil || job != nil {
+			return job != nil, err
+		}
+	}
+
+	return false, nil
 }
 
 // Create creates a new job.
@@ -70,27 +426,41 @@ func (s *JobService) Create(rs app.RequestScope, model *models.Job) (*models.Job
 	if err := s.dao.Create(rs.DB(), model); err != nil {
 		return nil, err
 	}
-	return s.dao.Get(rs.DB(), model.Name)
+	return s.dao.GetByID(rs.DB(), model.ID)
 }
 
-// Update updates the job with the specified name.
+// Update updates the job a repository name addresses, see resolve. The state and its history can only be changed
+// through Transition.
 func (s *JobService) Update(rs app.RequestScope, name string, model *models.Job) (*models.Job, error) {
 	if err := model.Validate(); err != nil {
 		return nil, err
 	}
-	if err := s.dao.Update(rs.DB(), name, model); err != nil {
+
+	current, err := s.resolve(rs.DB(), name)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.State != model.State {
+		return nil, errors.Conflict("job "+name, "state can only be changed through the transitions endpoint")
+	}
+
+	model.ID = current.ID
+	model.Transitions = current.Transitions
+
+	if err := s.dao.UpdateByID(rs.DB(), current.ID, model); err != nil {
 		return nil, err
 	}
-	return s.dao.Get(rs.DB(), model.Name)
+	return s.dao.GetByID(rs.DB(), current.ID)
 }
 
-// Delete deletes the job with the specified name.
+// Delete deletes the job a repository name addresses, see resolve, leaving the other jobs of the repository.
 func (s *JobService) Delete(rs app.RequestScope, name string) (*models.Job, error) {
-	job, err := s.dao.Get(rs.DB(), name)
+	job, err := s.resolve(rs.DB(), name)
 	if err != nil {
 		return nil, err
 	}
-	err = s.dao.Delete(rs.DB(), name)
+	err = s.dao.DeleteByID(rs.DB(), job.ID)
 	return job, err
 }
 
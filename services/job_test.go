@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"testing"
+	"time"
 )
 
 type MockRequestScope struct {
@@ -16,10 +17,14 @@ type MockRequestScope struct {
 	app.RequestScope
 }
 
-func (m MockRequestScope) DB() *mongo.Database {
+func (m *MockRequestScope) DB() *mongo.Database {
 	return &mongo.Database{}
 }
 
+func (m *MockRequestScope) Now() time.Time {
+	return time.Date(2018, 9, 1, 0, 0, 0, 0, time.UTC)
+}
+
 func TestNewJobService(t *testing.T) {
 	dao := newMockJobDAO()
 	s := NewJobService(dao, newMockRepositoryDAO())
@@ -28,12 +33,12 @@ func TestNewJobService(t *testing.T) {
 
 func TestJobService_Get(t *testing.T) {
 	s := NewJobService(newMockJobDAO(), newMockRepositoryDAO())
-	job, err := s.Get(new(MockRequestScope), 1)
+	job, err := s.Get(new(MockRequestScope), "aaa")
 	if assert.Nil(t, err) && assert.NotNil(t, job) {
-		assert.Equal(t, "aaa", job.Name)
+		assert.Equal(t, int64(1), job.ID)
 	}
 
-	job, err = s.Get(new(MockRequestScope), 100)
+	job, err = s.Get(new(MockRequestScope), "zzz")
 	assert.NotNil(t, err)
 }
 
@@ -61,20 +66,20 @@ func TestJobService_Create(t *testing.T) {
 
 func TestJobService_Update(t *testing.T) {
 	s := NewJobService(newMockJobDAO(), newMockRepositoryDAO())
-	job, err := s.Update(new(MockRequestScope), 2, createJob("ddd", "a", "1.2.4"))
+	job, err := s.Update(new(MockRequestScope), "bbb", createJob("ddd", "a", "1.2.4"))
 	if assert.Nil(t, err) && assert.NotNil(t, job) {
 		assert.Equal(t, int64(2), job.ID)
 		assert.Equal(t, "ddd", job.Name)
 	}
 
 	// dao error
-	_, err = s.Update(new(MockRequestScope), 100, &models.Job{
+	_, err = s.Update(new(MockRequestScope), "zzz", &models.Job{
 		Name: "ddd",
 	})
 	assert.NotNil(t, err)
 
 	// validation error
-	_, err = s.Update(new(MockRequestScope), 2, &models.Job{
+	_, err = s.Update(new(MockRequestScope), "bbb", &models.Job{
 		Name: "",
 	})
 	assert.NotNil(t, err)
@@ -82,16 +87,62 @@ func TestJobService_Update(t *testing.T) {
 
 func TestJobService_Delete(t *testing.T) {
 	s := NewJobService(newMockJobDAO(), newMockRepositoryDAO())
-	job, err := s.Delete(new(MockRequestScope), 2)
+	job, err := s.Delete(new(MockRequestScope), "bbb")
 	if assert.Nil(t, err) && assert.NotNil(t, job) {
 		assert.Equal(t, int64(2), job.ID)
 		assert.Equal(t, "bbb", job.Name)
 	}
 
-	_, err = s.Delete(new(MockRequestScope), 2)
+	_, err = s.Delete(new(MockRequestScope), "bbb")
 	assert.NotNil(t, err)
 }
 
+func TestJobService_Resolve(t *testing.T) {
+	jobDAO := &mockJobDAO{}
+	s := NewJobService(jobDAO, newMockRepositoryDAO())
+	rs := new(MockRequestScope)
+
+	for _, state := range []models.State{models.Succeeded, models.InProgress, models.Locked, models.Succeeded} {
+		job := createJob("aaa", "lib", "1.0.0")
+		job.State = state
+		jobDAO.Create(nil, job)
+	}
+
+	// the job in progress is addressed first, then the pending one, then the latest
+	job, err := s.Get(rs, "aaa")
+	if assert.Nil(t, err) {
+		assert.Equal(t, int64(2), job.ID)
+	}
+
+	jobDAO.records[1].State = models.Succeeded
+	job, err = s.Get(rs, "aaa")
+	if assert.Nil(t, err) {
+		assert.Equal(t, int64(3), job.ID)
+	}
+
+	// only the addressed job is deleted
+	job, err = s.Delete(rs, "aaa")
+	if assert.Nil(t, err) {
+		assert.Equal(t, int64(3), job.ID)
+	}
+
+	job, err = s.Get(rs, "aaa")
+	if assert.Nil(t, err) {
+		assert.Equal(t, int64(4), job.ID)
+	}
+
+	count, err := s.CountHistory(rs, "aaa")
+	if assert.Nil(t, err) {
+		assert.Equal(t, int64(3), count)
+	}
+
+	jobList, err := s.QueryHistory(rs, "aaa", 1, 5)
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(jobList)) {
+		assert.Equal(t, int64(2), jobList[0].ID)
+		assert.Equal(t, int64(1), jobList[1].ID)
+	}
+}
+
 func TestJobService_Query(t *testing.T) {
 	s := NewJobService(newMockJobDAO(), newMockRepositoryDAO())
 	result, err := s.Query(new(MockRequestScope), 1, 2)
@@ -100,6 +151,215 @@ func TestJobService_Query(t *testing.T) {
 	}
 }
 
+func TestJobService_CreateJobsFromHook(t *testing.T) {
+	jobDAO := newMockJobDAO().(*mockJobDAO)
+	jobDAO.records[1].State = models.InProgress
+	repDAO := &mockRepositoryDAO{records: []*models.Repository{
+		createRepository("aaa", "lib", "^1.0.0", "1.0.0"),
+		createRepository("bbb", "lib", "^1.0.0", "1.0.0"),
+		createRepository("ddd", "lib", "^1.0.0", "1.0.0"),
+		createRepository("eee", "lib", "^2.0.0", "2.0.0"),
+	}}
+	s := NewJobService(jobDAO, repDAO)
+
+	result, err := s.CreateJobsFromHook(new(MockRequestScope), &models.NpmHook{Name: "lib", Version: "1.1.0"})
+	if assert.Nil(t, err) && assert.Equal(t, 3, len(result.Jobs)) {
+		// idle job gets the dependency appended
+		assert.Equal(t, int64(1), result.Jobs[0].ID)
+		assert.Equal(t, models.Idle, result.Jobs[0].State)
+		assert.Equal(t, 2, len(result.Jobs[0].Dependencies))
+
+		// job in progress gets a locked job queued behind it
+		assert.Equal(t, "bbb", result.Jobs[1].Name)
+		assert.Equal(t, models.Locked, result.Jobs[1].State)
+		assert.Equal(t, models.InProgress, jobDAO.records[1].State)
+
+		// repository without a job gets a new one
+		assert.Equal(t, "ddd", result.Jobs[2].Name)
+		assert.Equal(t, models.Idle, result.Jobs[2].State)
+	}
+
+	if assert.Equal(t, 1, len(result.Skipped)) {
+		assert.Equal(t, "eee", result.Skipped[0].Name)
+	}
+
+	assert.Equal(t, 5, len(jobDAO.records))
+
+	// a second hook is appended to the locked job rather than creating another one
+	result, err = s.CreateJobsFromHook(new(MockRequestScope), &models.NpmHook{Name: "lib", Version: "1.2.0"})
+	if assert.Nil(t, err) && assert.Equal(t, 3, len(result.Jobs)) {
+		assert.Equal(t, models.Locked, result.Jobs[1].State)
+		assert.Equal(t, 2, len(result.Jobs[1].Dependencies))
+	}
+
+	assert.Equal(t, 5, len(jobDAO.records))
+}
+
+func TestJobService_CreateJobsFromHook_Redelivered(t *testing.T) {
+	jobDAO := &mockJobDAO{}
+	repDAO := &mockRepositoryDAO{records: []*models.Repository{
+		createRepository("aaa", "lib", "^1.0.0", "1.0.0"),
+		createRepository("bbb", "lib", "^1.0.0", "1.0.0"),
+	}}
+	s := NewJobService(jobDAO, repDAO)
+	hook := &models.NpmHook{Name: "lib", Version: "1.1.0"}
+
+	_, err := s.CreateJobsFromHook(new(MockRequestScope), hook)
+	assert.Nil(t, err)
+
+	result, err := s.CreateJobsFromHook(new(MockRequestScope), hook)
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(result.Jobs)) {
+		assert.Equal(t, 1, len(result.Jobs[0].Dependencies))
+		assert.Equal(t, 1, len(result.Jobs[1].Dependencies))
+	}
+
+	assert.Equal(t, 2, len(jobDAO.records))
+}
+
+func TestJobService_CreateJobsFromHook_Concurrent(t *testing.T) {
+	jobDAO := &racingJobDAO{mockJobDAO: &mockJobDAO{}}
+	repDAO := &mockRepositoryDAO{records: []*models.Repository{createRepository("aaa", "lib", "^1.0.0", "1.0.0")}}
+	s := NewJobService(jobDAO, repDAO)
+
+	result, err := s.CreateJobsFromHook(new(MockRequestScope), &models.NpmHook{Name: "lib", Version: "1.1.0"})
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(result.Jobs)) {
+		assert.Equal(t, 2, len(result.Jobs[0].Dependencies))
+	}
+
+	assert.Equal(t, 1, len(jobDAO.records))
+}
+
+func TestJobService_CreateJobsFromHook_Unlock(t *testing.T) {
+	jobDAO := &finishingJobDAO{mockJobDAO: newMockJobDAO().(*mockJobDAO)}
+	jobDAO.records[0].State = models.InProgress
+	repDAO := &mockRepositoryDAO{records: []*models.Repository{createRepository("aaa", "lib", "^1.0.0", "1.0.0")}}
+	s := NewJobService(jobDAO, repDAO)
+
+	result, err := s.CreateJobsFromHook(new(MockRequestScope), &models.NpmHook{Name: "lib", Version: "1.1.0"})
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(result.Jobs)) {
+		assert.Equal(t, models.Idle, result.Jobs[0].State)
+	}
+}
+
+func TestJobService_CreateJobsFromHook_Debounce(t *testing.T) {
+	jobDAO := &mockJobDAO{}
+	rep := createRepository("aaa", "lib", "^1.0.0", "1.0.0")
+	rep.Dependencies = append(rep.Dependencies, models.Dependency{Name: "other", Semver: "^1.0.0", Installed: "1.0.0"})
+	rep.Config.Debounce = "2m"
+	s := NewJobService(jobDAO, &mockRepositoryDAO{records: []*models.Repository{rep}})
+	rs := new(MockRequestScope)
+
+	_, err := s.CreateJobsFromHook(rs, &models.NpmHook{Name: "lib", Version: "1.1.0"})
+	assert.Nil(t, err)
+	_, err = s.CreateJobsFromHook(rs, &models.NpmHook{Name: "other", Version: "1.1.0"})
+	assert.Nil(t, err)
+
+	if assert.Equal(t, 1, len(jobDAO.records)) {
+		assert.Equal(t, 2, len(jobDAO.records[0].Dependencies))
+		assert.Equal(t, rs.Now().Add(2*time.Minute), jobDAO.records[0].NotBefore)
+	}
+
+	// the job is only queued once the window closes
+	assert.Nil(t, s.Sweep(nil, rs.Now().Add(time.Minute)))
+	assert.Equal(t, models.Idle, jobDAO.records[0].State)
+	assert.Nil(t, s.Sweep(nil, rs.Now().Add(2*time.Minute)))
+	assert.Equal(t, models.Queued, jobDAO.records[0].State)
+}
+
+func TestJobService_CreateJobsFromHook_Graph(t *testing.T) {
+	jobDAO := &mockJobDAO{}
+	repDAO := &mockRepositoryDAO{records: []*models.Repository{
+		createPublisher("app", "@org/app", "lib", "@org/ui"),
+		createPublisher("ui", "@org/ui", "lib", "@org/core"),
+		createPublisher("core", "@org/core", "lib"),
+	}}
+	s := NewJobService(jobDAO, repDAO)
+
+	result, err := s.CreateJobsFromHook(new(MockRequestScope), &models.NpmHook{Name: "lib", Version: "1.1.0"})
+	if assert.Nil(t, err) && assert.Equal(t, 3, len(result.Jobs)) {
+		// upstream repositories come first and downstream jobs wait on every one of them
+		assert.Equal(t, "core", result.Jobs[0].Name)
+		assert.Equal(t, models.Idle, result.Jobs[0].State)
+		assert.Equal(t, "ui", result.Jobs[1].Name)
+		assert.Equal(t, models.Locked, result.Jobs[1].State)
+		assert.Equal(t, []string{"core"}, result.Jobs[1].BlockedBy)
+		assert.Equal(t, "app", result.Jobs[2].Name)
+		assert.Equal(t, []string{"core", "ui"}, result.Jobs[2].BlockedBy)
+	}
+}
+
+func TestJobService_CreateJobsFromHook_GraphPending(t *testing.T) {
+	jobDAO := &mockJobDAO{}
+	repDAO := &mockRepositoryDAO{records: []*models.Repository{
+		createPublisher("ui", "@org/ui", "lib", "@org/core", "other"),
+		createPublisher("core", "@org/core", "lib"),
+	}}
+	s := NewJobService(jobDAO, repDAO)
+	rs := new(MockRequestScope)
+
+	_, err := s.CreateJobsFromHook(rs, &models.NpmHook{Name: "other", Version: "1.1.0"})
+	assert.Nil(t, err)
+
+	// the pending job of the downstream repository is locked behind the new upstream job
+	result, err := s.CreateJobsFromHook(rs, &models.NpmHook{Name: "lib", Version: "1.1.0"})
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(result.Jobs)) {
+		assert.Equal(t, "ui", result.Jobs[1].Name)
+		assert.Equal(t, models.Locked, result.Jobs[1].State)
+		assert.Equal(t, []string{"core"}, result.Jobs[1].BlockedBy)
+		assert.Equal(t, 2, len(result.Jobs[1].Dependencies))
+	}
+
+	// a blocker that finished before it was merged is dropped again
+	jobDAO.records[1].State = models.Succeeded
+	job, err := s.queueDependency(rs, repDAO.records[0], &models.PublishedDependency{Name: "other", Version: "1.2.0"}, []string{"core"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, models.Idle, job.State)
+		assert.Empty(t, job.BlockedBy)
+	}
+}
+
+func TestDebounceWindow(t *testing.T) {
+	defer func(window time.Duration) { app.Config.Debounce = window }(app.Config.Debounce)
+	app.Config.Debounce = time.Minute
+	rep := createRepository("aaa", "lib", "^1.0.0", "1.0.0")
+
+	assert.Equal(t, time.Minute, DebounceWindow(rep))
+
+	rep.Config.Debounce = "30s"
+	assert.Equal(t, 30*time.Second, DebounceWindow(rep))
+}
+
+// racingJobDAO simulates another hook creating the pending job between the append and the insert.
+type racingJobDAO struct {
+	*mockJobDAO
+	raced bool
+}
+
+func (m *racingJobDAO) AppendDependency(db *mongo.Database, name string, dep *models.PublishedDependency, blockerList []string, transition *models.Transition, stateList []models.State) (*models.Job, error) {
+	if !m.raced {
+		m.raced = true
+		other := createJob(name, "lib", "1.0.1")
+		m.mockJobDAO.Create(db, other)
+		return nil, nil
+	}
+	return m.mockJobDAO.AppendDependency(db, name, dep, blockerList, transition, stateList)
+}
+
+// finishingJobDAO simulates the job in progress finishing while a locked job is being created behind it.
+type finishingJobDAO struct {
+	*mockJobDAO
+}
+
+func (m *finishingJobDAO) Create(db *mongo.Database, job *models.Job) error {
+	for _, record := range m.records {
+		if record.State == models.InProgress {
+			record.State = models.Idle
+			record.Name = "finished"
+		}
+	}
+	return m.mockJobDAO.Create(db, job)
+}
+
 func createJob(name string, depName string, depVersion string) *models.Job {
 	return &models.Job{
 		Name:  name,
@@ -128,24 +388,59 @@ type mockJobDAO struct {
 	records []*models.Job
 }
 
-func (m *mockJobDAO) Get(db *mongo.Database, id int64) (*models.Job, error) {
+func (m *mockJobDAO) Get(db *mongo.Database, name string) (*models.Job, error) {
+	return m.GetByName(db, name)
+}
+
+func (m *mockJobDAO) GetByName(db *mongo.Database, name string) (*models.Job, error) {
 	for _, record := range m.records {
-		if record.ID == id {
+		if record.Name == name {
 			return record, nil
 		}
 	}
 	return nil, errors.New("not found")
 }
 
-func (m *mockJobDAO) GetByName(db *mongo.Database, name string) (*models.Job, error) {
+func (m *mockJobDAO) GetByID(db *mongo.Database, id int64) (*models.Job, error) {
 	for _, record := range m.records {
-		if record.Name == name {
+		if record.ID == id {
 			return record, nil
 		}
 	}
 	return nil, errors.New("not found")
 }
 
+func (m *mockJobDAO) QueryByName(db *mongo.Database, name string, offset, limit int) ([]*models.Job, error) {
+	var jobList []*models.Job
+	for i := len(m.records) - 1; i >= 0; i-- {
+		if m.records[i].Name == name {
+			jobList = append(jobList, m.records[i])
+		}
+	}
+	if offset >= len(jobList) {
+		return nil, nil
+	}
+	jobList = jobList[offset:]
+	if limit < len(jobList) {
+		jobList = jobList[:limit]
+	}
+	return jobList, nil
+}
+
+func (m *mockJobDAO) CountByName(db *mongo.Database, name string) (int64, error) {
+	jobList, err := m.QueryByName(db, name, 0, len(m.records))
+	return int64(len(jobList)), err
+}
+
+func (m *mockJobDAO) GetByState(db *mongo.Database, name string, state models.State) (*models.Job, error) {
+	for _, record := range m.records {
+		if record.Name == name && record.State == state {
+			return record, nil
+		}
+	}
+	return nil, nil
+}
+
 func (m *mockJobDAO) Query(db *mongo.Database, offset, limit int) ([]*models.Job, error) {
 	return m.records[offset : offset+limit], nil
 }
@@ -154,20 +449,56 @@ func (m *mockJobDAO) Count(db *mongo.Database) (int64, error) {
 	return int64(len(m.records)), nil
 }
 
+func (m *mockJobDAO) QueryPublished(db *mongo.Database) ([]*models.PublishedDependency, error) {
+	var publishedList []*models.PublishedDependency
+	seen := map[string]bool{}
+	for _, record := range m.records {
+		for _, dep := range record.Dependencies {
+			key := PackageKey(dep.Ecosystem, dep.Name) + "@" + dep.Version
+			if !seen[key] {
+				seen[key] = true
+				publishedList = append(publishedList, &models.PublishedDependency{Ecosystem: dep.Ecosystem, Name: dep.Name, Version: dep.Version})
+			}
+		}
+	}
+	return publishedList, nil
+}
+
 func (m *mockJobDAO) Create(db *mongo.Database, job *models.Job) error {
 	if job.ID != 0 {
 		return errors.New("Id cannot be set")
 	}
+
+	pending := func(job *models.Job) bool {
+		return job.State == models.Idle || job.State == models.Queued || job.State == models.Locked
+	}
+	for _, record := range m.records {
+		if record.Name == job.Name && pending(record) && !wasClaimed(record) && pending(job) {
+			return errors.New("duplicate pending job")
+		}
+	}
+
 	job.ID = int64(len(m.records) + 1)
 
 	m.records = append(m.records, job)
 	return nil
 }
 
-func (m *mockJobDAO) Update(db *mongo.Database, id int64, job *models.Job) error {
-	job.ID = id
+func (m *mockJobDAO) Update(db *mongo.Database, name string, job *models.Job) error {
+	for i, record := range m.records {
+		if record.Name == name {
+			job.ID = record.ID
+			m.records[i] = job
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (m *mockJobDAO) UpdateByID(db *mongo.Database, id int64, job *models.Job) error {
 	for i, record := range m.records {
 		if record.ID == id {
+			job.ID = id
 			m.records[i] = job
 			return nil
 		}
@@ -175,7 +506,205 @@ func (m *mockJobDAO) Update(db *mongo.Database, id int64, job *models.Job) error
 	return errors.New("not found")
 }
 
-func (m *mockJobDAO) Delete(db *mongo.Database, id int64) error {
+func (m *mockJobDAO) Transition(db *mongo.Database, name string, transition *models.Transition) (bool, error) {
+	for _, record := range m.records {
+		if record.Name == name && record.State == transition.From {
+			transitionRecord(record, transition)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockJobDAO) TransitionByID(db *mongo.Database, id int64, transition *models.Transition) (bool, error) {
+	for _, record := range m.records {
+		if record.ID == id && record.State == transition.From {
+			transitionRecord(record, transition)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func transitionRecord(record *models.Job, transition *models.Transition) {
+	record.State = transition.To
+	record.Transitions = append(record.Transitions, transition)
+}
+
+func (m *mockJobDAO) Unblock(db *mongo.Database, blocker string, transition *models.Transition) (int64, error) {
+	var count int64
+	for _, record := range m.records {
+		if record.State != models.Locked || !containsString(record.BlockedBy, blocker) {
+			continue
+		}
+		var blockedBy []string
+		for _, name := range record.BlockedBy {
+			if name != blocker {
+				blockedBy = append(blockedBy, name)
+			}
+		}
+		record.BlockedBy = blockedBy
+		if len(blockedBy) == 0 {
+			record.State = transition.To
+			record.Transitions = append(record.Transitions, transition)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockJobDAO) UnblockJob(db *mongo.Database, name string, blocker string, transition *models.Transition) (bool, error) {
+	for _, record := range m.records {
+		if record.Name != name || record.State != models.Locked || !containsString(record.BlockedBy, blocker) {
+			continue
+		}
+		var blockedBy []string
+		for _, other := range record.BlockedBy {
+			if other != blocker {
+				blockedBy = append(blockedBy, other)
+			}
+		}
+		record.BlockedBy = blockedBy
+		if len(blockedBy) == 0 {
+			transitionRecord(record, transition)
+			return true, nil
+		}
+		return false, nil
+	}
+	return false, nil
+}
+
+func (m *mockJobDAO) TransitionDue(db *mongo.Database, transition *models.Transition) (int64, error) {
+	var count int64
+	for _, record := range m.records {
+		if record.State == transition.From && !record.NotBefore.After(transition.Time) {
+			transitionRecord(record, transition)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockJobDAO) Claim(db *mongo.Database, lease *models.Lease, transition *models.Transition) (*models.Job, error) {
+	for _, record := range m.records {
+		if record.State == transition.From && !record.NotBefore.After(transition.Time) {
+			transitionRecord(record, transition)
+			record.Lease = lease
+			return record, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockJobDAO) ExtendLease(db *mongo.Database, name string, lease *models.Lease) (bool, error) {
+	for _, record := range m.records {
+		if record.Name == name && record.State == models.InProgress && record.Lease != nil && record.Lease.Worker == lease.Worker {
+			record.Lease = lease
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockJobDAO) Release(db *mongo.Database, name string, worker string, transition *models.Transition, attempt *models.Attempt) (*models.Job, error) {
+	for _, record := range m.records {
+		if record.Name == name && record.State == transition.From && record.Lease != nil && record.Lease.Worker == worker {
+			record.Lease = nil
+			record.Attempts = append(record.Attempts, attempt)
+			transitionRecord(record, transition)
+			return record, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockJobDAO) Reschedule(db *mongo.Database, name string, notBefore time.Time, transition *models.Transition) (bool, error) {
+	for _, record := range m.records {
+		if record.Name == name && record.State == transition.From {
+			record.NotBefore = notBefore
+			transitionRecord(record, transition)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockJobDAO) RescheduleByID(db *mongo.Database, id int64, notBefore time.Time, transition *models.Transition) (bool, error) {
+	for _, record := range m.records {
+		if record.ID == id && record.State == transition.From {
+			record.NotBefore = notBefore
+			transitionRecord(record, transition)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockJobDAO) QueryByState(db *mongo.Database, state models.State, offset, limit int) ([]*models.Job, error) {
+	var jobList []*models.Job
+	for _, record := range m.records {
+		if record.State == state {
+			jobList = append(jobList, record)
+		}
+	}
+	return jobList, nil
+}
+
+func (m *mockJobDAO) CountByState(db *mongo.Database, state models.State) (int64, error) {
+	jobList, err := m.QueryByState(db, state, 0, 0)
+	return int64(len(jobList)), err
+}
+
+func (m *mockJobDAO) ExpireLeases(db *mongo.Database, transition *models.Transition, attempt *models.Attempt) ([]*models.Job, error) {
+	var jobList []*models.Job
+	for _, record := range m.records {
+		if record.State == transition.From && record.Lease != nil && record.Lease.ExpiresAt.Before(transition.Time) {
+			expired := *attempt
+			expired.Worker = record.Lease.Worker
+			record.Lease = nil
+			record.Attempts = append(record.Attempts, &expired)
+			transitionRecord(record, transition)
+			jobList = append(jobList, record)
+		}
+	}
+	return jobList, nil
+}
+
+func (m *mockJobDAO) AppendDependency(db *mongo.Database, name string, dep *models.PublishedDependency, blockerList []string, transition *models.Transition, stateList []models.State) (*models.Job, error) {
+	for _, record := range m.records {
+		for _, state := range stateList {
+			if record.Name == name && record.State == state && !wasClaimed(record) {
+				if !listsDependency(record, dep) {
+					record.Dependencies = append(record.Dependencies, dep)
+				}
+				for _, blocker := range blockerList {
+					if !containsString(record.BlockedBy, blocker) {
+						record.BlockedBy = append(record.BlockedBy, blocker)
+					}
+				}
+				if len(blockerList) > 0 && record.State != models.Locked {
+					locked := *transition
+					locked.From = record.State
+					transitionRecord(record, &locked)
+				}
+				return record, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockJobDAO) SetPullRequest(db *mongo.Database, id int64, pr *models.PullRequest) error {
+	for _, record := range m.records {
+		if record.ID == id {
+			record.PullRequest = pr
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (m *mockJobDAO) DeleteByID(db *mongo.Database, id int64) error {
 	for i, record := range m.records {
 		if record.ID == id {
 			m.records = append(m.records[:i], m.records[i+1:]...)
@@ -184,3 +713,13 @@ func (m *mockJobDAO) Delete(db *mongo.Database, id int64) error {
 	}
 	return errors.New("not found")
 }
+
+func (m *mockJobDAO) Delete(db *mongo.Database, name string) error {
+	for i, record := range m.records {
+		if record.Name == name {
+			m.records = append(m.records[:i], m.records[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/errors"
+	"github.com/quantumew/listener/notify"
+)
+
+// LeaseExpired is the error recorded on the attempt of a job whose worker stopped sending heartbeats.
+const LeaseExpired = "lease expired"
+
+// Claim leases the oldest queued job that is due to a worker. The worker holds the job until it completes or fails it,
+// or until the lease expires without a heartbeat. Nil is returned when no job is waiting.
+func (s *JobService) Claim(rs app.RequestScope, worker string) (*models.Job, error) {
+	if err := validateWorker(worker); err != nil {
+		return nil, err
+	}
+
+	now := rs.Now()
+
+	return s.dao.Claim(
+		rs.DB(),
+		&models.Lease{Worker: worker, ExpiresAt: now.Add(app.Config.Worker.LeaseTTL)},
+		&models.Transition{From: models.Queued, To: models.InProgress, Actor: worker, Time: now},
+	)
+}
+
+// Heartbeat extends the lease a worker holds on a job.
+func (s *JobService) Heartbeat(rs app.RequestScope, name string, worker string) (*models.Job, error) {
+	if err := validateWorker(worker); err != nil {
+		return nil, err
+	}
+
+	lease := &models.Lease{Worker: worker, ExpiresAt: rs.Now().Add(app.Config.Worker.LeaseTTL)}
+
+	extended, err := s.dao.ExtendLease(rs.DB(), name, lease)
+	if err != nil {
+		return nil, err
+	}
+
+	if !extended {
+		return nil, leaseConflict(name, worker)
+	}
+
+	return s.dao.GetByState(rs.DB(), name, models.InProgress)
+}
+
+// Complete marks the job a worker holds as succeeded. The packages the worker published while updating the repository
+// are queued on the repositories depending on them, the same way a hook from the registry would. When pull requests
+// are enabled, the pull request bumping the dependencies of the job is then opened and recorded on the job, so a slow
+// code host does not hold the downstream jobs back.
+func (s *JobService) Complete(rs app.RequestScope, name string, worker string, publishedList []*models.PublishedDependency) (*models.Job, error) {
+	job, err := s.release(rs, name, worker, models.Succeeded, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// The job is done either way, failing the request would only make the worker report it twice.
+	if err := s.cascade(rs, publishedList); err != nil {
+		rs.Errorf("cascading %s: %v", name, err)
+	}
+
+	if s.pullRequests != nil {
+		if err := s.recordPullRequest(rs, job); err != nil {
+			rs.Errorf("recording the pull request of %s: %v", name, err)
+		}
+	}
+
+	return job, nil
+}
+
+// recordPullRequest opens the pull request of a completed job and records it on the job. A pull request that cannot
+// be opened does not fail the job, its error is recorded instead.
+func (s *JobService) recordPullRequest(rs app.RequestScope, job *models.Job) error {
+	pr, err := s.pullRequests.Open(rs, job)
+	if err != nil {
+		pr = &models.PullRequest{State: PullRequestFailed, Error: err.Error()}
+	}
+
+	if pr == nil {
+		return nil
+	}
+
+	job.PullRequest = pr
+	if err := s.dao.SetPullRequest(rs.DB(), job.ID, pr); err != nil {
+		return err
+	}
+
+	if pr.State != PullRequestFailed {
+		s.notify(rs, notify.PullRequestOpened, job)
+	}
+
+	return nil
+}
+
+// Fail marks the job a worker holds as failed, recording the error the worker ran into.
+// The job is retried later unless it is out of attempts.
+func (s *JobService) Fail(rs app.RequestScope, name string, worker string, message string) (*models.Job, error) {
+	job, err := s.release(rs, name, worker, models.Failed, message)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyFailure(rs.DB(), job, rs.Now())
+
+	return job, nil
+}
+
+// Sweep fails jobs whose lease expired and queues idle jobs whose debounce window closed so workers can claim them.
+// An expired lease counts as a failed attempt, so a job that keeps crashing its worker is retried with the same
+// backoff as any failed job and eventually dead-lettered, and its failure is notified like any other.
+func (s *JobService) Sweep(db *mongo.Database, now time.Time) error {
+	transition := &models.Transition{From: models.InProgress, To: models.Failed, Actor: SystemActor, Time: now}
+	expiredList, err := s.dao.ExpireLeases(db, transition, &models.Attempt{Error: LeaseExpired, FinishedAt: now})
+	if err != nil {
+		return err
+	}
+
+	for _, job := range expiredList {
+		if _, err := s.afterTransition(db, job.Name, models.Failed, now); err != nil {
+			return err
+		}
+
+		if s.notifications != nil {
+			failed, err := s.dao.GetByID(db, job.ID)
+			if err != nil {
+				return err
+			}
+
+			s.notifyFailure(db, failed, now)
+		}
+	}
+
+	transition = &models.Transition{From: models.Idle, To: models.Queued, Actor: SystemActor, Time: now}
+	_, err = s.dao.TransitionDue(db, transition)
+
+	return err
+}
+
+// release ends the lease a worker holds on a job and records the attempt.
+func (s *JobService) release(rs app.RequestScope, name string, worker string, to models.State, message string) (*models.Job, error) {
+	if err := validateWorker(worker); err != nil {
+		return nil, err
+	}
+
+	now := rs.Now()
+	transition := &models.Transition{From: models.InProgress, To: to, Actor: worker, Time: now}
+	attempt := &models.Attempt{Worker: worker, Error: message, FinishedAt: now}
+
+	job, err := s.dao.Release(rs.DB(), name, worker, transition, attempt)
+	if err != nil {
+		return nil, err
+	}
+
+	if job == nil {
+		return nil, leaseConflict(name, worker)
+	}
+
+	if _, err := s.afterTransition(rs.DB(), name, to, now); err != nil {
+		return nil, err
+	}
+
+	return s.dao.GetByID(rs.DB(), job.ID)
+}
+
+func validateWorker(worker string) error {
+	if err := validation.Validate(worker, validation.Required); err != nil {
+		return validation.Errors{"worker": err}
+	}
+
+	return nil
+}
+
+func leaseConflict(name string, worker string) error {
+	return errors.Conflict("job "+name, fmt.Sprintf("%s does not hold a lease on the job, it may have expired", worker))
+}
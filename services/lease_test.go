@@ -0,0 +1,246 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobService_Claim(t *testing.T) {
+	app.Config.Worker.LeaseTTL = time.Minute
+	dao := newMockJobDAO().(*mockJobDAO)
+	dao.records[1].State = models.Queued
+	dao.records[2].State = models.Queued
+	s := NewJobService(dao, newMockRepositoryDAO())
+	rs := new(MockRequestScope)
+
+	job, err := s.Claim(rs, "worker-1")
+	if assert.Nil(t, err) && assert.NotNil(t, job) {
+		assert.Equal(t, "bbb", job.Name)
+		assert.Equal(t, models.InProgress, job.State)
+		assert.Equal(t, "worker-1", job.Lease.Worker)
+		assert.Equal(t, rs.Now().Add(time.Minute), job.Lease.ExpiresAt)
+	}
+
+	job, err = s.Claim(rs, "worker-2")
+	if assert.Nil(t, err) && assert.NotNil(t, job) {
+		assert.Equal(t, "ccc", job.Name)
+	}
+
+	job, err = s.Claim(rs, "worker-3")
+	assert.Nil(t, err)
+	assert.Nil(t, job)
+
+	_, err = s.Claim(rs, "")
+	assert.NotNil(t, err)
+}
+
+func TestJobService_Heartbeat(t *testing.T) {
+	dao := newMockJobDAO().(*mockJobDAO)
+	dao.records[0].State = models.InProgress
+	dao.records[0].Lease = &models.Lease{Worker: "worker-1"}
+	s := NewJobService(dao, newMockRepositoryDAO())
+	rs := new(MockRequestScope)
+
+	job, err := s.Heartbeat(rs, "aaa", "worker-1")
+	if assert.Nil(t, err) {
+		assert.Equal(t, rs.Now().Add(app.Config.Worker.LeaseTTL), job.Lease.ExpiresAt)
+	}
+
+	_, err = s.Heartbeat(rs, "aaa", "worker-2")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusConflict, err.(*errors.APIError).Status)
+	}
+}
+
+func TestJobService_Complete(t *testing.T) {
+	dao := newMockJobDAO().(*mockJobDAO)
+	dao.records[0].State = models.InProgress
+	dao.records[0].Lease = &models.Lease{Worker: "worker-1"}
+	locked := createJob("aaa", "test", "1.3.0")
+	locked.State = models.Locked
+	locked.BlockedBy = []string{"aaa"}
+	dao.records = append(dao.records, locked)
+	s := NewJobService(dao, newMockRepositoryDAO())
+	rs := new(MockRequestScope)
+
+	_, err := s.Complete(rs, "aaa", "worker-2", nil)
+	assert.NotNil(t, err)
+
+	job, err := s.Complete(rs, "aaa", "worker-1", nil)
+	if assert.Nil(t, err) {
+		assert.Equal(t, models.Succeeded, job.State)
+		assert.Nil(t, job.Lease)
+		assert.Equal(t, models.Idle, locked.State)
+	}
+}
+
+func TestJobService_Complete_Cascade(t *testing.T) {
+	jobDAO := &mockJobDAO{}
+	repDAO := &mockRepositoryDAO{records: []*models.Repository{
+		createPublisher("core", "@org/core", "lib"),
+		createPublisher("ui", "@org/ui", "lib", "@org/core"),
+		createPublisher("left", "@org/left", "@org/right"),
+		createPublisher("right", "@org/right", "@org/left"),
+	}}
+	s := NewJobService(jobDAO, repDAO)
+	rs := new(MockRequestScope)
+
+	_, err := s.CreateJobsFromHook(rs, &models.NpmHook{Name: "lib", Version: "1.1.0"})
+	assert.Nil(t, err)
+
+	core, ui := jobDAO.records[0], jobDAO.records[1]
+	core.State = models.InProgress
+	core.Lease = &models.Lease{Worker: "worker-1"}
+
+	published := []*models.PublishedDependency{{Name: "@org/core", Version: "1.0.1"}}
+	_, err = s.Complete(rs, "core", "worker-1", published)
+	if assert.Nil(t, err) {
+		// the downstream job is released and picks up the version core published
+		assert.Equal(t, models.Idle, ui.State)
+		assert.Empty(t, ui.BlockedBy)
+		assert.Equal(t, 2, len(ui.Dependencies))
+	}
+
+	// publishes are not cascaded around a cycle
+	left := createJob("left", "@org/right", "1.0.1")
+	left.State = models.InProgress
+	left.Lease = &models.Lease{Worker: "worker-1"}
+	jobDAO.records = append(jobDAO.records, left)
+
+	_, err = s.Complete(rs, "left", "worker-1", []*models.PublishedDependency{{Name: "@org/left", Version: "1.0.1"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(jobDAO.records))
+}
+
+func TestJobService_Complete_Successor(t *testing.T) {
+	jobDAO := &mockJobDAO{}
+	repDAO := &mockRepositoryDAO{records: []*models.Repository{
+		createPublisher("core", "@org/core", "lib"),
+		createPublisher("ui", "@org/ui", "lib", "@org/core"),
+	}}
+	s := NewJobService(jobDAO, repDAO)
+	rs := new(MockRequestScope)
+
+	_, err := s.CreateJobsFromHook(rs, &models.NpmHook{Name: "lib", Version: "1.1.0"})
+	assert.Nil(t, err)
+
+	core, ui := jobDAO.records[0], jobDAO.records[1]
+	core.State = models.InProgress
+	core.Lease = &models.Lease{Worker: "worker-1"}
+
+	// the next version queues a successor behind the core job in progress
+	_, err = s.CreateJobsFromHook(rs, &models.NpmHook{Name: "lib", Version: "1.2.0"})
+	assert.Nil(t, err)
+	successor := jobDAO.records[2]
+	assert.Equal(t, []string{"core"}, successor.BlockedBy)
+
+	// the successor is released, the downstream job waits for it too
+	_, err = s.Complete(rs, "core", "worker-1", nil)
+	if assert.Nil(t, err) {
+		assert.Equal(t, models.Idle, successor.State)
+		assert.Equal(t, models.Locked, ui.State)
+	}
+
+	successor.State = models.InProgress
+	successor.Lease = &models.Lease{Worker: "worker-2"}
+	_, err = s.Complete(rs, "core", "worker-2", nil)
+	if assert.Nil(t, err) {
+		assert.Equal(t, models.Idle, ui.State)
+	}
+}
+
+func TestJobService_Fail(t *testing.T) {
+	dao := newMockJobDAO().(*mockJobDAO)
+	dao.records[0].State = models.InProgress
+	dao.records[0].Lease = &models.Lease{Worker: "worker-1"}
+	s := NewJobService(dao, newMockRepositoryDAO())
+
+	job, err := s.Fail(new(MockRequestScope), "aaa", "worker-1", "npm install failed")
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(job.Attempts)) {
+		assert.Equal(t, "npm install failed", job.Attempts[0].Error)
+	}
+}
+
+func TestJobService_Sweep(t *testing.T) {
+	defer setRetryConfig(2, 0)()
+
+	now := time.Date(2018, 9, 1, 0, 0, 0, 0, time.UTC)
+	dao := newMockJobDAO().(*mockJobDAO)
+	dao.records[0].State = models.InProgress
+	dao.records[0].Lease = &models.Lease{Worker: "worker-1", ExpiresAt: now.Add(-time.Second)}
+	dao.records[1].State = models.InProgress
+	dao.records[1].Lease = &models.Lease{Worker: "worker-2", ExpiresAt: now.Add(time.Second)}
+	s := NewJobService(dao, newMockRepositoryDAO())
+
+	// an expired lease is a failed attempt, retried after the backoff
+	assert.Nil(t, s.Sweep(nil, now))
+	assert.Equal(t, models.Queued, dao.records[0].State)
+	assert.Nil(t, dao.records[0].Lease)
+	assert.Equal(t, now.Add(time.Second), dao.records[0].NotBefore)
+	if assert.Equal(t, 1, len(dao.records[0].Attempts)) {
+		assert.Equal(t, "worker-1", dao.records[0].Attempts[0].Worker)
+		assert.Equal(t, LeaseExpired, dao.records[0].Attempts[0].Error)
+	}
+	assert.Equal(t, models.InProgress, dao.records[1].State)
+	assert.Equal(t, models.Queued, dao.records[2].State)
+
+	// a job that keeps losing its worker runs out of attempts
+	dao.records[0].State = models.InProgress
+	dao.records[0].Lease = &models.Lease{Worker: "worker-3", ExpiresAt: now}
+	assert.Nil(t, s.Sweep(nil, now.Add(time.Minute)))
+	assert.Equal(t, models.DeadLetter, dao.records[0].State)
+}
+
+func TestJobService_Sweep_Successor(t *testing.T) {
+	defer setRetryConfig(2, 0)()
+	app.Config.Worker.LeaseTTL = time.Minute
+	dao := &mockJobDAO{}
+	repDAO := &mockRepositoryDAO{records: []*models.Repository{createRepository("aaa", "lib", "^1.0.0", "1.0.0")}}
+	s := NewJobService(dao, repDAO)
+	rs := new(MockRequestScope)
+
+	_, err := s.CreateJobsFromHook(rs, &models.NpmHook{Name: "lib", Version: "1.1.0"})
+	assert.Nil(t, err)
+	assert.Nil(t, s.Sweep(nil, rs.Now()))
+	claimed, err := s.Claim(rs, "worker-1")
+	assert.Nil(t, err)
+
+	// a hook arriving while the job is in progress queues a successor behind it
+	_, err = s.CreateJobsFromHook(rs, &models.NpmHook{Name: "lib", Version: "1.2.0"})
+	assert.Nil(t, err)
+	if assert.Equal(t, 2, len(dao.records)) {
+		assert.Equal(t, models.Locked, dao.records[1].State)
+	}
+
+	// the expired job is requeued and the successor stays locked behind it, taking the new dependencies
+	assert.Nil(t, s.Sweep(nil, rs.Now().Add(2*time.Minute)))
+	assert.Equal(t, models.Queued, claimed.State)
+	assert.Equal(t, models.Locked, dao.records[1].State)
+
+	_, err = s.CreateJobsFromHook(rs, &models.NpmHook{Name: "lib", Version: "1.3.0"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(dao.records))
+	assert.Equal(t, 1, len(claimed.Dependencies))
+	assert.Equal(t, 2, len(dao.records[1].Dependencies))
+
+	// only the requeued job can be claimed, the successor is released once it completes
+	claimed.NotBefore = time.Time{}
+	job, err := s.Claim(rs, "worker-2")
+	if assert.Nil(t, err) && assert.NotNil(t, job) {
+		assert.Equal(t, claimed.ID, job.ID)
+	}
+
+	job, err = s.Claim(rs, "worker-3")
+	assert.Nil(t, err)
+	assert.Nil(t, job)
+
+	_, err = s.Complete(rs, "aaa", "worker-2", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, models.Idle, dao.records[1].State)
+}
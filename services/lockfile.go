@@ -0,0 +1,132 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// lockfile maps the packages a lockfile resolves to their installed version. Entries are keyed by package name, by
+// "<workspace>/node_modules/<name>" for packages a workspace installs apart from the hoisted ones, and by
+// "name@range" for lockfiles like yarn.lock that resolve every declared range.
+type lockfile map[string]string
+
+// installed returns the version a lockfile resolved a range a workspace declares to, or an empty string.
+func (l lockfile) installed(workspace string, name string, rangeStr string) string {
+	if version, ok := l[workspace+"/node_modules/"+name]; ok && workspace != "" {
+		return version
+	}
+
+	if version, ok := l[name+"@"+rangeStr]; ok {
+		return version
+	}
+
+	return l[name]
+}
+
+// parsePackageLock reads the top level packages of a package-lock.json. Lockfiles of npm 7 and above list them under
+// "packages" as "node_modules/<name>", or "<workspace>/node_modules/<name>" when a workspace cannot use the hoisted
+// version, older ones under "dependencies".
+func parsePackageLock(data []byte) (lockfile, error) {
+	var lock struct {
+		Packages map[string]struct {
+			Version string `json:"version"`
+		} `json:"packages"`
+		Dependencies map[string]struct {
+			Version string `json:"version"`
+		} `json:"dependencies"`
+	}
+
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, err
+	}
+
+	l := lockfile{}
+
+	if len(lock.Packages) > 0 {
+		for key, pkg := range lock.Packages {
+			if strings.HasPrefix(key, "node_modules/") {
+				if name := strings.TrimPrefix(key, "node_modules/"); !strings.Contains(name, "/node_modules/") {
+					l[name] = pkg.Version
+				}
+			} else if strings.Count(key, "/node_modules/") == 1 {
+				l[key] = pkg.Version
+			}
+		}
+
+		return l, nil
+	}
+
+	for name, dep := range lock.Dependencies {
+		l[name] = dep.Version
+	}
+
+	return l, nil
+}
+
+// parseYarnLock reads a yarn.lock, either the v1 format or the YAML one of Yarn 2 and above. Each entry lists the
+// ranges it resolves, e.g. `"lodash@^4.17.0", lodash@^4.17.15:` or `"lodash@npm:^4.17.15":`, followed by the
+// indented version. A package resolved to a single version is also keyed by its name.
+func parseYarnLock(data []byte) lockfile {
+	l := lockfile{}
+	versions := map[string]string{}
+	var specList []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if !strings.HasPrefix(line, " ") && strings.HasSuffix(line, ":") {
+			specList = strings.Split(strings.TrimSuffix(line, ":"), ",")
+			continue
+		}
+
+		if !strings.HasPrefix(trimmed, "version") || !strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "   ") {
+			continue
+		}
+
+		version := strings.Trim(strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(trimmed, "version"), ":")), `"`)
+
+		for _, spec := range specList {
+			name, rangeStr := splitYarnSpec(strings.Trim(strings.TrimSpace(spec), `"`))
+			if name == "" {
+				continue
+			}
+
+			l[name+"@"+rangeStr] = version
+
+			if previous, ok := versions[name]; ok && previous != version {
+				versions[name] = ""
+			} else {
+				versions[name] = version
+			}
+		}
+
+		specList = nil
+	}
+
+	for name, version := range versions {
+		if version != "" {
+			l[name] = version
+		}
+	}
+
+	return l
+}
+
+// splitYarnSpec splits a yarn.lock spec such as "@babel/core@^7.0.0" or "lodash@npm:^4.17.15" into the package name
+// and the range it declares.
+func splitYarnSpec(spec string) (string, string) {
+	at := strings.LastIndex(spec, "@")
+	if at <= 0 {
+		return "", ""
+	}
+
+	return spec[:at], strings.TrimPrefix(spec[at+1:], "npm:")
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/app"
+)
+
+// Dependency types of the dependencies registered from a manifest, after the package.json field declaring them.
+const (
+	DependencyProd     = "prod"
+	DependencyDev      = "dev"
+	DependencyPeer     = "peer"
+	DependencyOptional = "optional"
+)
+
+// Manifest is what a repository declares about its npm dependencies: its package.json and, optionally, the
+// package-lock.json or yarn.lock of the versions it has installed. Monorepos also send the package.json of each of
+// their workspaces, and pnpm ones the pnpm-workspace.yaml listing them.
+type Manifest struct {
+	PackageJSON   []byte
+	PackageLock   []byte
+	YarnLock      []byte
+	PnpmWorkspace []byte
+	// Workspaces are the package.json files of the workspaces by workspace path, e.g. "packages/ui".
+	Workspaces map[string][]byte
+	// Config is the config of the repository, used when the manifest registers a new one.
+	Config models.Config
+}
+
+// ParsedManifest is what a manifest declares: the packages and workspaces of the repository, and the dependencies of
+// every workspace along with the ones that were left out.
+type ParsedManifest struct {
+	Packages     []string
+	Workspaces   []models.Workspace
+	Dependencies []models.Dependency
+	Skipped      []SkippedDependency
+}
+
+// ManifestResult is the repository a manifest registered and the dependencies it left out.
+type ManifestResult struct {
+	Repository *models.Repository  `json:"repository"`
+	Skipped    []SkippedDependency `json:"skipped"`
+}
+
+// SkippedDependency describes a dependency of a manifest that was not registered and why.
+type SkippedDependency struct {
+	Name      string `json:"name"`
+	Workspace string `json:"workspace,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+// packageJSON is the part of a package.json the listener reads.
+type packageJSON struct {
+	Name                 string            `json:"name"`
+	Workspaces           json.RawMessage   `json:"workspaces"`
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+}
+
+// RegisterManifest creates or updates a repository from its manifest. The npm dependencies and the workspaces of an
+// existing repository are replaced by the ones of the manifest, keeping the update policies of the dependencies, while
+// its other settings and dependencies are left untouched. The packages the manifest names are added to the packages of
+// the repository.
+func (s *RepositoryService) RegisterManifest(rs app.RequestScope, name string, manifest *Manifest) (*ManifestResult, error) {
+	parsed, err := ParseManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	db := rs.DB()
+
+	existingList, err := s.dao.QueryByName(db, []string{name})
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &models.Repository{Name: name, Config: manifest.Config}
+	if len(existingList) > 0 {
+		rep = existingList[0]
+	}
+
+	rep.Dependencies = mergeManifestDependencies(rep, parsed.Dependencies)
+	rep.Workspaces = parsed.Workspaces
+
+	for _, pkg := range parsed.Packages {
+		if !containsString(rep.Packages, pkg) {
+			rep.Packages = append(rep.Packages, pkg)
+		}
+	}
+
+	if err := validateRepository(rep); err != nil {
+		return nil, err
+	}
+
+	if len(existingList) > 0 {
+		err = s.dao.Update(db, name, rep)
+	} else {
+		err = s.dao.Create(db, rep)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	rep, err = s.dao.Get(db, name)
+	if err != nil {
+		return nil, err
+	}
+
+	skippedList := parsed.Skipped
+	if skippedList == nil {
+		skippedList = []SkippedDependency{}
+	}
+
+	return &ManifestResult{rep, skippedList}, nil
+}
+
+// ParseManifest returns what a manifest declares. Dependencies are sorted by workspace, the root first, then by name.
+// A package declared in several fields of a package.json is registered once, as a prod, optional, dev or peer
+// dependency in that order. Dependencies on packages of the repository itself, or whose range is not a registry range
+// such as git URLs or local paths, are left out. Installed versions are taken from the lockfile when there is one.
+// Workspaces must be matched by the workspace patterns of the root package.json or pnpm-workspace.yaml.
+func ParseManifest(manifest *Manifest) (*ParsedManifest, error) {
+	if err := validation.Validate(manifest.PackageJSON, validation.Required); err != nil {
+		return nil, validation.Errors{"package.json": err}
+	}
+
+	var root packageJSON
+	if err := json.Unmarshal(manifest.PackageJSON, &root); err != nil {
+		return nil, validation.Errors{"package.json": err}
+	}
+
+	globs, err := parseWorkspacesField(root.Workspaces)
+	if err != nil {
+		return nil, validation.Errors{"package.json": err}
+	}
+
+	if len(manifest.PnpmWorkspace) > 0 {
+		globs = append(globs, parsePnpmWorkspace(manifest.PnpmWorkspace)...)
+	}
+
+	lock := lockfile{}
+
+	if len(manifest.PackageLock) > 0 {
+		packageLock, err := parsePackageLock(manifest.PackageLock)
+		if err != nil {
+			return nil, validation.Errors{"package-lock.json": err}
+		}
+
+		lock = packageLock
+	} else if len(manifest.YarnLock) > 0 {
+		lock = parseYarnLock(manifest.YarnLock)
+	}
+
+	parsed := &ParsedManifest{}
+	pkgMap := map[string]packageJSON{"": root}
+	workspaceList := []string{""}
+
+	if root.Name != "" {
+		parsed.Packages = append(parsed.Packages, root.Name)
+	}
+
+	var workspacePathList []string
+	for workspacePath := range manifest.Workspaces {
+		workspacePathList = append(workspacePathList, workspacePath)
+	}
+	sort.Strings(workspacePathList)
+
+	for _, workspacePath := range workspacePathList {
+		workspace, err := cleanWorkspacePath(workspacePath)
+		if err == nil && (workspace == "" || !globs.Match(workspace)) {
+			err = fmt.Errorf("%q is not a workspace of the root package.json", workspacePath)
+		}
+
+		var pkg packageJSON
+		if err == nil {
+			err = json.Unmarshal(manifest.Workspaces[workspacePath], &pkg)
+		}
+
+		if err != nil {
+			return nil, validation.Errors{"workspaces[" + workspacePath + "]": err}
+		}
+
+		pkgMap[workspace] = pkg
+		workspaceList = append(workspaceList, workspace)
+		parsed.Workspaces = append(parsed.Workspaces, models.Workspace{Path: workspace, Package: pkg.Name})
+
+		if pkg.Name != "" && !containsString(parsed.Packages, pkg.Name) {
+			parsed.Packages = append(parsed.Packages, pkg.Name)
+		}
+	}
+
+	for _, workspace := range workspaceList {
+		depList, skippedList := parsePackageDependencies(pkgMap[workspace], workspace, parsed.Packages, lock)
+		parsed.Dependencies = append(parsed.Dependencies, depList...)
+		parsed.Skipped = append(parsed.Skipped, skippedList...)
+	}
+
+	return parsed, nil
+}
+
+// parsePackageDependencies returns the dependencies the package.json of a workspace declares, sorted by name, and the
+// ones it leaves out.
+func parsePackageDependencies(pkg packageJSON, workspace string, localList []string, lock lockfile) ([]models.Dependency, []SkippedDependency) {
+	npm, _ := LookupEcosystem(EcosystemNpm)
+	seen := map[string]bool{}
+
+	var (
+		depList     []models.Dependency
+		skippedList []SkippedDependency
+	)
+
+	for _, declared := range []struct {
+		depType string
+		depMap  map[string]string
+	}{
+		{DependencyProd, pkg.Dependencies},
+		{DependencyOptional, pkg.OptionalDependencies},
+		{DependencyDev, pkg.DevDependencies},
+		{DependencyPeer, pkg.PeerDependencies},
+	} {
+		for _, name := range sortedKeys(declared.depMap) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			rangeStr := declared.depMap[name]
+
+			if containsString(localList, name) {
+				skippedList = append(skippedList, SkippedDependency{name, workspace, "package of the repository"})
+				continue
+			}
+
+			if _, err := npm.ParseRange(rangeStr); err != nil {
+				skippedList = append(skippedList, SkippedDependency{name, workspace, fmt.Sprintf("%s is not a registry range", rangeStr)})
+				continue
+			}
+
+			depList = append(depList, models.Dependency{
+				Name:      name,
+				Semver:    rangeStr,
+				Installed: lock.installed(workspace, name, rangeStr),
+				Type:      declared.depType,
+				Workspace: workspace,
+			})
+		}
+	}
+
+	sort.SliceStable(depList, func(i, j int) bool {
+		return depList[i].Name < depList[j].Name
+	})
+
+	return depList, skippedList
+}
+
+// mergeManifestDependencies returns the dependencies of a repository with its npm dependencies replaced by the ones of
+// a manifest. Update policies of the dependencies that are still declared by the same workspace are kept.
+func mergeManifestDependencies(rep *models.Repository, depList []models.Dependency) []models.Dependency {
+	policies := map[string]*models.UpdatePolicy{}
+	var mergedList []models.Dependency
+
+	for _, dep := range rep.Dependencies {
+		if DependencyEcosystem(rep, dep) == EcosystemNpm {
+			policies[dep.Workspace+"\x00"+dep.Name] = dep.Policy
+		} else {
+			mergedList = append(mergedList, dep)
+		}
+	}
+
+	for _, dep := range depList {
+		dep.Policy = policies[dep.Workspace+"\x00"+dep.Name]
+
+		if RepositoryEcosystem(rep) != EcosystemNpm {
+			dep.Ecosystem = EcosystemNpm
+		}
+
+		mergedList = append(mergedList, dep)
+	}
+
+	return mergedList
+}
+
+func sortedKeys(m map[string]string) []string {
+	keyList := make([]string, 0, len(m))
+
+	for key := range m {
+		keyList = append(keyList, key)
+	}
+
+	sort.Strings(keyList)
+
+	return keyList
+}
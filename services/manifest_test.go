@@ -0,0 +1,116 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func expectedManifestDependencies(installed bool) []models.Dependency {
+	depList := []models.Dependency{
+		{Name: "@octo-org/widgets", Semver: "^1.2.0", Installed: "1.3.1", Type: DependencyProd},
+		{Name: "fsevents", Semver: "^1.2.9", Installed: "1.2.9", Type: DependencyOptional},
+		{Name: "jest", Semver: "~24.8.0", Installed: "24.8.0", Type: DependencyDev},
+		{Name: "lodash", Semver: "^4.17.0", Installed: "4.17.15", Type: DependencyProd},
+		{Name: "react", Semver: "^16.8.0", Installed: "16.8.6", Type: DependencyDev},
+	}
+
+	if !installed {
+		for i := range depList {
+			depList[i].Installed = ""
+		}
+	}
+
+	return depList
+}
+
+func TestParseManifest(t *testing.T) {
+	packageJSON := readFixture(t, "manifest/package.json")
+
+	tests := []struct {
+		tag      string
+		manifest *Manifest
+	}{
+		{"package-lock.json", &Manifest{PackageJSON: packageJSON, PackageLock: readFixture(t, "manifest/package-lock.json")}},
+		{"package-lock.json v2", &Manifest{PackageJSON: packageJSON, PackageLock: readFixture(t, "manifest/package-lock-v2.json")}},
+		{"yarn.lock", &Manifest{PackageJSON: packageJSON, YarnLock: readFixture(t, "manifest/yarn.lock")}},
+		{"yarn.lock berry", &Manifest{PackageJSON: packageJSON, YarnLock: readFixture(t, "manifest/yarn-berry.lock")}},
+	}
+
+	for _, test := range tests {
+		parsed, err := ParseManifest(test.manifest)
+		if assert.Nil(t, err, test.tag) {
+			assert.Equal(t, []string{"@octo-org/app"}, parsed.Packages, test.tag)
+			assert.Empty(t, parsed.Workspaces, test.tag)
+			assert.Equal(t, expectedManifestDependencies(true), parsed.Dependencies, test.tag)
+			if assert.Equal(t, 1, len(parsed.Skipped), test.tag) {
+				assert.Equal(t, "left-pad", parsed.Skipped[0].Name, test.tag)
+			}
+		}
+	}
+
+	parsed, err := ParseManifest(&Manifest{PackageJSON: packageJSON})
+	if assert.Nil(t, err) {
+		assert.Equal(t, expectedManifestDependencies(false), parsed.Dependencies)
+	}
+
+	_, err = ParseManifest(&Manifest{})
+	assert.NotNil(t, err)
+
+	_, err = ParseManifest(&Manifest{PackageJSON: []byte("{")})
+	assert.NotNil(t, err)
+
+	_, err = ParseManifest(&Manifest{PackageJSON: packageJSON, PackageLock: []byte("{")})
+	assert.NotNil(t, err)
+
+	// workspaces the root package.json does not declare
+	_, err = ParseManifest(&Manifest{PackageJSON: packageJSON, Workspaces: map[string][]byte{"packages/ui": []byte("{}")}})
+	assert.NotNil(t, err)
+}
+
+func TestRepositoryService_RegisterManifest(t *testing.T) {
+	manifest := &Manifest{
+		PackageJSON: readFixture(t, "manifest/package.json"),
+		YarnLock:    readFixture(t, "manifest/yarn.lock"),
+		Config:      models.Config{Branch: "main", Remote: "git@github.com:octo-org/app.git"},
+	}
+
+	// new repository
+	s := NewRepositoryService(newMockRepositoryDAO())
+	result, err := s.RegisterManifest(new(MockRequestScope), "app", manifest)
+	if assert.Nil(t, err) {
+		assert.Equal(t, int64(4), result.Repository.ID)
+		assert.Equal(t, manifest.Config, result.Repository.Config)
+		assert.Equal(t, []string{"@octo-org/app"}, result.Repository.Packages)
+		assert.Equal(t, expectedManifestDependencies(true), result.Repository.Dependencies)
+		assert.Equal(t, "left-pad", result.Skipped[0].Name)
+	}
+
+	// existing repository, keeping its settings and the dependencies of other ecosystems
+	policy := &models.UpdatePolicy{Level: PolicyMinor}
+	existing := createRepository("app", "lodash", "^3.0.0", "3.10.1")
+	existing.Config.Debounce = "1m"
+	existing.Packages = []string{"@octo-org/app"}
+	existing.Dependencies[0].Policy = policy
+	existing.Dependencies = append(existing.Dependencies,
+		models.Dependency{Ecosystem: EcosystemPyPI, Name: "requests", Semver: "~=2.19"},
+		models.Dependency{Name: "moment", Semver: "^2.0.0"},
+	)
+	s = NewRepositoryService(&mockRepositoryDAO{records: []*models.Repository{existing}})
+
+	result, err = s.RegisterManifest(new(MockRequestScope), "app", manifest)
+	if assert.Nil(t, err) {
+		rep := result.Repository
+		assert.Equal(t, "1m", rep.Config.Debounce)
+		assert.Equal(t, "stuff", rep.Config.Remote)
+		assert.Equal(t, []string{"@octo-org/app"}, rep.Packages)
+
+		expectedList := expectedManifestDependencies(true)
+		expectedList[3].Policy = policy
+		assert.Equal(t, append([]models.Dependency{{Ecosystem: EcosystemPyPI, Name: "requests", Semver: "~=2.19"}}, expectedList...), rep.Dependencies)
+	}
+
+	_, err = s.RegisterManifest(new(MockRequestScope), "app", &Manifest{})
+	assert.NotNil(t, err)
+}
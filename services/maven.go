@@ -0,0 +1,75 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/quantumew/data-access/models"
+)
+
+func init() {
+	RegisterEcosystem(mavenEcosystem{})
+}
+
+// EcosystemMaven is the tag of the Maven ecosystem.
+const EcosystemMaven = "maven"
+
+// mavenCoordinatesRegexp matches the "groupId:artifactId" coordinates Maven packages are named by.
+var mavenCoordinatesRegexp = regexp.MustCompile(`^([A-Za-z0-9_.-]+):([A-Za-z0-9_.-]+)$`)
+
+// mavenEcosystem is the Maven ecosystem. Packages are named by their "groupId:artifactId" coordinates, versions are
+// ordered like Maven's ComparableVersion and ranges are Maven version ranges.
+type mavenEcosystem struct{}
+
+func (mavenEcosystem) Name() string {
+	return EcosystemMaven
+}
+
+// ParseHook reads a generic hook delivery whose names are Maven coordinates.
+func (e mavenEcosystem) ParseHook(payload []byte) ([]*models.PublishedDependency, error) {
+	pubList, err := ParseGenericHook(EcosystemMaven, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, pub := range pubList {
+		if err := e.CheckName(pub.Name); err != nil {
+			return nil, validation.Errors{fmt.Sprintf("payload[%d].name", i): err}
+		}
+	}
+
+	return pubList, nil
+}
+
+func (mavenEcosystem) ParseVersion(version string) (Version, error) {
+	v, err := ParseMavenVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func (mavenEcosystem) ParseRange(rangeStr string) (VersionRange, error) {
+	r, err := ParseMavenRange(rangeStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// CheckName returns an error unless the name is made of "groupId:artifactId" coordinates.
+func (mavenEcosystem) CheckName(name string) error {
+	if !mavenCoordinatesRegexp.MatchString(name) {
+		return fmt.Errorf("%q is not a groupId:artifactId coordinate", name)
+	}
+
+	return nil
+}
+
+// MavenCoordinates returns the name of the Maven package with the given groupId and artifactId.
+func MavenCoordinates(groupID string, artifactID string) string {
+	return groupID + ":" + artifactID
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMavenVersion_Compare(t *testing.T) {
+	// in ascending order, from the ComparableVersion test suite
+	tests := [][]string{
+		{
+			"1-alpha2snapshot", "1-alpha2", "1-alpha-123", "1-beta-2", "1-beta123", "1-m2", "1-m11", "1-rc", "1-cr2",
+			"1-rc123", "1-SNAPSHOT", "1", "1-sp", "1-sp2", "1-sp123", "1-abc", "1-def", "1-pom-1", "1-1-snapshot",
+			"1-1", "1-2", "1-123",
+		},
+		{
+			"2.0", "2-1", "2.0.a", "2.0.0.a", "2.0.2", "2.0.123", "2.1.0", "2.1-a", "2.1b", "2.1-c", "2.1-1",
+			"2.1.0.1", "2.2", "2.123", "11.a2", "11.a11", "11.b2", "11.b11", "11.m2", "11.m11", "11", "11.a", "11b",
+			"11c", "11m",
+		},
+		{"1.0-alpha", "1.0", "1.0-sp"},
+	}
+
+	for _, versionList := range tests {
+		for i := 1; i < len(versionList); i++ {
+			lower, _ := ParseMavenVersion(versionList[i-1])
+			higher, _ := ParseMavenVersion(versionList[i])
+			assert.True(t, lower.Compare(higher) < 0, "%s < %s", lower, higher)
+			assert.True(t, higher.Compare(lower) > 0, "%s > %s", higher, lower)
+		}
+	}
+
+	equalList := [][]string{
+		{"1", "1.0", "1.0.0", "1-0", "1.0-0", "1ga", "1.ga", "1-ga", "1-final", "1-release", "1.0.0-GA"},
+		{"1a1", "1-a1", "1alpha1", "1-alpha-1", "1-ALPHA1"},
+		{"1cr", "1rc", "1-CR", "1-rc"},
+		{"1.010", "1.10"},
+	}
+
+	for _, versionList := range equalList {
+		first, _ := ParseMavenVersion(versionList[0])
+		for _, version := range versionList[1:] {
+			v, _ := ParseMavenVersion(version)
+			assert.Equal(t, 0, first.Compare(v), "%s == %s", first, v)
+		}
+	}
+
+	v, _ := ParseMavenVersion("1.2-beta-1")
+	assert.Equal(t, []uint64{1, 2}, v.Release())
+	assert.Equal(t, "1.2-beta-1", v.String())
+
+	_, err := ParseMavenVersion(" ")
+	assert.NotNil(t, err)
+}
+
+func TestMavenRange_Contains(t *testing.T) {
+	tests := []struct {
+		rangeStr  string
+		version   string
+		contained bool
+	}{
+		{"[1.0,2.0)", "1.0", true},
+		{"[1.0,2.0)", "1.5-beta", true},
+		{"[1.0,2.0)", "2.0", false},
+		{"[1.0,2.0)", "2.0-alpha", true},
+		{"[1.0,2.0)", "0.9", false},
+		{"(1.0,2.0]", "1.0", false},
+		{"(1.0,2.0]", "2.0", true},
+		{"(,1.5]", "1.5", true},
+		{"(,1.5]", "1.0", true},
+		{"(,1.5]", "1.5-sp", false},
+		{"[1.5,)", "1.5", true},
+		{"[1.5,)", "99", true},
+		{"[1.5,)", "1.5-rc", false},
+		{"[1.2]", "1.2", true},
+		{"[1.2]", "1.2.0", true},
+		{"[1.2]", "1.3", false},
+		{"(,1.0],[1.2,)", "1.1", false},
+		{"(,1.0],[1.2,)", "1.0", true},
+		{"(,1.0],[1.2,)", "1.2", true},
+		{"(,1.1),(1.1,)", "1.1", false},
+		{"(,1.1),(1.1,)", "1.2", true},
+		{"1.0", "1.0", false},
+		{"1.0", "1.1", true},
+		{"1.0", "0.9", false},
+		{"", "1.0", true},
+	}
+
+	for _, test := range tests {
+		r, err := ParseMavenRange(test.rangeStr)
+		if !assert.Nil(t, err, test.rangeStr) {
+			continue
+		}
+
+		version, _ := ParseMavenVersion(test.version)
+		assert.Equal(t, test.contained, r.Contains(version), "%s in %q", test.version, test.rangeStr)
+	}
+
+	for _, rangeStr := range []string{"[1.0", "(1.0)", "[1.0)", "[2.0,1.0]", "(1.0,1.0)", "[1.0,1.5],1.7", "[1.0,2.0],[1.5,3.0]", "[1.0,2.0,3.0]"} {
+		_, err := ParseMavenRange(rangeStr)
+		assert.NotNil(t, err, rangeStr)
+	}
+}
+
+func TestMavenEcosystem_ParseHook(t *testing.T) {
+	ecosystem, _ := LookupEcosystem(EcosystemMaven)
+
+	pubList, err := ecosystem.ParseHook([]byte(`{"name":"com.example:widgets","version":"1.4.0"}`))
+	if assert.Nil(t, err) {
+		assert.Equal(t, []*models.PublishedDependency{{Ecosystem: EcosystemMaven, Name: "com.example:widgets", Version: "1.4.0"}}, pubList)
+	}
+
+	_, err = ecosystem.ParseHook([]byte(`{"name":"widgets","version":"1.4.0"}`))
+	assert.NotNil(t, err)
+}
+
+func TestValidateRepository_Maven(t *testing.T) {
+	rep := createRepository("aaa", "com.example:widgets", "[1.0,2.0)", "1.2")
+	rep.Ecosystem = EcosystemMaven
+	assert.Nil(t, validateRepository(rep))
+
+	rep.Packages = []string{"widgets"}
+	assert.NotNil(t, validateRepository(rep))
+
+	rep = createRepository("aaa", "widgets", "[1.0,2.0)", "1.2")
+	rep.Ecosystem = EcosystemMaven
+	assert.NotNil(t, validateRepository(rep))
+}
@@ -0,0 +1,448 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MavenVersion is a Maven artifact version, ordered like Maven's ComparableVersion: "1.0-alpha" < "1.0" < "1.0-sp".
+type MavenVersion struct {
+	source string
+	items  mavenList
+}
+
+// MavenRange is a parsed Maven version range such as "[1.0,2.0)" or "(,1.0],[1.2,)". A range without brackets is a
+// soft requirement on a version, e.g. "1.0".
+type MavenRange struct {
+	source       string
+	soft         *MavenVersion
+	restrictions []mavenRestriction
+}
+
+// mavenRestriction is a single bracketed set of a range. Missing bounds are nil.
+type mavenRestriction struct {
+	lower, upper                   *MavenVersion
+	lowerInclusive, upperInclusive bool
+}
+
+// mavenItem is an item of a parsed version: a number, a qualifier or a sub list started by a "-". A nil item stands
+// for a missing one, to compare versions of different lengths.
+type mavenItem interface {
+	compare(other mavenItem) int
+}
+
+type (
+	// mavenNumber is a number without its leading zeros, zero being empty.
+	mavenNumber string
+	// mavenQualifier is a qualifier such as "beta" or "sp", with its aliases resolved.
+	mavenQualifier string
+	// mavenList is a list of items, the version itself or a sub list.
+	mavenList []mavenItem
+)
+
+var (
+	// mavenQualifiers are the well known qualifiers in ascending order, the empty qualifier being the release.
+	mavenQualifiers = []string{"alpha", "beta", "milestone", "rc", "snapshot", "", "sp"}
+	// mavenQualifierAliases are the alternative spellings of well known qualifiers.
+	mavenQualifierAliases = map[string]string{"ga": "", "final": "", "release": "", "cr": "rc"}
+	// mavenReleaseQualifier is how the release qualifier compares.
+	mavenReleaseQualifier = comparableQualifier("")
+)
+
+// ParseMavenVersion parses a Maven version. Any string is a version, but only the empty one is rejected.
+func ParseMavenVersion(version string) (*MavenVersion, error) {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return nil, fmt.Errorf("Maven version is empty")
+	}
+
+	return &MavenVersion{source: version, items: parseMavenItems(strings.ToLower(version))}, nil
+}
+
+// parseMavenItems splits a version into items the way ComparableVersion does. "." separates items, while "-" and
+// transitions between digits and letters start a sub list. Trailing null items, zeros and release qualifiers, are
+// dropped from every list so "1.0.0" equals "1" and "1-ga".
+func parseMavenItems(version string) mavenList {
+	root := &mavenList{}
+	list := root
+	stack := []*mavenList{root}
+	isDigit := false
+	start := 0
+
+	pushList := func() {
+		sub := &mavenList{}
+		*list = append(*list, sub)
+		list = sub
+		stack = append(stack, sub)
+	}
+
+	for i := 0; i < len(version); i++ {
+		c := version[i]
+
+		switch {
+		case c == '.':
+			*list = append(*list, parseMavenItem(isDigit, version[start:i]))
+			start = i + 1
+		case c == '-':
+			*list = append(*list, parseMavenItem(isDigit, version[start:i]))
+			start = i + 1
+			pushList()
+		case c >= '0' && c <= '9':
+			if !isDigit && i > start {
+				// "alpha1" is a qualifier followed by a number, which turns "a1" into "alpha-1".
+				*list = append(*list, newMavenQualifier(version[start:i], true))
+				start = i
+				pushList()
+			}
+			isDigit = true
+		default:
+			if isDigit && i > start {
+				*list = append(*list, parseMavenItem(true, version[start:i]))
+				start = i
+				pushList()
+			}
+			isDigit = false
+		}
+	}
+
+	if len(version) > start {
+		*list = append(*list, parseMavenItem(isDigit, version[start:]))
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		stack[i].normalize()
+	}
+
+	return root.resolve()
+}
+
+func parseMavenItem(isDigit bool, item string) mavenItem {
+	if item == "" {
+		return mavenNumber("")
+	}
+
+	if isDigit {
+		return mavenNumber(strings.TrimLeft(item, "0"))
+	}
+
+	return newMavenQualifier(item, false)
+}
+
+func newMavenQualifier(qualifier string, followedByDigit bool) mavenQualifier {
+	if followedByDigit && len(qualifier) == 1 {
+		switch qualifier {
+		case "a":
+			qualifier = "alpha"
+		case "b":
+			qualifier = "beta"
+		case "m":
+			qualifier = "milestone"
+		}
+	}
+
+	if alias, ok := mavenQualifierAliases[qualifier]; ok {
+		qualifier = alias
+	}
+
+	return mavenQualifier(qualifier)
+}
+
+// comparableQualifier returns a string that sorts well known qualifiers in their order, before any other qualifier,
+// and other qualifiers alphabetically.
+func comparableQualifier(qualifier string) string {
+	for i, known := range mavenQualifiers {
+		if qualifier == known {
+			return strconv.Itoa(i)
+		}
+	}
+
+	return fmt.Sprintf("%d-%s", len(mavenQualifiers), qualifier)
+}
+
+func (n mavenNumber) compare(other mavenItem) int {
+	switch o := other.(type) {
+	case nil:
+		if n == "" {
+			return 0
+		}
+		return 1
+	case mavenNumber:
+		// Numbers have no leading zeros, so a longer number is a bigger one.
+		if len(n) != len(o) {
+			return compareInt(int64(len(n)), int64(len(o)))
+		}
+		return strings.Compare(string(n), string(o))
+	default:
+		// "1.1" is newer than "1-sp" and "1-1".
+		return 1
+	}
+}
+
+func (q mavenQualifier) compare(other mavenItem) int {
+	switch o := other.(type) {
+	case nil:
+		// "1-rc" is older than "1" while "1-sp" is newer.
+		return strings.Compare(comparableQualifier(string(q)), mavenReleaseQualifier)
+	case mavenQualifier:
+		return strings.Compare(comparableQualifier(string(q)), comparableQualifier(string(o)))
+	default:
+		// "1.sp" is older than "1.1" and "1-1".
+		return -1
+	}
+}
+
+func (l mavenList) compare(other mavenItem) int {
+	switch o := other.(type) {
+	case nil:
+		if len(l) == 0 {
+			return 0
+		}
+		return l[0].compare(nil)
+	case mavenNumber:
+		// "1-1" is older than "1.1".
+		return -1
+	case mavenQualifier:
+		// "1-1" is newer than "1-sp".
+		return 1
+	case mavenList:
+		for i := 0; i < len(l) || i < len(o); i++ {
+			var left, right mavenItem
+			if i < len(l) {
+				left = l[i]
+			}
+			if i < len(o) {
+				right = o[i]
+			}
+
+			var c int
+			if left == nil {
+				if right != nil {
+					c = -right.compare(nil)
+				}
+			} else {
+				c = left.compare(right)
+			}
+
+			if c != 0 {
+				return c
+			}
+		}
+	}
+
+	return 0
+}
+
+// normalize drops the trailing null items of the list, stopping at the first item that is not a null or a list.
+func (l *mavenList) normalize() {
+	for i := len(*l) - 1; i >= 0; i-- {
+		item := (*l)[i]
+
+		if isNullMavenItem(item) {
+			*l = append((*l)[:i], (*l)[i+1:]...)
+		} else if _, ok := item.(*mavenList); !ok {
+			break
+		}
+	}
+}
+
+// resolve turns the sub lists the parser builds in place into plain lists.
+func (l *mavenList) resolve() mavenList {
+	resolved := make(mavenList, len(*l))
+
+	for i, item := range *l {
+		if sub, ok := item.(*mavenList); ok {
+			item = sub.resolve()
+		}
+		resolved[i] = item
+	}
+
+	return resolved
+}
+
+func isNullMavenItem(item mavenItem) bool {
+	switch i := item.(type) {
+	case mavenNumber:
+		return i == ""
+	case mavenQualifier:
+		return comparableQualifier(string(i)) == mavenReleaseQualifier
+	case *mavenList:
+		return len(*i) == 0
+	}
+
+	return false
+}
+
+// String returns the version as it was written.
+func (v *MavenVersion) String() string {
+	return v.source
+}
+
+// Compare orders versions like Maven's ComparableVersion.
+func (v *MavenVersion) Compare(other Version) int {
+	o, ok := other.(*MavenVersion)
+	if !ok {
+		return compareForeign(v, other)
+	}
+
+	return v.items.compare(o.items)
+}
+
+// Release returns the leading numbers of the version, e.g. 1 and 2 for "1.2-beta-1".
+func (v *MavenVersion) Release() []uint64 {
+	var release []uint64
+
+	for _, item := range v.items {
+		number, ok := item.(mavenNumber)
+		if !ok {
+			break
+		}
+
+		n, _ := strconv.ParseUint("0"+string(number), 10, 64)
+		release = append(release, n)
+	}
+
+	return release
+}
+
+// ParseMavenRange parses a Maven version range. An empty range accepts any version.
+func ParseMavenRange(rangeStr string) (*MavenRange, error) {
+	r := &MavenRange{source: rangeStr}
+	process := strings.TrimSpace(rangeStr)
+
+	for strings.HasPrefix(process, "[") || strings.HasPrefix(process, "(") {
+		end := strings.IndexAny(process, ")]")
+		if end < 0 {
+			return nil, fmt.Errorf("unbounded range %q", rangeStr)
+		}
+
+		restriction, err := parseMavenRestriction(process[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q: %s", rangeStr, err)
+		}
+
+		if len(r.restrictions) > 0 {
+			previous := r.restrictions[len(r.restrictions)-1]
+			if previous.upper == nil || restriction.lower == nil || restriction.lower.Compare(previous.upper) < 0 {
+				return nil, fmt.Errorf("invalid range %q: ranges overlap", rangeStr)
+			}
+		}
+
+		r.restrictions = append(r.restrictions, restriction)
+		process = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(process[end+1:]), ","))
+	}
+
+	if process != "" {
+		if len(r.restrictions) > 0 {
+			return nil, fmt.Errorf("invalid range %q: only bracketed sets are allowed with several sets", rangeStr)
+		}
+
+		soft, err := ParseMavenVersion(process)
+		if err != nil {
+			return nil, err
+		}
+
+		r.soft = soft
+	}
+
+	return r, nil
+}
+
+func parseMavenRestriction(spec string) (mavenRestriction, error) {
+	restriction := mavenRestriction{
+		lowerInclusive: strings.HasPrefix(spec, "["),
+		upperInclusive: strings.HasSuffix(spec, "]"),
+	}
+
+	inner := strings.TrimSpace(spec[1 : len(spec)-1])
+
+	comma := strings.Index(inner, ",")
+	if comma < 0 {
+		if !restriction.lowerInclusive || !restriction.upperInclusive {
+			return restriction, fmt.Errorf("single version %s must be surrounded by []", spec)
+		}
+
+		version, err := ParseMavenVersion(inner)
+		if err != nil {
+			return restriction, err
+		}
+
+		restriction.lower, restriction.upper = version, version
+
+		return restriction, nil
+	}
+
+	lowerStr, upperStr := strings.TrimSpace(inner[:comma]), strings.TrimSpace(inner[comma+1:])
+	if strings.Contains(upperStr, ",") {
+		return restriction, fmt.Errorf("%s has more than two bounds", spec)
+	}
+
+	if lowerStr != "" {
+		restriction.lower, _ = ParseMavenVersion(lowerStr)
+	}
+
+	if upperStr != "" {
+		restriction.upper, _ = ParseMavenVersion(upperStr)
+	}
+
+	if restriction.lower != nil && restriction.upper != nil {
+		c := restriction.lower.Compare(restriction.upper)
+
+		if c > 0 {
+			return restriction, fmt.Errorf("%s defies version ordering", spec)
+		}
+
+		if c == 0 && (!restriction.lowerInclusive || !restriction.upperInclusive) {
+			return restriction, fmt.Errorf("%s cannot have identical exclusive bounds", spec)
+		}
+	}
+
+	return restriction, nil
+}
+
+// String returns the range as it was written.
+func (r *MavenRange) String() string {
+	return r.source
+}
+
+// Contains returns whether the version is in one of the sets of the range. A soft requirement only contains newer
+// versions than the one it recommends, the versions a dependency pinned that way would be updated to.
+func (r *MavenRange) Contains(version Version) bool {
+	if _, ok := version.(*MavenVersion); !ok {
+		return false
+	}
+
+	if r.soft != nil {
+		return version.Compare(r.soft) > 0
+	}
+
+	if len(r.restrictions) == 0 {
+		return true
+	}
+
+	for _, restriction := range r.restrictions {
+		if restriction.contains(version) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r mavenRestriction) contains(version Version) bool {
+	if r.lower != nil {
+		c := version.Compare(r.lower)
+		if c < 0 || (c == 0 && !r.lowerInclusive) {
+			return false
+		}
+	}
+
+	if r.upper != nil {
+		c := version.Compare(r.upper)
+		if c > 0 || (c == 0 && !r.upperInclusive) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"time"
+
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/notify"
+)
+
+// EnableNotifications makes the service dispatch the lifecycle events of jobs: when they are created or locked, when
+// their pull request is opened, and when they fail or run out of attempts.
+func (s *JobService) EnableNotifications(notifications *notify.Dispatcher) {
+	s.notifications = notifications
+}
+
+// notify dispatches an event about a job, when notifications are enabled.
+func (s *JobService) notify(rs app.RequestScope, eventType notify.EventType, job *models.Job) {
+	s.dispatch(rs.DB(), eventType, job, rs.Now())
+}
+
+// dispatch dispatches an event about a job, when notifications are enabled. The repository of the job is read by the
+// dispatcher so the event can be routed without holding up the caller. The event carries a copy of the job as it is
+// now, the caller going on to change the job while the event waits to be delivered.
+func (s *JobService) dispatch(db *mongo.Database, eventType notify.EventType, job *models.Job, now time.Time) {
+	if s.notifications == nil || job == nil {
+		return
+	}
+
+	copied := *job
+	copied.Dependencies = append([]*models.PublishedDependency(nil), job.Dependencies...)
+	copied.BlockedBy = append([]string(nil), job.BlockedBy...)
+
+	s.notifications.DispatchFor(notify.Event{Type: eventType, Job: &copied, Time: now}, func() (*models.Repository, error) {
+		return s.repDao.Get(db, copied.Name)
+	})
+}
+
+// notifyFailure dispatches the event of a job that failed, which is either retried or dead-lettered.
+func (s *JobService) notifyFailure(db *mongo.Database, job *models.Job, now time.Time) {
+	if job != nil && job.State == models.DeadLetter {
+		s.dispatch(db, notify.JobDeadLettered, job, now)
+	} else {
+		s.dispatch(db, notify.JobFailed, job, now)
+	}
+}
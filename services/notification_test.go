@@ -0,0 +1,135 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/notify"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockNotifier records the types of the events it is notified of, by job.
+type mockNotifier struct {
+	mu     sync.Mutex
+	events map[string][]notify.EventType
+}
+
+func (m *mockNotifier) Notify(event notify.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events[event.Job.Name] = append(m.events[event.Job.Name], event.Type)
+
+	return nil
+}
+
+func newTestDispatcher() (*notify.Dispatcher, *mockNotifier) {
+	notifier := &mockNotifier{events: map[string][]notify.EventType{}}
+	d := notify.NewDispatcher(logrus.New(), 10, 1, time.Millisecond)
+	d.Add("test", notifier)
+	d.Start()
+
+	return d, notifier
+}
+
+func TestJobService_Notifications(t *testing.T) {
+	defer setRetryConfig(2, 0)()
+
+	prService, _ := newTestPullRequestService(t, map[string]string{"package.json": `{"dependencies": {"lib": "^1.0.0"}}`})
+	jobDAO := newMockJobDAO().(*mockJobDAO)
+	jobDAO.records[1].State = models.InProgress
+	repDAO := prService.repDao.(*mockRepositoryDAO)
+	repDAO.records = append(repDAO.records,
+		createRepository("bbb", "lib", "^1.0.0", "1.0.0"),
+		createRepository("ddd", "lib", "^1.0.0", "1.0.0"),
+	)
+
+	prService.dao = jobDAO
+	s := NewJobService(jobDAO, repDAO)
+	s.EnablePullRequests(prService)
+	d, notifier := newTestDispatcher()
+	s.EnableNotifications(d)
+	rs := new(MockRequestScope)
+
+	_, err := s.CreateJobsFromHook(rs, &models.NpmHook{Name: "lib", Version: "1.1.0"})
+	assert.Nil(t, err)
+
+	jobDAO.records[len(jobDAO.records)-1].State = models.Queued
+	job, _ := s.Claim(rs, "worker-1")
+	_, err = s.Fail(rs, job.Name, "worker-1", "boom")
+	assert.Nil(t, err)
+
+	jobDAO.records[len(jobDAO.records)-1].NotBefore = time.Time{}
+	job, _ = s.Claim(rs, "worker-1")
+	_, err = s.Fail(rs, job.Name, "worker-1", "boom again")
+	assert.Nil(t, err)
+
+	_, err = s.Requeue(rs, job.ID, "someone")
+	assert.Nil(t, err)
+	job, _ = s.Claim(rs, "worker-1")
+	_, err = s.Transition(rs, job.Name, 0, models.Failed, "someone")
+	assert.Nil(t, err)
+
+	active := createJob("app", "lib", "1.1.0")
+	active.ID = 42
+	active.State = models.InProgress
+	active.Lease = &models.Lease{Worker: "worker-2"}
+	jobDAO.records = append(jobDAO.records, active)
+	_, err = s.Complete(rs, "app", "worker-2", nil)
+	assert.Nil(t, err)
+
+	// a lease expiring is a failure too
+	expired := createJob("eee", "lib", "1.1.0")
+	expired.ID = 43
+	expired.State = models.InProgress
+	expired.Lease = &models.Lease{Worker: "worker-3", ExpiresAt: rs.Now().Add(-time.Second)}
+	jobDAO.records = append(jobDAO.records, expired)
+	assert.Nil(t, s.Sweep(nil, rs.Now()))
+
+	d.Close()
+
+	assert.Equal(t, map[string][]notify.EventType{
+		"app": {notify.JobCreated, notify.PullRequestOpened},
+		"bbb": {notify.JobCreated, notify.JobLocked},
+		"ddd": {notify.JobCreated, notify.JobFailed, notify.JobDeadLettered, notify.JobFailed},
+		"eee": {notify.JobFailed},
+	}, notifier.events)
+}
+
+// stateNotifier records the states of the jobs of the events it is notified of.
+type stateNotifier struct {
+	mu     sync.Mutex
+	states []models.State
+}
+
+func (n *stateNotifier) Notify(event notify.Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.states = append(n.states, event.Job.State)
+
+	return nil
+}
+
+func TestJobService_Notify_Copy(t *testing.T) {
+	notifier := &stateNotifier{}
+	d := notify.NewDispatcher(logrus.New(), 10, 1, time.Millisecond)
+	d.Add("test", notifier)
+
+	s := NewJobService(newMockJobDAO(), newMockRepositoryDAO())
+	s.EnableNotifications(d)
+
+	// the job changes before the dispatcher gets to the event
+	job := createJob("aaa", "test", "1.0.0")
+	job.State = models.Locked
+	s.notify(new(MockRequestScope), notify.JobCreated, job)
+	job.State = models.Idle
+
+	d.Start()
+	d.Close()
+
+	assert.Equal(t, []models.State{models.Locked}, notifier.states)
+}
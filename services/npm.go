@@ -0,0 +1,92 @@
+package services
+
+import (
+	"encoding/json"
+
+	"github.com/blang/semver"
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/quantumew/data-access/models"
+)
+
+func init() {
+	RegisterEcosystem(npmEcosystem{})
+}
+
+// npmEcosystem is the npm ecosystem. Versions are semantic versions parsed in loose mode and ranges are node-semver
+// ranges.
+type npmEcosystem struct{}
+
+// npmVersion is a version of an npm package.
+type npmVersion struct {
+	version semver.Version
+}
+
+// npmRange is a range of versions of an npm package.
+type npmRange struct {
+	source string
+	r      Range
+}
+
+func (npmEcosystem) Name() string {
+	return EcosystemNpm
+}
+
+// ParseHook reads an npm hook delivery.
+func (npmEcosystem) ParseHook(payload []byte) ([]*models.PublishedDependency, error) {
+	var hook models.NpmHook
+	if err := json.Unmarshal(payload, &hook); err != nil {
+		return nil, validation.Errors{"payload": err}
+	}
+
+	return []*models.PublishedDependency{NpmPublish(&hook)}, nil
+}
+
+func (npmEcosystem) ParseVersion(version string) (Version, error) {
+	v, err := ParseVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	return npmVersion{v}, nil
+}
+
+func (npmEcosystem) ParseRange(rangeStr string) (VersionRange, error) {
+	r, err := ParseRange(rangeStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return npmRange{rangeStr, r}, nil
+}
+
+func (v npmVersion) String() string {
+	return v.version.String()
+}
+
+func (v npmVersion) Compare(other Version) int {
+	o, ok := other.(npmVersion)
+	if !ok {
+		return compareForeign(v, other)
+	}
+
+	return v.version.Compare(o.version)
+}
+
+func (v npmVersion) Release() []uint64 {
+	return []uint64{v.version.Major, v.version.Minor, v.version.Patch}
+}
+
+func (r npmRange) String() string {
+	return r.source
+}
+
+func (r npmRange) Contains(version Version) bool {
+	v, ok := version.(npmVersion)
+
+	return ok && r.r.Test(v.version)
+}
+
+// NpmPublish returns the published version an npm hook announces.
+func NpmPublish(hook *models.NpmHook) *models.PublishedDependency {
+	return &models.PublishedDependency{Ecosystem: EcosystemNpm, Name: hook.Name, Version: hook.Version}
+}
@@ -0,0 +1,530 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Pep440Version is a parsed PEP 440 version, e.g. "1!2.0.1rc2.post1.dev3+ubuntu.1".
+type Pep440Version struct {
+	epoch   uint64
+	release []uint64
+	// pre is the pre-release phase, "a", "b" or "rc", and preNum its number. post and dev are -1 when missing.
+	pre    string
+	preNum uint64
+	post   int64
+	dev    int64
+	local  []string
+}
+
+// Pep440Specifier is a parsed PEP 440 version specifier, a list of clauses such as ">=1.0, !=1.3.*, <2.0" that a
+// version must all satisfy.
+type Pep440Specifier struct {
+	clauseList []specifierClause
+}
+
+// specifierClause is a single clause of a specifier such as "~=1.4.5". The version of prefix matching clauses, "==1.*"
+// or "!=1.3.*", is stored without the wildcard. raw is the version as written, for the "===" operator, whose version
+// is nil when it is not a valid one.
+type specifierClause struct {
+	operator string
+	version  *Pep440Version
+	prefix   bool
+	raw      string
+}
+
+var (
+	pep440Regexp = regexp.MustCompile(`(?i)^\s*v?` +
+		`(?:(?P<epoch>[0-9]+)!)?` +
+		`(?P<release>[0-9]+(?:\.[0-9]+)*)` +
+		`(?:[-_.]?(?P<pre_l>alpha|a|beta|b|preview|pre|c|rc)[-_.]?(?P<pre_n>[0-9]+)?)?` +
+		`(?:-(?P<post_n1>[0-9]+)|[-_.]?(?P<post_l>post|rev|r)[-_.]?(?P<post_n2>[0-9]+)?)?` +
+		`(?:[-_.]?(?P<dev_l>dev)[-_.]?(?P<dev_n>[0-9]+)?)?` +
+		`(?:\+(?P<local>[a-z0-9]+(?:[-_.][a-z0-9]+)*))?` +
+		`\s*$`)
+	clauseRegexp       = regexp.MustCompile(`^\s*(~=|===|==|!=|<=|>=|<|>)\s*(.*?)\s*$`)
+	localSplitRegexp   = regexp.MustCompile(`[-_.]`)
+	preLabelAliases    = map[string]string{"alpha": "a", "beta": "b", "c": "rc", "pre": "rc", "preview": "rc"}
+	preLabelOrder      = map[string]int{"a": 0, "b": 1, "rc": 2}
+	includesPrerelease = map[string]bool{"==": true, "===": true, "<": true, "<=": true, ">": true, ">=": true, "~=": true}
+)
+
+// ParsePep440Version parses a PEP 440 version, accepting the alternative spellings the PEP normalizes such as
+// "1.0-alpha1", "1.0c1" or "1.0-1".
+func ParsePep440Version(version string) (*Pep440Version, error) {
+	match := pep440Regexp.FindStringSubmatch(version)
+	if match == nil {
+		return nil, fmt.Errorf("invalid PEP 440 version %q", version)
+	}
+
+	group := map[string]string{}
+	for i, name := range pep440Regexp.SubexpNames() {
+		group[name] = strings.ToLower(match[i])
+	}
+
+	v := &Pep440Version{post: -1, dev: -1}
+
+	if group["epoch"] != "" {
+		v.epoch, _ = strconv.ParseUint(group["epoch"], 10, 64)
+	}
+
+	for _, part := range strings.Split(group["release"], ".") {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PEP 440 version %q: %s", version, err)
+		}
+		v.release = append(v.release, n)
+	}
+
+	if label := group["pre_l"]; label != "" {
+		if alias, ok := preLabelAliases[label]; ok {
+			label = alias
+		}
+		v.pre = label
+		v.preNum = parseOptionalNumber(group["pre_n"])
+	}
+
+	if group["post_n1"] != "" {
+		v.post = int64(parseOptionalNumber(group["post_n1"]))
+	} else if group["post_l"] != "" {
+		v.post = int64(parseOptionalNumber(group["post_n2"]))
+	}
+
+	if group["dev_l"] != "" {
+		v.dev = int64(parseOptionalNumber(group["dev_n"]))
+	}
+
+	if group["local"] != "" {
+		v.local = localSplitRegexp.Split(group["local"], -1)
+	}
+
+	return v, nil
+}
+
+// parseOptionalNumber parses the number of a pre, post or dev release, a missing number meaning zero.
+func parseOptionalNumber(number string) uint64 {
+	n, _ := strconv.ParseUint(number, 10, 64)
+	return n
+}
+
+// String returns the normalized form of the version.
+func (v *Pep440Version) String() string {
+	return v.public() + v.localString()
+}
+
+// public returns the normalized form of the version without its local label.
+func (v *Pep440Version) public() string {
+	var b strings.Builder
+
+	if v.epoch != 0 {
+		fmt.Fprintf(&b, "%d!", v.epoch)
+	}
+
+	b.WriteString(joinRelease(v.release))
+
+	if v.pre != "" {
+		fmt.Fprintf(&b, "%s%d", v.pre, v.preNum)
+	}
+
+	if v.post >= 0 {
+		fmt.Fprintf(&b, ".post%d", v.post)
+	}
+
+	if v.dev >= 0 {
+		fmt.Fprintf(&b, ".dev%d", v.dev)
+	}
+
+	return b.String()
+}
+
+func (v *Pep440Version) localString() string {
+	if len(v.local) == 0 {
+		return ""
+	}
+
+	return "+" + strings.Join(v.local, ".")
+}
+
+// Release returns the release numbers of the version, e.g. 1, 4 and 5 for "1.4.5rc1".
+func (v *Pep440Version) Release() []uint64 {
+	return append([]uint64{}, v.release...)
+}
+
+// IsPrerelease returns whether the version is a pre-release or a development release.
+func (v *Pep440Version) IsPrerelease() bool {
+	return v.pre != "" || v.dev >= 0
+}
+
+// IsPostrelease returns whether the version is a post-release.
+func (v *Pep440Version) IsPostrelease() bool {
+	return v.post >= 0
+}
+
+// Compare orders versions as PEP 440 does. Within a release, development releases come first, then pre-releases,
+// the final release and post-releases. A local version comes after the version without its local label.
+func (v *Pep440Version) Compare(other Version) int {
+	o, ok := other.(*Pep440Version)
+	if !ok {
+		return compareForeign(v, other)
+	}
+
+	if c := v.comparePublic(o); c != 0 {
+		return c
+	}
+
+	return compareLocal(v.local, o.local)
+}
+
+func (v *Pep440Version) comparePublic(o *Pep440Version) int {
+	if c := compareUint(v.epoch, o.epoch); c != 0 {
+		return c
+	}
+
+	if c := compareRelease(v.release, o.release); c != 0 {
+		return c
+	}
+
+	if c := compareKey(v.preKey(), o.preKey()); c != 0 {
+		return c
+	}
+
+	// A missing post-release sorts before any post-release, a missing dev release after any dev release.
+	if c := compareInt(v.post, o.post); c != 0 {
+		return c
+	}
+
+	return compareInt(devKey(v.dev), devKey(o.dev))
+}
+
+// preKey returns how the pre-release phase of the version sorts: development releases of a final release come before
+// pre-releases, which come before final releases and their post-releases.
+func (v *Pep440Version) preKey() []int64 {
+	switch {
+	case v.pre != "":
+		return []int64{0, int64(preLabelOrder[v.pre]), int64(v.preNum)}
+	case v.post < 0 && v.dev >= 0:
+		return []int64{-1}
+	default:
+		return []int64{1}
+	}
+}
+
+// baseVersion returns the version with only its epoch and release.
+func (v *Pep440Version) baseVersion() *Pep440Version {
+	return &Pep440Version{epoch: v.epoch, release: v.release, post: -1, dev: -1}
+}
+
+// withoutLocal returns the version without its local label.
+func (v *Pep440Version) withoutLocal() *Pep440Version {
+	public := *v
+	public.local = nil
+
+	return &public
+}
+
+// segments returns the parts of the version prefix matching compares: the epoch, the release numbers padded to a
+// length, then the pre, post and dev releases.
+func (v *Pep440Version) segments(releaseLen int) []string {
+	segmentList := []string{strconv.FormatUint(v.epoch, 10)}
+
+	for i := 0; i < len(v.release) || i < releaseLen; i++ {
+		segmentList = append(segmentList, strconv.FormatUint(releasePart(v.release, i), 10))
+	}
+
+	if v.pre != "" {
+		segmentList = append(segmentList, fmt.Sprintf("%s%d", v.pre, v.preNum))
+	}
+
+	if v.post >= 0 {
+		segmentList = append(segmentList, fmt.Sprintf("post%d", v.post))
+	}
+
+	if v.dev >= 0 {
+		segmentList = append(segmentList, fmt.Sprintf("dev%d", v.dev))
+	}
+
+	return segmentList
+}
+
+func devKey(dev int64) int64 {
+	if dev < 0 {
+		return 1<<63 - 1
+	}
+
+	return dev
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareRelease compares release numbers, missing numbers counting as zero so "1.0" equals "1.0.0".
+func compareRelease(a, b []uint64) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		if c := compareUint(releasePart(a, i), releasePart(b, i)); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+func compareKey(a, b []int64) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareInt(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareInt(int64(len(a)), int64(len(b)))
+}
+
+// compareLocal compares local labels. Numeric segments sort after alphanumeric ones and numerically between
+// themselves, and a label sorts after the labels it starts with.
+func compareLocal(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		aNum, aErr := strconv.ParseUint(a[i], 10, 64)
+		bNum, bErr := strconv.ParseUint(b[i], 10, 64)
+
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = compareUint(aNum, bNum)
+		case aErr == nil:
+			c = 1
+		case bErr == nil:
+			c = -1
+		default:
+			c = strings.Compare(a[i], b[i])
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return compareInt(int64(len(a)), int64(len(b)))
+}
+
+func joinRelease(release []uint64) string {
+	partList := make([]string, len(release))
+
+	for i, n := range release {
+		partList[i] = strconv.FormatUint(n, 10)
+	}
+
+	return strings.Join(partList, ".")
+}
+
+// ParsePep440Specifier parses a comma separated PEP 440 version specifier. An empty specifier accepts any version.
+func ParsePep440Specifier(specifier string) (*Pep440Specifier, error) {
+	s := &Pep440Specifier{}
+
+	if strings.TrimSpace(specifier) == "" {
+		return s, nil
+	}
+
+	for _, clauseStr := range strings.Split(specifier, ",") {
+		clause, err := parseSpecifierClause(clauseStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PEP 440 specifier %q: %s", specifier, err)
+		}
+
+		s.clauseList = append(s.clauseList, clause)
+	}
+
+	return s, nil
+}
+
+func parseSpecifierClause(clauseStr string) (specifierClause, error) {
+	match := clauseRegexp.FindStringSubmatch(clauseStr)
+	if match == nil {
+		return specifierClause{}, fmt.Errorf("clause %q has no comparison operator", strings.TrimSpace(clauseStr))
+	}
+
+	clause := specifierClause{operator: match[1], raw: match[2]}
+
+	if clause.operator == "===" {
+		if clause.raw == "" || strings.ContainsAny(clause.raw, " \t") {
+			return clause, fmt.Errorf("clause %q has no version", clauseStr)
+		}
+
+		// Arbitrary equality compares strings, the version does not have to be valid.
+		clause.version, _ = ParsePep440Version(clause.raw)
+
+		return clause, nil
+	}
+
+	versionStr := clause.raw
+	if strings.HasSuffix(versionStr, ".*") {
+		if clause.operator != "==" && clause.operator != "!=" {
+			return clause, fmt.Errorf("%s does not allow a wildcard", clause.operator)
+		}
+
+		clause.prefix = true
+		versionStr = strings.TrimSuffix(versionStr, ".*")
+	}
+
+	version, err := ParsePep440Version(versionStr)
+	if err != nil {
+		return clause, err
+	}
+
+	clause.version = version
+
+	if len(version.local) > 0 && (clause.prefix || (clause.operator != "==" && clause.operator != "!=")) {
+		return clause, fmt.Errorf("%s does not allow a local version", clauseStr)
+	}
+
+	if clause.operator == "~=" && len(version.release) < 2 {
+		return clause, fmt.Errorf("~= needs at least two release numbers")
+	}
+
+	return clause, nil
+}
+
+// String returns the specifier as it was written, normalized.
+func (s *Pep440Specifier) String() string {
+	clauseList := make([]string, len(s.clauseList))
+
+	for i, clause := range s.clauseList {
+		clauseList[i] = clause.String()
+	}
+
+	return strings.Join(clauseList, ", ")
+}
+
+// Contains returns whether the version satisfies every clause of the specifier. Pre-releases only satisfy a specifier
+// that mentions a pre-release, as PEP 440 excludes them by default.
+func (s *Pep440Specifier) Contains(version Version) bool {
+	v, ok := version.(*Pep440Version)
+	if !ok {
+		return false
+	}
+
+	if v.IsPrerelease() && !s.allowsPrereleases() {
+		return false
+	}
+
+	for _, clause := range s.clauseList {
+		if !clause.contains(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Pep440Specifier) allowsPrereleases() bool {
+	for _, clause := range s.clauseList {
+		if includesPrerelease[clause.operator] && clause.version != nil && clause.version.IsPrerelease() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c specifierClause) String() string {
+	if c.operator == "===" {
+		return c.operator + c.raw
+	}
+
+	version := c.version.String()
+	if c.prefix {
+		version += ".*"
+	}
+
+	return c.operator + version
+}
+
+func (c specifierClause) contains(v *Pep440Version) bool {
+	switch c.operator {
+	case "===":
+		return strings.EqualFold(v.String(), c.raw)
+	case "==":
+		return c.equal(v)
+	case "!=":
+		return !c.equal(v)
+	case "~=":
+		prefix := specifierClause{operator: "==", version: &Pep440Version{
+			epoch:   c.version.epoch,
+			release: c.version.release[:len(c.version.release)-1],
+			post:    -1,
+			dev:     -1,
+		}, prefix: true}
+
+		return v.withoutLocal().Compare(c.version) >= 0 && prefix.equal(v)
+	case "<=":
+		return v.withoutLocal().Compare(c.version) <= 0
+	case ">=":
+		return v.withoutLocal().Compare(c.version) >= 0
+	case "<":
+		if v.withoutLocal().Compare(c.version) >= 0 {
+			return false
+		}
+
+		// "<3.1" does not allow pre-releases of 3.1 unless it is a pre-release itself.
+		return c.version.IsPrerelease() || !v.IsPrerelease() || v.baseVersion().Compare(c.version.baseVersion()) != 0
+	case ">":
+		if v.withoutLocal().Compare(c.version) <= 0 {
+			return false
+		}
+
+		// ">1.7" does not allow post-releases or local versions of 1.7 unless it is a post-release itself.
+		if v.baseVersion().Compare(c.version.baseVersion()) == 0 {
+			return (c.version.IsPostrelease() || !v.IsPostrelease()) && len(v.local) == 0
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// equal returns whether the version matches the version of a "==" clause. Without a local label in the clause the
+// local label of the version is ignored, and prefix matching compares the leading segments of the version only.
+func (c specifierClause) equal(v *Pep440Version) bool {
+	if c.prefix {
+		specList := c.version.segments(0)
+		candidateList := v.withoutLocal().segments(len(c.version.release))
+
+		if len(candidateList) < len(specList) {
+			return false
+		}
+
+		for i, segment := range specList {
+			if candidateList[i] != segment {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if len(c.version.local) == 0 {
+		v = v.withoutLocal()
+	}
+
+	return v.Compare(c.version) == 0
+}
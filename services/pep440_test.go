@@ -0,0 +1,205 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePep440Version(t *testing.T) {
+	// normalization examples from PEP 440
+	tests := map[string]string{
+		"1.0":            "1.0",
+		"v1.0":           "1.0",
+		"1!2.0":          "1!2.0",
+		"1.0a1":          "1.0a1",
+		"1.0-alpha1":     "1.0a1",
+		"1.0.beta.2":     "1.0b2",
+		"1.0c1":          "1.0rc1",
+		"1.0pre1":        "1.0rc1",
+		"1.0RC1":         "1.0rc1",
+		"1.0a":           "1.0a0",
+		"1.0-1":          "1.0.post1",
+		"1.0-r4":         "1.0.post4",
+		"1.0.rev4":       "1.0.post4",
+		"1.0.post":       "1.0.post0",
+		"1.0dev":         "1.0.dev0",
+		"1.0-dev2":       "1.0.dev2",
+		"1.0b2-346":      "1.0b2.post346",
+		" 1.0.post1 ":    "1.0.post1",
+		"1.0+ubuntu-1":   "1.0+ubuntu.1",
+		"1.0+Ubuntu_1.x": "1.0+ubuntu.1.x",
+		"01.02.003":      "1.2.3",
+	}
+
+	for version, normalized := range tests {
+		v, err := ParsePep440Version(version)
+		if assert.Nil(t, err, version) {
+			assert.Equal(t, normalized, v.String(), version)
+		}
+	}
+
+	for _, version := range []string{"", "1.0.", "1.0+", "1.0+ubuntu!", "a1.0", "1.0-", "latest", "1.*"} {
+		_, err := ParsePep440Version(version)
+		assert.NotNil(t, err, version)
+	}
+}
+
+func TestPep440Version_Compare(t *testing.T) {
+	// in ascending order, the examples from PEP 440 followed by the epochs
+	versionList := []string{
+		"1.dev0",
+		"1.0.dev456",
+		"1.0a1",
+		"1.0a2.dev456",
+		"1.0a12.dev456",
+		"1.0a12",
+		"1.0b1.dev456",
+		"1.0b2",
+		"1.0b2.post345.dev456",
+		"1.0b2.post345",
+		"1.0rc1.dev456",
+		"1.0rc1",
+		"1.0",
+		"1.0+abc.5",
+		"1.0+abc.7",
+		"1.0+5",
+		"1.0.post456.dev34",
+		"1.0.post456",
+		"1.0.15",
+		"1.1.dev1",
+		"2013.10",
+		"2014.04",
+		"1!1.0",
+		"1!1.1",
+	}
+
+	for i := 1; i < len(versionList); i++ {
+		lower, _ := ParsePep440Version(versionList[i-1])
+		higher, _ := ParsePep440Version(versionList[i])
+		assert.True(t, lower.Compare(higher) < 0, "%s < %s", lower, higher)
+		assert.True(t, higher.Compare(lower) > 0, "%s > %s", higher, lower)
+	}
+
+	a, _ := ParsePep440Version("1.0")
+	b, _ := ParsePep440Version("1.0.0")
+	assert.Equal(t, 0, a.Compare(b))
+}
+
+func TestPep440Specifier_Contains(t *testing.T) {
+	// examples from PEP 440
+	tests := []struct {
+		specifier string
+		version   string
+		contained bool
+	}{
+		{"~=2.2", "2.2", true},
+		{"~=2.2", "2.9", true},
+		{"~=2.2", "2.1", false},
+		{"~=2.2", "3.0", false},
+		{"~=1.4.5", "1.4.9", true},
+		{"~=1.4.5", "1.5.0", false},
+		{"~=2.2.post3", "2.2.post3", true},
+		{"~=2.2.post3", "2.2", false},
+		{"~=2.2.post3", "2.5", true},
+		{"~=1.4.5a4", "1.4.5a4", true},
+		{"~=1.4.5a4", "1.4.5", true},
+		{"~=1.4.5a4", "1.5.0", false},
+		{"~=2.2.0", "2.2.9", true},
+		{"~=2.2.0", "2.3.0", false},
+		{"~=1!1.0", "1!1.5", true},
+		{"~=1!1.0", "1.5", false},
+		{"==1.1.post1", "1.1.post1", true},
+		{"==1.1.post1", "1.1", false},
+		{"==1.1.*", "1.1.post1", true},
+		{"==1.1.*", "1.1.0", true},
+		{"==1.1.*", "1.10", false},
+		{"==1.*", "1.9.3", true},
+		{"==1.*", "2.0", false},
+		{"==1.1", "1.1.0", true},
+		{"==1.0", "1.0+upstream1", true},
+		{"==1.0+upstream1", "1.0+upstream1", true},
+		{"==1.0+upstream1", "1.0", false},
+		{"!=1.1.post1", "1.1", true},
+		{"!=1.1.post1", "1.1.post1", false},
+		{"!=1.1.*", "1.1.post1", false},
+		{"!=1.1.*", "1.2", true},
+		{">1.7", "1.7.1", true},
+		{">1.7", "1.7.0.post1", false},
+		{">1.7", "1.7+local", false},
+		{">1.7.post2", "1.7.1", true},
+		{">1.7.post2", "1.7.0.post3", true},
+		{">1.7.post2", "1.7.0", false},
+		{"<3.1", "3.0", true},
+		{"<3.1", "3.1", false},
+		{"<3.1", "3.1.dev0", false},
+		{"<3.1rc1", "3.1a1", true},
+		{"<=2.0", "2.0+local", true},
+		{">=1.0,<2.0", "1.5", true},
+		{">=1.0,<2.0", "2.0", false},
+		{">= 1.0, < 2.0", "0.9", false},
+		{"~=0.9, >=1.0, !=1.3.4.*, <2.0", "1.3.5", false},
+		{">=1.0, !=1.3.4.*, <2.0", "1.3.4", false},
+		{">=1.0, !=1.3.4.*, <2.0", "1.3.5", true},
+		{">=1.0, !=1.3.4.*, <2.0", "2.0", false},
+		{"===1.0", "1.0", true},
+		{"===1.0", "1.0.0", false},
+		{"", "3.0", true},
+		{"", "3.0rc1", false},
+		{">=1.0", "2.0b1", false},
+		{">=1.0b1", "2.0b1", true},
+		{">=1.0, !=1.5b1", "2.0b1", false},
+	}
+
+	for _, test := range tests {
+		specifier, err := ParsePep440Specifier(test.specifier)
+		if !assert.Nil(t, err, test.specifier) {
+			continue
+		}
+
+		version, err := ParsePep440Version(test.version)
+		if assert.Nil(t, err, test.version) {
+			assert.Equal(t, test.contained, specifier.Contains(version), "%s in %q", test.version, test.specifier)
+		}
+	}
+}
+
+func TestParsePep440Specifier(t *testing.T) {
+	specifier, err := ParsePep440Specifier(" >= 1.0 ,!=1.3.*,<2.0-dev")
+	if assert.Nil(t, err) {
+		assert.Equal(t, ">=1.0, !=1.3.*, <2.0.dev0", specifier.String())
+	}
+
+	for _, specifierStr := range []string{"1.0", "~=1", ">=1.*", "<1.0+local", "==1.0+local.*", "~=1.0+local", ">=1.0,", "==", "=>1.0"} {
+		_, err := ParsePep440Specifier(specifierStr)
+		assert.NotNil(t, err, specifierStr)
+	}
+}
+
+func TestPyPIEcosystem_ParseHook(t *testing.T) {
+	ecosystem, _ := LookupEcosystem(EcosystemPyPI)
+
+	pubList, err := ecosystem.ParseHook([]byte(`{"name":"requests","version":"2.20.0-rc1"}`))
+	if assert.Nil(t, err) {
+		assert.Equal(t, []*models.PublishedDependency{{Ecosystem: EcosystemPyPI, Name: "requests", Version: "2.20.0rc1"}}, pubList)
+	}
+
+	_, err = ecosystem.ParseHook([]byte(`{"name":"requests","version":"latest"}`))
+	assert.NotNil(t, err)
+}
+
+func TestJobService_CreateJobsFromPayload_PyPI(t *testing.T) {
+	jobDAO := &mockJobDAO{}
+	compatible := createRepository("aaa", "requests", "~=2.19", "2.19.1")
+	compatible.Ecosystem = EcosystemPyPI
+	pinned := createRepository("bbb", "requests", "==2.19.*", "2.19.1")
+	pinned.Ecosystem = EcosystemPyPI
+	s := NewJobService(jobDAO, &mockRepositoryDAO{records: []*models.Repository{compatible, pinned}})
+
+	result, err := s.CreateJobsFromPayload(new(MockRequestScope), EcosystemPyPI, []byte(`{"name":"requests","version":"2.20.0"}`))
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(result.Jobs)) {
+		assert.Equal(t, "aaa", result.Jobs[0].Name)
+		assert.Equal(t, "bbb", result.Skipped[0].Name)
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/quantumew/data-access/models"
+)
+
+// Update policy levels a repository or a single dependency can opt into. An empty level leaves it to the declared range.
+const (
+	PolicyPinned = "pinned"
+	PolicyPatch  = "patch"
+	PolicyMinor  = "minor"
+	PolicyMajor  = "major"
+)
+
+// SkippedRepository describes a repository that was not updated for a published dependency and why.
+type SkippedRepository struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// EffectivePolicy returns the update policy of a dependency, falling back on the repository wide policy.
+func EffectivePolicy(rep *models.Repository, dep models.Dependency) models.UpdatePolicy {
+	if dep.Policy != nil {
+		return *dep.Policy
+	}
+
+	return rep.Policy
+}
+
+// CheckPolicy returns an error describing why the published version is not allowed by the policy, if it is not.
+// The installed version is parsed in the ecosystem of the dependency to determine how big of a jump the update would be.
+func CheckPolicy(ecosystem Ecosystem, policy models.UpdatePolicy, installed string, pub Version) error {
+	if err := CheckPolicyPatterns(policy); err != nil {
+		return err
+	}
+
+	version := pub.String()
+
+	for _, pattern := range policy.Deny {
+		if matched, _ := path.Match(pattern, version); matched {
+			return fmt.Errorf("version %s is denied by %q", version, pattern)
+		}
+	}
+
+	if len(policy.Allow) > 0 && !matchAny(policy.Allow, version) {
+		return fmt.Errorf("version %s is not in the allow list", version)
+	}
+
+	switch policy.Level {
+	case "", PolicyMajor:
+		return nil
+	case PolicyPinned:
+		return fmt.Errorf("dependency is pinned")
+	case PolicyPatch, PolicyMinor:
+	default:
+		return fmt.Errorf("unknown update policy %q", policy.Level)
+	}
+
+	current, err := ecosystem.ParseVersion(installed)
+	if err != nil {
+		return fmt.Errorf("%s policy needs a valid installed version: %s", policy.Level, err)
+	}
+
+	pubRelease, currentRelease := pub.Release(), current.Release()
+
+	if releasePart(pubRelease, 0) != releasePart(currentRelease, 0) {
+		return fmt.Errorf("%s policy does not allow a major update from %s to %s", policy.Level, current, version)
+	}
+
+	if policy.Level == PolicyPatch && releasePart(pubRelease, 1) != releasePart(currentRelease, 1) {
+		return fmt.Errorf("patch policy does not allow a minor update from %s to %s", current, version)
+	}
+
+	return nil
+}
+
+// CheckPolicyPatterns returns an error if an allow or deny pattern of the policy is malformed.
+func CheckPolicyPatterns(policy models.UpdatePolicy) error {
+	for _, patternList := range [][]string{policy.Allow, policy.Deny} {
+		for _, pattern := range patternList {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %s", pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// releasePart returns a number of a release, missing numbers counting as zero.
+func releasePart(release []uint64, i int) uint64 {
+	if i < len(release) {
+		return release[i]
+	}
+
+	return 0
+}
+
+func matchAny(patternList []string, version string) bool {
+	for _, pattern := range patternList {
+		if matched, _ := path.Match(pattern, version); matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEffectivePolicy(t *testing.T) {
+	rep := createRepository("aaa", "test", "^1.0.0", "1.0.0")
+	rep.Policy = models.UpdatePolicy{Level: PolicyMinor}
+
+	assert.Equal(t, PolicyMinor, EffectivePolicy(rep, rep.Dependencies[0]).Level)
+
+	rep.Dependencies[0].Policy = &models.UpdatePolicy{Level: PolicyPinned}
+	assert.Equal(t, PolicyPinned, EffectivePolicy(rep, rep.Dependencies[0]).Level)
+}
+
+func TestCheckPolicy(t *testing.T) {
+	tests := []struct {
+		tag       string
+		policy    models.UpdatePolicy
+		installed string
+		published string
+		allowed   bool
+	}{
+		{"no policy", models.UpdatePolicy{}, "", "2.0.0", true},
+		{"major", models.UpdatePolicy{Level: PolicyMajor}, "1.0.0", "2.0.0", true},
+		{"minor allows minor", models.UpdatePolicy{Level: PolicyMinor}, "1.0.0", "1.3.0", true},
+		{"minor denies major", models.UpdatePolicy{Level: PolicyMinor}, "1.0.0", "2.0.0", false},
+		{"patch allows patch", models.UpdatePolicy{Level: PolicyPatch}, "1.2.0", "1.2.9", true},
+		{"patch denies minor", models.UpdatePolicy{Level: PolicyPatch}, "1.2.0", "1.3.0", false},
+		{"patch needs installed", models.UpdatePolicy{Level: PolicyPatch}, "", "1.3.0", false},
+		{"pinned", models.UpdatePolicy{Level: PolicyPinned}, "1.2.0", "1.2.1", false},
+		{"unknown level", models.UpdatePolicy{Level: "sometimes"}, "1.2.0", "1.2.1", false},
+		{"allow list match", models.UpdatePolicy{Allow: []string{"1.2.*"}}, "1.2.0", "1.2.1", true},
+		{"allow list miss", models.UpdatePolicy{Allow: []string{"1.2.*"}}, "1.2.0", "1.3.0", false},
+		{"deny list", models.UpdatePolicy{Deny: []string{"*-*"}}, "1.2.0", "1.3.0-beta.1", false},
+		{"deny wins over allow", models.UpdatePolicy{Allow: []string{"*"}, Deny: []string{"1.3.0"}}, "1.2.0", "1.3.0", false},
+		{"malformed pattern", models.UpdatePolicy{Deny: []string{"1.[2"}}, "1.2.0", "1.3.0", false},
+	}
+
+	npm, _ := LookupEcosystem(EcosystemNpm)
+
+	for _, test := range tests {
+		pub, err := npm.ParseVersion(test.published)
+		assert.Nil(t, err, test.tag)
+
+		err = CheckPolicy(npm, test.policy, test.installed, pub)
+		if test.allowed {
+			assert.Nil(t, err, test.tag)
+		} else {
+			assert.NotNil(t, err, test.tag)
+		}
+	}
+}
+
+func TestCheckPolicyPatterns(t *testing.T) {
+	assert.Nil(t, CheckPolicyPatterns(models.UpdatePolicy{Allow: []string{"1.2.*"}, Deny: []string{"*-*"}}))
+	assert.NotNil(t, CheckPolicyPatterns(models.UpdatePolicy{Allow: []string{"1.[2"}}))
+	assert.NotNil(t, CheckPolicyPatterns(models.UpdatePolicy{Deny: []string{"1.2.\\"}}))
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/app"
+)
+
+// tagPublishes returns the versions a tag cut in a project on a code host, such as "octo-org/widgets", publishes. Every
+// package the registered repositories publish from the project is published at the version of the tag, without a "v"
+// prefix. Tags in the "<package>@<version>" form monorepo tools cut only publish the package they name.
+func (s *JobService) tagPublishes(rs app.RequestScope, project string, tag string) ([]*models.PublishedDependency, error) {
+	repList, err := queryAllRepositories(rs.DB(), s.repDao)
+	if err != nil {
+		return nil, err
+	}
+
+	tagPackage, version := parseTag(tag)
+	var pubList []*models.PublishedDependency
+
+	for _, rep := range repList {
+		for _, pkg := range ProjectPackages(rep, project) {
+			if tagPackage == "" || tagPackage == pkg {
+				pubList = append(pubList, &models.PublishedDependency{Ecosystem: RepositoryEcosystem(rep), Name: pkg, Version: version})
+			}
+		}
+	}
+
+	return pubList, nil
+}
+
+// ProjectPackages returns the packages a repository publishes from a project on a code host. The project mapping of
+// the repository is used when it has one for the project, with no packages listed meaning all of them. Otherwise all
+// of its packages are published from the project its remote points to.
+func ProjectPackages(rep *models.Repository, project string) []string {
+	for _, mapping := range rep.Projects {
+		if !strings.EqualFold(mapping.Project, project) {
+			continue
+		}
+
+		if len(mapping.Packages) == 0 {
+			return rep.Packages
+		}
+
+		return mapping.Packages
+	}
+
+	if matchesRemote(rep, project) {
+		return rep.Packages
+	}
+
+	return nil
+}
+
+// parseTag splits a release tag into the package it names, if any, and the version.
+func parseTag(tag string) (string, string) {
+	tag = strings.TrimPrefix(tag, "refs/tags/")
+	pkg := ""
+
+	// The package of a scoped tag such as "@org/pkg@1.0.0" starts with an @ of its own.
+	if i := strings.LastIndex(tag, "@"); i > 0 {
+		pkg, tag = tag[:i], tag[i+1:]
+	}
+
+	return pkg, strings.TrimPrefix(tag, "v")
+}
+
+// matchesRemote returns whether the remote of a repository points to a project on a code host, whether it is cloned
+// over SSH or HTTPS.
+func matchesRemote(rep *models.Repository, project string) bool {
+	if project == "" {
+		return false
+	}
+
+	remote := strings.TrimSuffix(strings.TrimSuffix(rep.Config.Remote, "/"), ".git")
+	remote = strings.ToLower(remote)
+	project = strings.ToLower(project)
+
+	return strings.HasSuffix(remote, "/"+project) || strings.HasSuffix(remote, ":"+project)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectPackages(t *testing.T) {
+	rep := createPublisher("widgets", "@octo-org/widgets")
+	rep.Packages = append(rep.Packages, "@octo-org/dials")
+	rep.Config.Remote = "git@gitlab.com:octo-org/widgets.git"
+
+	assert.Equal(t, rep.Packages, ProjectPackages(rep, "octo-org/widgets"))
+	assert.Empty(t, ProjectPackages(rep, "octo-org/dials"))
+
+	rep.Projects = []models.ProjectMapping{
+		{Project: "octo-org/dials", Packages: []string{"@octo-org/dials"}},
+		{Project: "mirror/widgets"},
+	}
+
+	assert.Equal(t, []string{"@octo-org/dials"}, ProjectPackages(rep, "Octo-Org/dials"))
+	assert.Equal(t, rep.Packages, ProjectPackages(rep, "mirror/widgets"))
+	assert.Equal(t, rep.Packages, ProjectPackages(rep, "octo-org/widgets"))
+}
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		tag     string
+		pkg     string
+		version string
+	}{
+		{"v1.2.3", "", "1.2.3"},
+		{"1.2.3", "", "1.2.3"},
+		{"refs/tags/v1.2.3", "", "1.2.3"},
+		{"widgets@1.2.3", "widgets", "1.2.3"},
+		{"@octo-org/widgets@v1.2.3", "@octo-org/widgets", "1.2.3"},
+	}
+
+	for _, test := range tests {
+		pkg, version := parseTag(test.tag)
+		assert.Equal(t, test.pkg, pkg, test.tag)
+		assert.Equal(t, test.version, version, test.tag)
+	}
+}
+
+func TestMatchesRemote(t *testing.T) {
+	rep := createPublisher("widgets", "@octo-org/widgets")
+
+	for _, remote := range []string{"git@github.com:Octo-Org/widgets.git", "https://github.com/octo-org/widgets", "ssh://git@github.com/Octo-Org/widgets.git"} {
+		rep.Config.Remote = remote
+		assert.True(t, matchesRemote(rep, "Octo-Org/widgets"), remote)
+	}
+
+	rep.Config.Remote = "git@github.com:Octo-Org/other-widgets.git"
+	assert.False(t, matchesRemote(rep, "Octo-Org/widgets"))
+	assert.False(t, matchesRemote(rep, ""))
+}
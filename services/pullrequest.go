@@ -0,0 +1,411 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/quantumew/data-access"
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/vcs"
+)
+
+// PullRequestFailed is the state recorded on a job whose pull request could not be opened.
+const PullRequestFailed = "failed"
+
+// pullRequestHistoryPage is how many earlier jobs are read at a time when looking for the ones a pull request carries.
+const pullRequestHistoryPage = 20
+
+// defaultBaseBranch is the branch pull requests target when the repository does not configure one.
+const defaultBaseBranch = "master"
+
+const (
+	// DefaultPullRequestTitle is the template of the title of pull requests.
+	DefaultPullRequestTitle = `{{if eq (len .Dependencies) 1}}{{with index .Dependencies 0}}Update {{.Name}} to {{.Version}}` +
+		`{{end}}{{else}}Update {{len .Dependencies}} dependencies{{end}}`
+	// DefaultPullRequestBody is the template of the description of pull requests.
+	DefaultPullRequestBody = `New versions of the dependencies of {{.Repository}} were published:
+
+{{range .Dependencies}}* {{if .Changelog}}[{{.Name}} {{.Version}}]({{.Changelog}}){{else}}{{.Name}} {{.Version}}{{end}}` +
+		`{{if .Workspaces}} in {{join .Workspaces ", "}}{{end}}
+{{end}}`
+)
+
+// DefaultChangelogs are the templates of the links to the changelogs of published versions by ecosystem.
+var DefaultChangelogs = map[string]string{
+	EcosystemNpm:   "https://www.npmjs.com/package/{{.Name}}/v/{{.Version}}",
+	EcosystemGo:    "https://pkg.go.dev/{{.Name}}@{{.Version}}",
+	EcosystemPyPI:  "https://pypi.org/project/{{.Name}}/{{.Version}}/",
+	EcosystemMaven: `https://central.sonatype.com/artifact/{{replace .Name ":" "/"}}/{{.Version}}`,
+}
+
+// templateFuncs are the functions pull request and changelog templates can use besides the builtin ones.
+var templateFuncs = template.FuncMap{"join": strings.Join, "replace": strings.ReplaceAll}
+
+// PullRequestTemplates render the title and the description of the pull requests of jobs.
+type PullRequestTemplates struct {
+	title      *template.Template
+	body       *template.Template
+	changelogs map[string]*template.Template
+}
+
+// PullRequestData is what pull request templates are executed with.
+type PullRequestData struct {
+	Repository   string
+	Job          *models.Job
+	Dependencies []PullRequestDependency
+}
+
+// PullRequestDependency is a dependency a pull request updates, at the latest version its job was queued with.
+type PullRequestDependency struct {
+	Ecosystem  string
+	Name       string
+	Version    string
+	Workspaces []string
+	// Changelog is the link to the changelog of the version, empty when the ecosystem has no changelog template.
+	Changelog string
+}
+
+// PullRequestService opens the pull requests bumping the dependencies of completed jobs.
+type PullRequestService struct {
+	dao          access.JobDAO
+	repDao       access.RepositoryDAO
+	hosts        *vcs.Hosts
+	templates    *PullRequestTemplates
+	branchPrefix string
+	labelList    []string
+}
+
+// NewPullRequestTemplates parses the templates of pull requests, the defaults being used for empty ones. Changelog
+// templates are executed with each dependency and override the default of their ecosystem.
+func NewPullRequestTemplates(title string, body string, changelogs map[string]string) (*PullRequestTemplates, error) {
+	var err error
+	templates := &PullRequestTemplates{changelogs: map[string]*template.Template{}}
+
+	if title == "" {
+		title = DefaultPullRequestTitle
+	}
+
+	if body == "" {
+		body = DefaultPullRequestBody
+	}
+
+	if templates.title, err = template.New("title").Funcs(templateFuncs).Parse(title); err != nil {
+		return nil, err
+	}
+
+	if templates.body, err = template.New("body").Funcs(templateFuncs).Parse(body); err != nil {
+		return nil, err
+	}
+
+	for _, changelogMap := range []map[string]string{DefaultChangelogs, changelogs} {
+		for ecosystem, changelog := range changelogMap {
+			if templates.changelogs[ecosystem], err = template.New(ecosystem).Funcs(templateFuncs).Parse(changelog); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return templates, nil
+}
+
+// Render returns the title and the description of the pull request of a job.
+func (t *PullRequestTemplates) Render(rep *models.Repository, job *models.Job) (string, string, error) {
+	data := PullRequestData{Repository: rep.Name, Job: job}
+
+	for _, dep := range LatestDependencies(job) {
+		item := PullRequestDependency{
+			Ecosystem:  normalizeEcosystem(dep.Ecosystem),
+			Name:       dep.Name,
+			Version:    dep.Version,
+			Workspaces: dep.Workspaces,
+		}
+
+		if changelog, ok := t.changelogs[item.Ecosystem]; ok {
+			var link bytes.Buffer
+			if err := changelog.Execute(&link, item); err != nil {
+				return "", "", err
+			}
+
+			item.Changelog = link.String()
+		}
+
+		data.Dependencies = append(data.Dependencies, item)
+	}
+
+	var title, body bytes.Buffer
+
+	if err := t.title.Execute(&title, data); err != nil {
+		return "", "", err
+	}
+
+	if err := t.body.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+
+	return strings.TrimSpace(title.String()), body.String(), nil
+}
+
+// LatestDependencies returns the dependencies of a job at the latest version it was queued with, in the order they
+// were first queued. The workspaces of the versions of a dependency are merged.
+func LatestDependencies(job *models.Job) []*models.PublishedDependency {
+	var depList []*models.PublishedDependency
+	latestMap := map[string]*models.PublishedDependency{}
+
+	for _, dep := range job.Dependencies {
+		key := PackageKey(dep.Ecosystem, dep.Name)
+		latest, ok := latestMap[key]
+
+		if !ok {
+			latest = &models.PublishedDependency{Ecosystem: dep.Ecosystem, Name: dep.Name, Version: dep.Version}
+			latestMap[key] = latest
+			depList = append(depList, latest)
+		} else if isNewerVersion(dep, latest.Version) {
+			latest.Version = dep.Version
+		}
+
+		for _, workspace := range dep.Workspaces {
+			if !containsString(latest.Workspaces, workspace) {
+				latest.Workspaces = append(latest.Workspaces, workspace)
+			}
+		}
+	}
+
+	return depList
+}
+
+// isNewerVersion returns whether the version of a published dependency is newer than another version. Versions that
+// cannot be compared are taken as newer, as they were published later.
+func isNewerVersion(dep *models.PublishedDependency, other string) bool {
+	ecosystem, err := LookupEcosystem(dep.Ecosystem)
+	if err != nil {
+		return true
+	}
+
+	version, err := ecosystem.ParseVersion(dep.Version)
+	if err != nil {
+		return true
+	}
+
+	otherVersion, err := ecosystem.ParseVersion(other)
+	if err != nil {
+		return true
+	}
+
+	return version.Compare(otherVersion) > 0
+}
+
+// NewPullRequestService creates a new PullRequestService pushing to the version control services repositories are
+// hosted on. Pull requests are opened from a branch named after the repository, prefixed with the branch prefix, and
+// labeled with the labels of the list. The job DAO is where the earlier jobs a pull request carries are read from.
+func NewPullRequestService(dao access.JobDAO, repDao access.RepositoryDAO, hosts *vcs.Hosts, templates *PullRequestTemplates, branchPrefix string, labelList []string) *PullRequestService {
+	return &PullRequestService{dao, repDao, hosts, templates, branchPrefix, labelList}
+}
+
+// Open opens the pull request bumping the ranges of the npm dependencies of a job in the package.json of every
+// workspace they are updated in, or updates the one still open for the repository. Other ecosystems are listed in the
+// pull request but their manifests are left to workers. The code owners of the bumped files are requested as
+// reviewers. An updated pull request is rendered from the dependencies of every job it carries. Nil is returned when
+// there is nothing to bump and no pull request to update.
+func (s *PullRequestService) Open(rs app.RequestScope, job *models.Job) (*models.PullRequest, error) {
+	rep, err := s.repDao.Get(rs.DB(), job.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	vc, repo, err := s.hosts.Lookup(rep.Config.VersionControl, rep.Config.Remote)
+	if err != nil {
+		return nil, err
+	}
+
+	base := rep.Config.Branch
+	if base == "" {
+		base = defaultBaseBranch
+	}
+
+	branch := s.branchPrefix + job.Name
+
+	existing, err := vc.FindPullRequest(repo, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	// An open pull request is built upon, so the ranges it bumped for earlier jobs stay bumped.
+	ref := base
+	if existing != nil {
+		ref = branch
+	}
+
+	fileList, err := bumpManifests(vc, repo, ref, LatestDependencies(job))
+	if err != nil {
+		return nil, err
+	}
+
+	if existing == nil && len(fileList) == 0 {
+		return nil, nil
+	}
+
+	rendered := job
+	if existing != nil {
+		if rendered, err = s.withEarlierJobs(rs, job, existing.ID, branch); err != nil {
+			return nil, err
+		}
+	}
+
+	title, body, err := s.templates.Render(rep, rendered)
+	if err != nil {
+		return nil, err
+	}
+
+	codeOwners, err := vcs.ReadCodeOwners(vc, repo, base)
+	if err != nil {
+		return nil, err
+	}
+
+	var reviewerList []string
+	for _, file := range fileList {
+		for _, owner := range codeOwners.Owners(file.Path) {
+			if !containsString(reviewerList, owner) {
+				reviewerList = append(reviewerList, owner)
+			}
+		}
+	}
+
+	if existing == nil {
+		// A branch without a pull request is left over from one that was merged or declined.
+		if err := vc.DeleteBranch(repo, branch); err != nil && err != vcs.ErrNotFound {
+			return nil, err
+		}
+
+		if err := vc.CreateBranch(repo, branch, base); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(fileList) > 0 {
+		if _, err := vc.CommitFiles(repo, vcs.Commit{Branch: branch, Message: title, Files: fileList}); err != nil {
+			return nil, err
+		}
+	}
+
+	var pr *vcs.PullRequest
+
+	if existing != nil {
+		existing.Title, existing.Description, existing.Labels = title, body, s.labelList
+		// Reviewers are replaced on some services, so the ones already requested are kept.
+		for _, reviewer := range reviewerList {
+			if !containsString(existing.Reviewers, reviewer) {
+				existing.Reviewers = append(existing.Reviewers, reviewer)
+			}
+		}
+
+		pr, err = vc.UpdatePullRequest(repo, *existing)
+	} else {
+		pr, err = vc.OpenPullRequest(repo, vcs.PullRequest{
+			Title:        title,
+			Description:  body,
+			SourceBranch: branch,
+			TargetBranch: base,
+			Reviewers:    reviewerList,
+			Labels:       s.labelList,
+		})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PullRequest{ID: pr.ID, Branch: branch, URL: pr.URL, State: pr.State}, nil
+}
+
+// withEarlierJobs returns a copy of a job that also lists the dependencies of the earlier jobs of its repository that
+// were recorded on an open pull request, oldest first. The history is read back until a job recorded on another pull
+// request, which the open one came after.
+func (s *PullRequestService) withEarlierJobs(rs app.RequestScope, job *models.Job, id int64, branch string) (*models.Job, error) {
+	var earlierList []*models.PublishedDependency
+
+	for offset := 0; ; offset += pullRequestHistoryPage {
+		jobList, err := s.dao.QueryByName(rs.DB(), job.Name, offset, pullRequestHistoryPage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, earlier := range jobList {
+			pr := earlier.PullRequest
+			if earlier.ID == job.ID || pr == nil || pr.State == PullRequestFailed {
+				continue
+			}
+
+			if pr.ID != id || pr.Branch != branch {
+				return mergeDependencies(job, earlierList), nil
+			}
+
+			earlierList = append(append([]*models.PublishedDependency{}, earlier.Dependencies...), earlierList...)
+		}
+
+		if len(jobList) < pullRequestHistoryPage {
+			return mergeDependencies(job, earlierList), nil
+		}
+	}
+}
+
+// mergeDependencies returns a copy of a job listing earlier dependencies before its own.
+func mergeDependencies(job *models.Job, earlierList []*models.PublishedDependency) *models.Job {
+	merged := *job
+	merged.Dependencies = append(append([]*models.PublishedDependency{}, earlierList...), job.Dependencies...)
+
+	return &merged
+}
+
+// bumpManifests returns the package.json files at a ref with the ranges of the npm dependencies bumped, leaving out
+// the ones that did not change.
+func bumpManifests(vc vcs.VersionControl, repo vcs.Repo, ref string, depList []*models.PublishedDependency) ([]vcs.FileChange, error) {
+	var pathList []string
+	originalMap := map[string][]byte{}
+	bumpedMap := map[string][]byte{}
+
+	for _, dep := range depList {
+		if normalizeEcosystem(dep.Ecosystem) != EcosystemNpm {
+			continue
+		}
+
+		workspaceList := dep.Workspaces
+		if len(workspaceList) == 0 {
+			workspaceList = []string{RootWorkspace}
+		}
+
+		for _, workspace := range workspaceList {
+			manifestPath := path.Join(workspace, "package.json")
+
+			if _, ok := originalMap[manifestPath]; !ok {
+				content, err := vc.ReadFile(repo, ref, manifestPath)
+				if err != nil {
+					return nil, fmt.Errorf("reading %s: %s", manifestPath, err)
+				}
+
+				originalMap[manifestPath], bumpedMap[manifestPath] = content, content
+				pathList = append(pathList, manifestPath)
+			}
+
+			bumped, err := BumpPackageJSON(bumpedMap[manifestPath], dep.Name, dep.Version)
+			if err != nil {
+				return nil, fmt.Errorf("bumping %s: %s", manifestPath, err)
+			}
+
+			bumpedMap[manifestPath] = bumped
+		}
+	}
+
+	var fileList []vcs.FileChange
+
+	for _, manifestPath := range pathList {
+		if !bytes.Equal(originalMap[manifestPath], bumpedMap[manifestPath]) {
+			fileList = append(fileList, vcs.FileChange{Path: manifestPath, Content: bumpedMap[manifestPath]})
+		}
+	}
+
+	return fileList, nil
+}
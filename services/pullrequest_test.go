@@ -0,0 +1,307 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/vcs"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockVersionControl is an in-memory code host with a single repository.
+type mockVersionControl struct {
+	// branches are the files of every branch.
+	branches     map[string]map[string]string
+	commits      []vcs.Commit
+	pullRequests []*vcs.PullRequest
+}
+
+func newMockVersionControl(files map[string]string) *mockVersionControl {
+	return &mockVersionControl{branches: map[string]map[string]string{"master": files}}
+}
+
+func (m *mockVersionControl) CreateBranch(repo vcs.Repo, branch string, startPoint string) error {
+	if _, ok := m.branches[branch]; ok {
+		return errors.New("branch exists")
+	}
+
+	m.branches[branch] = map[string]string{}
+	for path, content := range m.branches[startPoint] {
+		m.branches[branch][path] = content
+	}
+
+	return nil
+}
+
+func (m *mockVersionControl) DeleteBranch(repo vcs.Repo, branch string) error {
+	if _, ok := m.branches[branch]; !ok {
+		return vcs.ErrNotFound
+	}
+
+	delete(m.branches, branch)
+
+	return nil
+}
+
+func (m *mockVersionControl) CommitFiles(repo vcs.Repo, commit vcs.Commit) (string, error) {
+	files, ok := m.branches[commit.Branch]
+	if !ok {
+		return "", vcs.ErrNotFound
+	}
+
+	for _, file := range commit.Files {
+		files[file.Path] = string(file.Content)
+	}
+
+	m.commits = append(m.commits, commit)
+
+	return fmt.Sprint(len(m.commits)), nil
+}
+
+func (m *mockVersionControl) ReadFile(repo vcs.Repo, ref string, path string) ([]byte, error) {
+	content, ok := m.branches[ref][path]
+	if !ok {
+		return nil, vcs.ErrNotFound
+	}
+
+	return []byte(content), nil
+}
+
+func (m *mockVersionControl) FindPullRequest(repo vcs.Repo, sourceBranch string) (*vcs.PullRequest, error) {
+	for _, pr := range m.pullRequests {
+		if pr.SourceBranch == sourceBranch && pr.State == vcs.StateOpen {
+			found := *pr
+			return &found, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (m *mockVersionControl) OpenPullRequest(repo vcs.Repo, pr vcs.PullRequest) (*vcs.PullRequest, error) {
+	pr.ID = int64(len(m.pullRequests) + 1)
+	pr.State = vcs.StateOpen
+	pr.URL = fmt.Sprintf("https://stash.example.com/projects/%s/repos/%s/pull-requests/%d", repo.Owner, repo.Name, pr.ID)
+	m.pullRequests = append(m.pullRequests, &pr)
+
+	return &pr, nil
+}
+
+func (m *mockVersionControl) UpdatePullRequest(repo vcs.Repo, pr vcs.PullRequest) (*vcs.PullRequest, error) {
+	for _, existing := range m.pullRequests {
+		if existing.ID == pr.ID {
+			existing.Title, existing.Description = pr.Title, pr.Description
+			existing.Reviewers, existing.Labels = pr.Reviewers, pr.Labels
+			existing.Version++
+			updated := *existing
+			return &updated, nil
+		}
+	}
+
+	return nil, vcs.ErrNotFound
+}
+
+func (m *mockVersionControl) ClosePullRequest(repo vcs.Repo, pr vcs.PullRequest) error {
+	for _, existing := range m.pullRequests {
+		if existing.ID == pr.ID {
+			existing.State = vcs.StateDeclined
+			return nil
+		}
+	}
+
+	return vcs.ErrNotFound
+}
+
+func newTestPullRequestService(t *testing.T, files map[string]string) (*PullRequestService, *mockVersionControl) {
+	rep := createRepository("app", "lib", "^1.0.0", "1.0.0")
+	rep.Config.Remote = "ssh://git@stash.example.com:7999/proj/app.git"
+	vc := newMockVersionControl(files)
+
+	templates, err := NewPullRequestTemplates("", "", nil)
+	assert.Nil(t, err)
+
+	hosts := &vcs.Hosts{}
+	hosts.Add("stash", "https://stash.example.com", vc)
+
+	return NewPullRequestService(&mockJobDAO{}, &mockRepositoryDAO{records: []*models.Repository{rep}}, hosts, templates, "aufait/", nil), vc
+}
+
+func TestPullRequestTemplates_Render(t *testing.T) {
+	rep := &models.Repository{Name: "app"}
+	job := createJob("app", "lib", "1.2.0")
+	job.Dependencies = append(job.Dependencies,
+		&models.PublishedDependency{Name: "lib", Version: "1.1.0", Workspaces: []string{"packages/ui"}},
+		&models.PublishedDependency{Ecosystem: EcosystemMaven, Name: "com.example:widgets", Version: "2.0"},
+	)
+
+	templates, err := NewPullRequestTemplates("", "", map[string]string{EcosystemNpm: "https://npm.example.com/{{.Name}}/{{.Version}}"})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	title, body, err := templates.Render(rep, job)
+	assert.Nil(t, err)
+	assert.Equal(t, "Update 2 dependencies", title)
+	assert.Equal(t, `New versions of the dependencies of app were published:
+
+* [lib 1.2.0](https://npm.example.com/lib/1.2.0) in packages/ui
+* [com.example:widgets 2.0](https://central.sonatype.com/artifact/com.example/widgets/2.0)
+`, body)
+
+	title, _, err = templates.Render(rep, createJob("app", "lib", "1.2.0"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Update lib to 1.2.0", title)
+
+	templates, err = NewPullRequestTemplates("{{.Repository}}: {{range .Dependencies}}{{.Name}}{{end}}", "{{.Job.Name}}", nil)
+	if assert.Nil(t, err) {
+		title, body, err = templates.Render(rep, job)
+		assert.Nil(t, err)
+		assert.Equal(t, "app: libcom.example:widgets", title)
+		assert.Equal(t, "app", body)
+	}
+
+	_, err = NewPullRequestTemplates("{{.Repository", "", nil)
+	assert.NotNil(t, err)
+}
+
+func TestPullRequestService_Open(t *testing.T) {
+	s, vc := newTestPullRequestService(t, map[string]string{
+		"package.json":             `{"dependencies": {"lib": "^1.0.0", "other": "^2.0.0"}}`,
+		"packages/ui/package.json": `{"dependencies": {"lib": "~1.0.0"}}`,
+	})
+	rs := new(MockRequestScope)
+	// a branch left over from a merged pull request
+	vc.branches["aufait/app"] = map[string]string{}
+
+	job := createJob("app", "lib", "1.1.0")
+	job.ID = 1
+	job.Dependencies[0].Workspaces = []string{RootWorkspace, "packages/ui"}
+
+	pr, err := s.Open(rs, job)
+	if !assert.Nil(t, err) {
+		return
+	}
+	job.PullRequest = pr
+	s.dao.(*mockJobDAO).records = append(s.dao.(*mockJobDAO).records, job)
+
+	assert.Equal(t, &models.PullRequest{
+		ID:     1,
+		Branch: "aufait/app",
+		URL:    "https://stash.example.com/projects/proj/repos/app/pull-requests/1",
+		State:  vcs.StateOpen,
+	}, pr)
+	assert.Equal(t, "Update lib to 1.1.0", vc.pullRequests[0].Title)
+	assert.Equal(t, "master", vc.pullRequests[0].TargetBranch)
+	assert.Equal(t, `{"dependencies": {"lib": "^1.1.0", "other": "^2.0.0"}}`, vc.branches["aufait/app"]["package.json"])
+	assert.Equal(t, `{"dependencies": {"lib": "~1.1.0"}}`, vc.branches["aufait/app"]["packages/ui/package.json"])
+	assert.Equal(t, `{"dependencies": {"lib": "^1.0.0", "other": "^2.0.0"}}`, vc.branches["master"]["package.json"])
+
+	// the next job updates the pull request that is still open, which carries both jobs
+	job = createJob("app", "other", "2.1.0")
+	job.ID = 2
+	pr, err = s.Open(rs, job)
+	if assert.Nil(t, err) {
+		assert.Equal(t, int64(1), pr.ID)
+		assert.Equal(t, 1, len(vc.pullRequests))
+		assert.Equal(t, "Update 2 dependencies", vc.pullRequests[0].Title)
+		assert.Contains(t, vc.pullRequests[0].Description, "* [lib 1.1.0]")
+		assert.Contains(t, vc.pullRequests[0].Description, "* [other 2.1.0]")
+		assert.Equal(t, `{"dependencies": {"lib": "^1.1.0", "other": "^2.1.0"}}`, vc.branches["aufait/app"]["package.json"])
+	}
+	job.PullRequest = pr
+	s.dao.(*mockJobDAO).records = append(s.dao.(*mockJobDAO).records, job)
+
+	// once it is merged, a new one is opened
+	vc.pullRequests[0].State = vcs.StateMerged
+	job = createJob("app", "lib", "1.2.0")
+	job.ID = 3
+	pr, err = s.Open(rs, job)
+	if assert.Nil(t, err) {
+		assert.Equal(t, int64(2), pr.ID)
+		assert.Equal(t, "Update lib to 1.2.0", vc.pullRequests[1].Title)
+		assert.Equal(t, `{"dependencies": {"lib": "^1.2.0", "other": "^2.0.0"}}`, vc.branches["aufait/app"]["package.json"])
+	}
+}
+
+func TestPullRequestService_Open_Reviewers(t *testing.T) {
+	s, vc := newTestPullRequestService(t, map[string]string{
+		"package.json":             `{"dependencies": {"lib": "^1.0.0"}}`,
+		"packages/ui/package.json": `{"dependencies": {"lib": "^1.0.0"}}`,
+		".github/CODEOWNERS":       "* @octo-org/core\npackages/ui/ @jdoe @octo-org/ui ui@example.com\n",
+	})
+	s.labelList = []string{"dependencies"}
+	rs := new(MockRequestScope)
+
+	job := createJob("app", "lib", "1.1.0")
+	job.Dependencies[0].Workspaces = []string{"packages/ui"}
+
+	_, err := s.Open(rs, job)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"jdoe", "octo-org/ui"}, vc.pullRequests[0].Reviewers)
+		assert.Equal(t, []string{"dependencies"}, vc.pullRequests[0].Labels)
+	}
+
+	_, err = s.Open(rs, createJob("app", "lib", "1.1.0"))
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"jdoe", "octo-org/ui", "octo-org/core"}, vc.pullRequests[0].Reviewers)
+	}
+
+	s.repDao.(*mockRepositoryDAO).records[0].Config.VersionControl = "github"
+	_, err = s.Open(rs, job)
+	assert.NotNil(t, err, "unknown version control service")
+}
+
+func TestPullRequestService_Open_NothingToBump(t *testing.T) {
+	s, vc := newTestPullRequestService(t, map[string]string{"package.json": `{"dependencies": {"lib": ">=1.0.0 <2.0.0"}}`})
+	rs := new(MockRequestScope)
+
+	pr, err := s.Open(rs, createJob("app", "lib", "1.1.0"))
+	assert.Nil(t, err)
+	assert.Nil(t, pr)
+	assert.Empty(t, vc.pullRequests)
+	assert.NotContains(t, vc.branches, "aufait/app")
+
+	_, err = s.Open(rs, createJob("app", "lib", "1.1.0"))
+	assert.Nil(t, err)
+
+	s, _ = newTestPullRequestService(t, map[string]string{})
+	_, err = s.Open(rs, createJob("app", "lib", "1.1.0"))
+	assert.NotNil(t, err, "missing package.json")
+}
+
+func TestJobService_Complete_PullRequest(t *testing.T) {
+	prService, vc := newTestPullRequestService(t, map[string]string{"package.json": `{"dependencies": {"lib": "^1.0.0"}}`})
+
+	job := createJob("app", "lib", "1.1.0")
+	job.ID = 1
+	job.State = models.InProgress
+	job.Lease = &models.Lease{Worker: "worker-1"}
+	dao := &mockJobDAO{records: []*models.Job{job}}
+
+	prService.dao = dao
+	s := NewJobService(dao, prService.repDao)
+	s.EnablePullRequests(prService)
+
+	completed, err := s.Complete(new(MockRequestScope), "app", "worker-1", nil)
+	if assert.Nil(t, err) && assert.NotNil(t, completed.PullRequest) {
+		assert.Equal(t, vc.pullRequests[0].URL, completed.PullRequest.URL)
+		assert.Equal(t, vcs.StateOpen, completed.PullRequest.State)
+		assert.Equal(t, completed.PullRequest, dao.records[0].PullRequest)
+	}
+
+	// a pull request that cannot be opened is recorded as failed
+	job = createJob("missing", "lib", "1.1.0")
+	job.ID = 2
+	job.State = models.InProgress
+	job.Lease = &models.Lease{Worker: "worker-1"}
+	dao.records = append(dao.records, job)
+
+	completed, err = s.Complete(new(MockRequestScope), "missing", "worker-1", nil)
+	if assert.Nil(t, err) && assert.NotNil(t, completed.PullRequest) {
+		assert.Equal(t, models.Succeeded, completed.State)
+		assert.Equal(t, PullRequestFailed, completed.PullRequest.State)
+		assert.Equal(t, "not found", completed.PullRequest.Error)
+	}
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/quantumew/data-access/models"
+)
+
+func init() {
+	RegisterEcosystem(pypiEcosystem{})
+}
+
+// EcosystemPyPI is the tag of the Python Package Index ecosystem.
+const EcosystemPyPI = "pypi"
+
+// pypiEcosystem is the Python Package Index ecosystem. Versions are PEP 440 versions and ranges are PEP 440 version
+// specifiers, e.g. "~=1.4" or ">=1.0, <2.0".
+type pypiEcosystem struct{}
+
+func (pypiEcosystem) Name() string {
+	return EcosystemPyPI
+}
+
+// ParseHook reads a generic hook delivery. Published versions are normalized, so "1.0-alpha1" is published as "1.0a1".
+func (pypiEcosystem) ParseHook(payload []byte) ([]*models.PublishedDependency, error) {
+	pubList, err := ParseGenericHook(EcosystemPyPI, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, pub := range pubList {
+		version, err := ParsePep440Version(pub.Version)
+		if err != nil {
+			return nil, validation.Errors{fmt.Sprintf("payload[%d]", i): err}
+		}
+
+		pub.Version = version.String()
+	}
+
+	return pubList, nil
+}
+
+func (pypiEcosystem) ParseVersion(version string) (Version, error) {
+	v, err := ParsePep440Version(version)
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func (pypiEcosystem) ParseRange(rangeStr string) (VersionRange, error) {
+	s, err := ParsePep440Specifier(rangeStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
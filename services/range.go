@@ -0,0 +1,400 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver"
+)
+
+// Range is a parsed npm version range. It is a union of comparator sets, a version satisfies the range when it
+// satisfies every comparator of at least one set.
+type Range struct {
+	setList [][]comparator
+}
+
+// comparator is a single primitive comparison such as ">=1.2.3". An empty operator matches any version.
+type comparator struct {
+	operator string
+	version  semver.Version
+}
+
+// partial is a possibly incomplete version as written in a range, e.g. "1", "1.2.x" or "1.2.3-beta".
+// Wildcard and missing parts are empty.
+type partial struct {
+	major, minor, patch string
+	pre                 string
+}
+
+var (
+	orSplitRegexp    = regexp.MustCompile(`\s*\|\|\s*`)
+	hyphenRegexp     = regexp.MustCompile(`^(\S+)\s+-\s+(\S+)$`)
+	operatorRegexp   = regexp.MustCompile(`(~>?|\^|[<>]=?|==?)\s+`)
+	comparatorRegexp = regexp.MustCompile(`^(~>?|\^|[<>]=?|==?)?(.*)$`)
+	partialRegexp    = regexp.MustCompile(`^[v=\s]*(\d+|[xX*])(?:\.(\d+|[xX*])(?:\.(\d+|[xX*])(?:-?([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*)?)?)?$`)
+	anyComparator    = comparator{}
+	noneComparator   = comparator{operator: "<", version: semver.Version{Pre: []semver.PRVersion{{VersionNum: 0, IsNum: true}}}}
+)
+
+// ParseVersion parses a published version the way npm does in loose mode, allowing a leading "v" or "=".
+func ParseVersion(version string) (semver.Version, error) {
+	p, err := parsePartial(strings.TrimSpace(version))
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	if p.isWildcard(p.patch) {
+		return semver.Version{}, fmt.Errorf("Version %q is incomplete", version)
+	}
+
+	return p.version(0, 0, 0, p.pre)
+}
+
+// Satisfies returns whether the version satisfies the npm range.
+func Satisfies(version string, rangeStr string) (bool, error) {
+	v, err := ParseVersion(version)
+	if err != nil {
+		return false, err
+	}
+
+	r, err := ParseRange(rangeStr)
+	if err != nil {
+		return false, err
+	}
+
+	return r.Test(v), nil
+}
+
+// ParseRange parses an npm range such as "^1.2.0", "~1.2.x", ">=1.0.0 <2.0.0", "1.x || 2.x" or "1.0.0 - 2.0.0".
+func ParseRange(rangeStr string) (Range, error) {
+	var r Range
+
+	for _, setStr := range orSplitRegexp.Split(strings.TrimSpace(rangeStr), -1) {
+		set, err := parseComparatorSet(setStr)
+		if err != nil {
+			return r, fmt.Errorf("Invalid range %q: %s", rangeStr, err)
+		}
+
+		r.setList = append(r.setList, set)
+	}
+
+	return r, nil
+}
+
+// Test returns whether the version satisfies the range.
+func (r Range) Test(v semver.Version) bool {
+	for _, set := range r.setList {
+		if testSet(set, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func testSet(set []comparator, v semver.Version) bool {
+	for _, c := range set {
+		if !c.test(v) {
+			return false
+		}
+	}
+
+	if len(v.Pre) == 0 {
+		return true
+	}
+
+	// A prerelease only satisfies a range when one of the comparators opts into prereleases of the same
+	// major.minor.patch tuple, so "^1.2.3-beta.1" allows "1.2.3-beta.2" but never "1.2.4-beta.1".
+	for _, c := range set {
+		if c.operator == "" || len(c.version.Pre) == 0 {
+			continue
+		}
+
+		if c.version.Major == v.Major && c.version.Minor == v.Minor && c.version.Patch == v.Patch {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c comparator) test(v semver.Version) bool {
+	switch c.operator {
+	case "":
+		return true
+	case "<":
+		return v.LT(c.version)
+	case "<=":
+		return v.LTE(c.version)
+	case ">":
+		return v.GT(c.version)
+	case ">=":
+		return v.GTE(c.version)
+	default:
+		return v.EQ(c.version)
+	}
+}
+
+func parseComparatorSet(setStr string) ([]comparator, error) {
+	setStr = strings.TrimSpace(setStr)
+
+	if match := hyphenRegexp.FindStringSubmatch(setStr); match != nil {
+		return parseHyphen(match[1], match[2])
+	}
+
+	setStr = operatorRegexp.ReplaceAllString(setStr, "$1")
+
+	if setStr == "" {
+		return []comparator{anyComparator}, nil
+	}
+
+	var set []comparator
+
+	for _, token := range strings.Fields(setStr) {
+		comparatorList, err := parseComparator(token)
+		if err != nil {
+			return nil, err
+		}
+
+		set = append(set, comparatorList...)
+	}
+
+	return set, nil
+}
+
+// parseComparator expands a single token such as "^1.2.3" or "<=1.x" into primitive comparators.
+func parseComparator(token string) ([]comparator, error) {
+	match := comparatorRegexp.FindStringSubmatch(token)
+	operator := match[1]
+
+	p, err := parsePartial(match[2])
+	if err != nil {
+		return nil, err
+	}
+
+	switch operator {
+	case "~", "~>":
+		return p.tilde()
+	case "^":
+		return p.caret()
+	case "==":
+		operator = "="
+	}
+
+	return p.xRange(operator)
+}
+
+func parseHyphen(fromStr string, toStr string) ([]comparator, error) {
+	var set []comparator
+
+	from, err := parsePartial(fromStr)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := parsePartial(toStr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case from.isWildcard(from.major):
+	case from.isWildcard(from.minor):
+		set, err = from.appendComparator(set, ">=", 0, 0, 0, "")
+	case from.isWildcard(from.patch):
+		set, err = from.appendComparator(set, ">=", 0, 0, 0, "")
+	default:
+		set, err = from.appendComparator(set, ">=", 0, 0, 0, from.pre)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case to.isWildcard(to.major):
+	case to.isWildcard(to.minor):
+		set, err = to.appendUpperBound(set, 1, 0)
+	case to.isWildcard(to.patch):
+		set, err = to.appendUpperBound(set, 0, 1)
+	default:
+		set, err = to.appendComparator(set, "<=", 0, 0, 0, to.pre)
+	}
+
+	if len(set) == 0 {
+		set = append(set, anyComparator)
+	}
+
+	return set, err
+}
+
+func parsePartial(str string) (partial, error) {
+	match := partialRegexp.FindStringSubmatch(str)
+	if match == nil {
+		return partial{}, fmt.Errorf("Invalid version %q", str)
+	}
+
+	p := partial{pre: match[4]}
+	wildcard := false
+
+	// Anything after the first wildcard is a wildcard too, "1.x.3" means "1.x".
+	for i, part := range []*string{&p.major, &p.minor, &p.patch} {
+		value := match[i+1]
+		wildcard = wildcard || value == "" || value == "x" || value == "X" || value == "*"
+
+		if !wildcard {
+			*part = value
+		}
+	}
+
+	if wildcard {
+		p.pre = ""
+	}
+
+	return p, nil
+}
+
+func (p partial) isWildcard(part string) bool {
+	return part == ""
+}
+
+// version builds a full version from the partial, incrementing each part by the given amounts.
+// Wildcard parts are treated as zero.
+func (p partial) version(majorInc, minorInc, patchInc uint64, pre string) (semver.Version, error) {
+	var v semver.Version
+
+	for i, part := range []string{p.major, p.minor, p.patch} {
+		if part == "" {
+			continue
+		}
+
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return v, fmt.Errorf("Invalid version number %q: %s", part, err)
+		}
+
+		switch i {
+		case 0:
+			v.Major = n
+		case 1:
+			v.Minor = n
+		case 2:
+			v.Patch = n
+		}
+	}
+
+	v.Major += majorInc
+	v.Minor += minorInc
+	v.Patch += patchInc
+
+	if majorInc > 0 {
+		v.Minor, v.Patch = 0, 0
+	} else if minorInc > 0 {
+		v.Patch = 0
+	}
+
+	if pre == "" {
+		return v, nil
+	}
+
+	for _, part := range strings.Split(pre, ".") {
+		prVersion, err := semver.NewPRVersion(part)
+		if err != nil {
+			return v, err
+		}
+
+		v.Pre = append(v.Pre, prVersion)
+	}
+
+	return v, nil
+}
+
+func (p partial) appendComparator(set []comparator, operator string, majorInc, minorInc, patchInc uint64, pre string) ([]comparator, error) {
+	v, err := p.version(majorInc, minorInc, patchInc, pre)
+	if err != nil {
+		return set, err
+	}
+
+	return append(set, comparator{operator: operator, version: v}), nil
+}
+
+// appendUpperBound appends an exclusive upper bound that also excludes prereleases of the bound itself.
+func (p partial) appendUpperBound(set []comparator, majorInc, minorInc uint64) ([]comparator, error) {
+	return p.appendComparator(set, "<", majorInc, minorInc, 0, "0")
+}
+
+// between returns the comparators ">=lower <upper" where lower is the partial itself.
+func (p partial) between(majorInc, minorInc, patchInc uint64) ([]comparator, error) {
+	set, err := p.appendComparator(nil, ">=", 0, 0, 0, p.pre)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.appendComparator(set, "<", majorInc, minorInc, patchInc, "0")
+}
+
+// tilde allows patch level changes when a minor version is specified and minor level changes when it is not.
+func (p partial) tilde() ([]comparator, error) {
+	switch {
+	case p.isWildcard(p.major):
+		return []comparator{anyComparator}, nil
+	case p.isWildcard(p.minor):
+		return p.between(1, 0, 0)
+	default:
+		return p.between(0, 1, 0)
+	}
+}
+
+// caret allows changes that do not modify the left-most non-zero part of the version.
+func (p partial) caret() ([]comparator, error) {
+	switch {
+	case p.isWildcard(p.major):
+		return []comparator{anyComparator}, nil
+	case p.isWildcard(p.minor):
+		return p.between(1, 0, 0)
+	case p.major != "0":
+		return p.between(1, 0, 0)
+	case p.isWildcard(p.patch) || p.minor != "0":
+		return p.between(0, 1, 0)
+	default:
+		return p.between(0, 0, 1)
+	}
+}
+
+// xRange handles plain and operator comparators, any of which may contain wildcards.
+func (p partial) xRange(operator string) ([]comparator, error) {
+	if operator == "" {
+		operator = "="
+	}
+
+	switch {
+	case p.isWildcard(p.major):
+		if operator == "<" || operator == ">" {
+			return []comparator{noneComparator}, nil
+		}
+
+		return []comparator{anyComparator}, nil
+	case !p.isWildcard(p.patch):
+		return p.appendComparator(nil, operator, 0, 0, 0, p.pre)
+	}
+
+	var majorInc, minorInc uint64 = 0, 1
+	if p.isWildcard(p.minor) {
+		majorInc, minorInc = 1, 0
+	}
+
+	switch operator {
+	case ">":
+		return p.appendComparator(nil, ">=", majorInc, minorInc, 0, "")
+	case ">=":
+		return p.appendComparator(nil, ">=", 0, 0, 0, "")
+	case "<":
+		return p.appendUpperBound(nil, 0, 0)
+	case "<=":
+		return p.appendUpperBound(nil, majorInc, minorInc)
+	default:
+		return p.between(majorInc, minorInc, 0)
+	}
+}
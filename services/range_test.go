@@ -0,0 +1,261 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Fixtures taken from node-semver test/fixtures/range-include.js and range-exclude.js, loose mode.
+// Entries that need the includePrerelease option are left out since npm does not use it when installing.
+func TestSatisfies_Include(t *testing.T) {
+	tests := []struct {
+		rangeStr, version string
+	}{
+		{"1.0.0 - 2.0.0", "1.2.3"},
+		{"^1.2.3+build", "1.2.3"},
+		{"^1.2.3+build", "1.3.0"},
+		{"1.2.3-pre+asdf - 2.4.3-pre+asdf", "1.2.3"},
+		{"1.2.3pre+asdf - 2.4.3-pre+asdf", "1.2.3"},
+		{"1.2.3-pre+asdf - 2.4.3pre+asdf", "1.2.3"},
+		{"1.2.3-pre+asdf - 2.4.3-pre+asdf", "1.2.3-pre.2"},
+		{"1.2.3-pre+asdf - 2.4.3-pre+asdf", "2.4.3-alpha"},
+		{"1.2.3+asdf - 2.4.3+asdf", "1.2.3"},
+		{"1.0.0", "1.0.0"},
+		{">=*", "0.2.4"},
+		{"", "1.0.0"},
+		{"*", "1.2.3"},
+		{"*", "v1.2.3"},
+		{">=1.0.0", "1.0.0"},
+		{">=1.0.0", "1.0.1"},
+		{">=1.0.0", "1.1.0"},
+		{">1.0.0", "1.0.1"},
+		{">1.0.0", "1.1.0"},
+		{"<=2.0.0", "2.0.0"},
+		{"<=2.0.0", "1.9999.9999"},
+		{"<=2.0.0", "0.2.9"},
+		{"<2.0.0", "1.9999.9999"},
+		{"<2.0.0", "0.2.9"},
+		{">= 1.0.0", "1.0.0"},
+		{">=  1.0.0", "1.0.1"},
+		{">=   1.0.0", "1.1.0"},
+		{"> 1.0.0", "1.0.1"},
+		{">  1.0.0", "1.1.0"},
+		{"<=   2.0.0", "2.0.0"},
+		{"<= 2.0.0", "1.9999.9999"},
+		{"<=  2.0.0", "0.2.9"},
+		{"<    2.0.0", "1.9999.9999"},
+		{"<\t2.0.0", "0.2.9"},
+		{">=0.1.97", "v0.1.97"},
+		{">=0.1.97", "0.1.97"},
+		{"0.1.20 || 1.2.4", "1.2.4"},
+		{">=0.2.3 || <0.0.1", "0.0.0"},
+		{">=0.2.3 || <0.0.1", "0.2.3"},
+		{">=0.2.3 || <0.0.1", "0.2.4"},
+		{"||", "1.3.4"},
+		{"2.x.x", "2.1.3"},
+		{"1.2.x", "1.2.3"},
+		{"1.2.x || 2.x", "2.1.3"},
+		{"1.2.x || 2.x", "1.2.3"},
+		{"x", "1.2.3"},
+		{"2.*.*", "2.1.3"},
+		{"1.2.*", "1.2.3"},
+		{"1.2.* || 2.*", "2.1.3"},
+		{"1.2.* || 2.*", "1.2.3"},
+		{"2", "2.1.2"},
+		{"2.3", "2.3.1"},
+		{"~0.0.1", "0.0.1"},
+		{"~0.0.1", "0.0.2"},
+		{"~x", "0.0.9"},
+		{"~2", "2.0.9"},
+		{"~2.4", "2.4.0"},
+		{"~2.4", "2.4.5"},
+		{"~>3.2.1", "3.2.2"},
+		{"~1", "1.2.3"},
+		{"~>1", "1.2.3"},
+		{"~> 1", "1.2.3"},
+		{"~1.0", "1.0.2"},
+		{"~ 1.0", "1.0.2"},
+		{"~ 1.0.3", "1.0.12"},
+		{"~ 1.0.3alpha", "1.0.12"},
+		{">=1", "1.0.0"},
+		{">= 1", "1.0.0"},
+		{"<1.2", "1.1.1"},
+		{"< 1.2", "1.1.1"},
+		{"~v0.5.4-pre", "0.5.5"},
+		{"~v0.5.4-pre", "0.5.4"},
+		{"=0.7.x", "0.7.2"},
+		{"<=0.7.x", "0.7.2"},
+		{">=0.7.x", "0.7.2"},
+		{"<=0.7.x", "0.6.2"},
+		{"~1.2.1 >=1.2.3", "1.2.3"},
+		{"~1.2.1 =1.2.3", "1.2.3"},
+		{"~1.2.1 1.2.3", "1.2.3"},
+		{"~1.2.1 >=1.2.3 1.2.3", "1.2.3"},
+		{"~1.2.1 1.2.3 >=1.2.3", "1.2.3"},
+		{">=1.2.1 1.2.3", "1.2.3"},
+		{"1.2.3 >=1.2.1", "1.2.3"},
+		{">=1.2.3 >=1.2.1", "1.2.3"},
+		{">=1.2.1 >=1.2.3", "1.2.3"},
+		{">=1.2", "1.2.8"},
+		{"^1.2.3", "1.8.1"},
+		{"^0.1.2", "0.1.2"},
+		{"^0.1", "0.1.2"},
+		{"^0.0.1", "0.0.1"},
+		{"^1.2", "1.4.2"},
+		{"^1.2 ^1", "1.4.2"},
+		{"^1.2.3-alpha", "1.2.3-pre"},
+		{"^1.2.0-alpha", "1.2.0-pre"},
+		{"^0.0.1-alpha", "0.0.1-beta"},
+		{"^0.0.1-alpha", "0.0.1"},
+		{"^0.1.1-alpha", "0.1.1-beta"},
+		{"^x", "1.2.3"},
+		{"x - 1.0.0", "0.9.7"},
+		{"x - 1.x", "0.9.7"},
+		{"1.0.0 - x", "1.9.7"},
+		{"1.x - x", "1.9.7"},
+		{"<=7.x", "7.9.9"},
+	}
+
+	for _, test := range tests {
+		ok, err := Satisfies(test.version, test.rangeStr)
+		assert.Nil(t, err, test.rangeStr)
+		assert.True(t, ok, "%q should include %s", test.rangeStr, test.version)
+	}
+}
+
+func TestSatisfies_Exclude(t *testing.T) {
+	tests := []struct {
+		rangeStr, version string
+	}{
+		{"1.0.0 - 2.0.0", "2.2.3"},
+		{"1.2.3+asdf - 2.4.3+asdf", "1.2.3-pre.2"},
+		{"1.2.3+asdf - 2.4.3+asdf", "2.4.3-alpha"},
+		{"^1.2.3+build", "2.0.0"},
+		{"^1.2.3+build", "1.2.0"},
+		{"^1.2.3", "1.2.3-pre"},
+		{"^1.2", "1.2.0-pre"},
+		{">1.2", "1.3.0-beta"},
+		{"<=1.2.3", "1.2.3-beta"},
+		{"^1.2.3", "1.2.3-beta"},
+		{"=0.7.x", "0.7.0-asdf"},
+		{">=0.7.x", "0.7.0-asdf"},
+		{"<=0.7.x", "0.7.0-asdf"},
+		{"1", "1.0.0beta"},
+		{"<1", "1.0.0beta"},
+		{"< 1", "1.0.0beta"},
+		{"1.0.0", "1.0.1"},
+		{">=1.0.0", "0.0.0"},
+		{">=1.0.0", "0.0.1"},
+		{">=1.0.0", "0.1.0"},
+		{">1.0.0", "0.0.1"},
+		{">1.0.0", "0.1.0"},
+		{"<=2.0.0", "3.0.0"},
+		{"<=2.0.0", "2.9999.9999"},
+		{"<=2.0.0", "2.2.9"},
+		{"<2.0.0", "2.9999.9999"},
+		{"<2.0.0", "2.2.9"},
+		{">=0.1.97", "v0.1.93"},
+		{">=0.1.97", "0.1.93"},
+		{"0.1.20 || 1.2.4", "1.2.3"},
+		{">=0.2.3 || <0.0.1", "0.0.3"},
+		{">=0.2.3 || <0.0.1", "0.2.2"},
+		{"2.x.x", "1.1.3"},
+		{"2.x.x", "3.1.3"},
+		{"1.2.x", "1.3.3"},
+		{"1.2.x || 2.x", "3.1.3"},
+		{"1.2.x || 2.x", "1.1.3"},
+		{"2.*.*", "1.1.3"},
+		{"2.*.*", "3.1.3"},
+		{"1.2.*", "1.3.3"},
+		{"1.2.* || 2.*", "3.1.3"},
+		{"1.2.* || 2.*", "1.1.3"},
+		{"2", "1.1.2"},
+		{"2.3", "2.4.1"},
+		{"~0.0.1", "0.1.0-alpha"},
+		{"~0.0.1", "0.1.0"},
+		{"~2.4", "2.5.0"},
+		{"~2.4", "2.3.9"},
+		{"~>3.2.1", "3.3.2"},
+		{"~>3.2.1", "3.2.0"},
+		{"~1", "0.2.3"},
+		{"~>1", "2.2.3"},
+		{"~1.0", "1.1.0"},
+		{"<1", "1.0.0"},
+		{">=1.2", "1.1.1"},
+		{"1", "2.0.0beta"},
+		{"~v0.5.4-beta", "0.5.4-alpha"},
+		{"=0.7.x", "0.8.2"},
+		{">=0.7.x", "0.6.2"},
+		{"<0.7.x", "0.7.2"},
+		{"<1.2.3", "1.2.3-beta"},
+		{"=1.2.3", "1.2.3-beta"},
+		{">1.2", "1.2.8"},
+		{"^0.0.1", "0.0.2-alpha"},
+		{"^0.0.1", "0.0.2"},
+		{"^1.2.3", "2.0.0-alpha"},
+		{"^1.2.3", "1.2.2"},
+		{"^1.2", "1.1.9"},
+		{"*", "v1.2.3-foo"},
+		{"2.x", "3.0.0-pre.0"},
+		{"^1.0.0", "1.0.0-rc1"},
+		{"^1.0.0", "2.0.0-rc1"},
+		{"^1.2.3-rc2", "2.0.0"},
+		{"1 - 2", "3.0.0-pre"},
+		{"1 - 2", "2.0.0-pre"},
+		{"1 - 2", "1.0.0-pre"},
+		{"1.0 - 2", "1.0.0-pre"},
+		{"1.1.x", "1.0.0-a"},
+		{"1.1.x", "1.1.0-a"},
+		{"1.1.x", "1.2.0-a"},
+		{"1.x", "1.0.0-a"},
+		{"1.x", "1.1.0-a"},
+		{"1.x", "1.2.0-a"},
+		{">=1.0.0 <1.1.0", "1.1.0"},
+		{">=1.0.0 <1.1.0", "1.1.0-pre"},
+		{">=1.0.0 <1.1.0-pre", "1.1.0-pre"},
+	}
+
+	for _, test := range tests {
+		ok, err := Satisfies(test.version, test.rangeStr)
+		assert.Nil(t, err, test.rangeStr)
+		assert.False(t, ok, "%q should exclude %s", test.rangeStr, test.version)
+	}
+}
+
+func TestSatisfies_Invalid(t *testing.T) {
+	tests := []struct {
+		rangeStr, version string
+	}{
+		{"*", "not a version"},
+		{">=2", "glorp"},
+		{">=2", "1.2"},
+		{"== 1.0.0 || foo", "2.0.0"},
+		{"1.2.3 - ", "1.2.3"},
+		{">=a.b.c", "1.2.3"},
+	}
+
+	for _, test := range tests {
+		ok, err := Satisfies(test.version, test.rangeStr)
+		assert.NotNil(t, err, test.rangeStr)
+		assert.False(t, ok, test.rangeStr)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	v, err := ParseVersion("v1.2.3-beta.4+build.5")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "1.2.3-beta.4", v.String())
+	}
+
+	v, err = ParseVersion("=1.0.0beta")
+	if assert.Nil(t, err) {
+		assert.Equal(t, "1.0.0-beta", v.String())
+	}
+
+	_, err = ParseVersion("1.2.x")
+	assert.NotNil(t, err)
+
+	_, err = ParseVersion("99999999999999999999.0.0")
+	assert.NotNil(t, err)
+}
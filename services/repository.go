@@ -2,6 +2,9 @@ package services
 
 import (
 	"fmt"
+	"time"
+
+	"github.com/go-ozzo/ozzo-validation"
 	"github.com/quantumew/data-access"
 	"github.com/quantumew/data-access/models"
 	"github.com/quantumew/listener/app"
@@ -24,7 +27,7 @@ func (s *RepositoryService) Get(rs app.RequestScope, name string) (*models.Repos
 
 // Create creates a new repository.
 func (s *RepositoryService) Create(rs app.RequestScope, model *models.Repository) (*models.Repository, error) {
-	if err := model.Validate(); err != nil {
+	if err := validateRepository(model); err != nil {
 		return nil, err
 	}
 	if err := s.dao.Create(rs.DB(), model); err != nil {
@@ -35,7 +38,7 @@ func (s *RepositoryService) Create(rs app.RequestScope, model *models.Repository
 
 // Update updates the repository with the specified name.
 func (s *RepositoryService) Update(rs app.RequestScope, name string, model *models.Repository) (*models.Repository, error) {
-	if err := model.Validate(); err != nil {
+	if err := validateRepository(model); err != nil {
 		return nil, err
 	}
 	if err := s.dao.Update(rs.DB(), name, model); err != nil {
@@ -47,7 +50,7 @@ func (s *RepositoryService) Update(rs app.RequestScope, name string, model *mode
 // Patch bulk update of repositories
 func (s *RepositoryService) Patch(rs app.RequestScope, repoList []*models.Repository) ([]*models.Repository, error) {
 	for _, model := range repoList {
-		if err := model.Validate(); err != nil {
+		if err := validateRepository(model); err != nil {
 			return nil, err
 		}
 	}
@@ -85,3 +88,56 @@ func (s *RepositoryService) Count(rs app.RequestScope) (int64, error) {
 func (s *RepositoryService) Query(rs app.RequestScope, offset, limit int) ([]*models.Repository, error) {
 	return s.dao.Query(rs.DB(), offset, limit)
 }
+
+// validateRepository validates a repository along with the settings only the listener understands.
+func validateRepository(model *models.Repository) error {
+	if err := model.Validate(); err != nil {
+		return err
+	}
+
+	if model.Config.Debounce != "" {
+		if _, err := time.ParseDuration(model.Config.Debounce); err != nil {
+			return validation.Errors{"config.debounce": err}
+		}
+	}
+
+	if err := CheckPolicyPatterns(model.Policy); err != nil {
+		return validation.Errors{"policy": err}
+	}
+
+	ecosystem, err := LookupEcosystem(model.Ecosystem)
+	if err != nil {
+		return validation.Errors{"ecosystem": err}
+	}
+
+	for i, name := range model.Packages {
+		if err := CheckPackageName(ecosystem, name); err != nil {
+			return validation.Errors{fmt.Sprintf("packages[%d]", i): err}
+		}
+	}
+
+	for i, dep := range model.Dependencies {
+		depEcosystem, err := LookupEcosystem(DependencyEcosystem(model, dep))
+		if err != nil {
+			return validation.Errors{fmt.Sprintf("dependencies[%d].ecosystem", i): err}
+		}
+
+		if err := CheckPackageName(depEcosystem, dep.Name); err != nil {
+			return validation.Errors{fmt.Sprintf("dependencies[%d].name", i): err}
+		}
+
+		if dep.Policy != nil {
+			if err := CheckPolicyPatterns(*dep.Policy); err != nil {
+				return validation.Errors{fmt.Sprintf("dependencies[%d].policy", i): err}
+			}
+		}
+	}
+
+	for i, mapping := range model.Projects {
+		if err := validation.Validate(mapping.Project, validation.Required); err != nil {
+			return validation.Errors{fmt.Sprintf("projects[%d].project", i): err}
+		}
+	}
+
+	return nil
+}
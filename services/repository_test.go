@@ -18,12 +18,12 @@ func TestNewRepositoryService(t *testing.T) {
 
 func TestRepositoryService_Get(t *testing.T) {
 	s := NewRepositoryService(newMockRepositoryDAO())
-	repository, err := s.Get(new(MockRequestScope), 1)
+	repository, err := s.Get(new(MockRequestScope), "aaa")
 	if assert.Nil(t, err) && assert.NotNil(t, repository) {
-		assert.Equal(t, "aaa", repository.Name)
+		assert.Equal(t, int64(1), repository.ID)
 	}
 
-	repository, err = s.Get(new(MockRequestScope), 100)
+	repository, err = s.Get(new(MockRequestScope), "zzz")
 	assert.NotNil(t, err)
 }
 
@@ -47,24 +47,42 @@ func TestRepositoryService_Create(t *testing.T) {
 		Name: "",
 	})
 	assert.NotNil(t, err)
+
+	// invalid debounce window
+	repository = createRepository("eee", "testing", "1.1.1", "1.2.3")
+	repository.Config.Debounce = "soon"
+	_, err = s.Create(new(MockRequestScope), repository)
+	assert.NotNil(t, err)
+
+	// project mapping without a project
+	repository = createRepository("fff", "testing", "1.1.1", "1.2.3")
+	repository.Projects = []models.ProjectMapping{{Packages: []string{"testing"}}}
+	_, err = s.Create(new(MockRequestScope), repository)
+	assert.NotNil(t, err)
+
+	// malformed policy pattern
+	repository = createRepository("ggg", "testing", "1.1.1", "1.2.3")
+	repository.Dependencies[0].Policy = &models.UpdatePolicy{Deny: []string{"1.[2"}}
+	_, err = s.Create(new(MockRequestScope), repository)
+	assert.NotNil(t, err)
 }
 
 func TestRepositoryService_Update(t *testing.T) {
 	s := NewRepositoryService(newMockRepositoryDAO())
-	repository, err := s.Update(new(MockRequestScope), 2, createRepository("ddd", "a", "1.2.4", "1.2.3"))
+	repository, err := s.Update(new(MockRequestScope), "bbb", createRepository("bbb", "a", "1.2.4", "1.2.3"))
 	if assert.Nil(t, err) && assert.NotNil(t, repository) {
 		assert.Equal(t, int64(2), repository.ID)
-		assert.Equal(t, "ddd", repository.Name)
+		assert.Equal(t, "a", repository.Dependencies[0].Name)
 	}
 
 	// dao error
-	_, err = s.Update(new(MockRequestScope), 100, &models.Repository{
+	_, err = s.Update(new(MockRequestScope), "zzz", &models.Repository{
 		Name: "ddd",
 	})
 	assert.NotNil(t, err)
 
 	// validation error
-	_, err = s.Update(new(MockRequestScope), 2, &models.Repository{
+	_, err = s.Update(new(MockRequestScope), "bbb", &models.Repository{
 		Name: "",
 	})
 	assert.NotNil(t, err)
@@ -72,13 +90,13 @@ func TestRepositoryService_Update(t *testing.T) {
 
 func TestRepositoryService_Delete(t *testing.T) {
 	s := NewRepositoryService(newMockRepositoryDAO())
-	repository, err := s.Delete(new(MockRequestScope), 2)
+	repository, err := s.Delete(new(MockRequestScope), "bbb")
 	if assert.Nil(t, err) && assert.NotNil(t, repository) {
 		assert.Equal(t, int64(2), repository.ID)
 		assert.Equal(t, "bbb", repository.Name)
 	}
 
-	_, err = s.Delete(new(MockRequestScope), 2)
+	_, err = s.Delete(new(MockRequestScope), "bbb")
 	assert.NotNil(t, err)
 }
 
@@ -117,9 +135,9 @@ type mockRepositoryDAO struct {
 	records []*models.Repository
 }
 
-func (m *mockRepositoryDAO) Get(db *mongo.Database, id int64) (*models.Repository, error) {
+func (m *mockRepositoryDAO) Get(db *mongo.Database, name string) (*models.Repository, error) {
 	for _, record := range m.records {
-		if record.ID == id {
+		if record.Name == name {
 			return record, nil
 		}
 	}
@@ -131,7 +149,26 @@ func (m *mockRepositoryDAO) Query(db *mongo.Database, offset, limit int) ([]*mod
 }
 
 func (m *mockRepositoryDAO) QueryByDependency(db *mongo.Database, dependencyName string) ([]*models.Repository, error) {
-	return []*models.Repository{}, nil
+	var repositoryList []*models.Repository
+	for _, record := range m.records {
+		for _, dep := range record.Dependencies {
+			if dep.Name == dependencyName {
+				repositoryList = append(repositoryList, record)
+				break
+			}
+		}
+	}
+	return repositoryList, nil
+}
+
+func (m *mockRepositoryDAO) QueryByName(db *mongo.Database, nameList []string) ([]*models.Repository, error) {
+	var repositoryList []*models.Repository
+	for _, name := range nameList {
+		if record, err := m.Get(db, name); err == nil {
+			repositoryList = append(repositoryList, record)
+		}
+	}
+	return repositoryList, nil
 }
 
 func (m *mockRepositoryDAO) Count(db *mongo.Database) (int64, error) {
@@ -148,10 +185,10 @@ func (m *mockRepositoryDAO) Create(db *mongo.Database, repository *models.Reposi
 	return nil
 }
 
-func (m *mockRepositoryDAO) Update(db *mongo.Database, id int64, repository *models.Repository) error {
-	repository.ID = id
+func (m *mockRepositoryDAO) Update(db *mongo.Database, name string, repository *models.Repository) error {
 	for i, record := range m.records {
-		if record.ID == id {
+		if record.Name == name {
+			repository.ID = record.ID
 			m.records[i] = repository
 			return nil
 		}
@@ -159,9 +196,19 @@ func (m *mockRepositoryDAO) Update(db *mongo.Database, id int64, repository *mod
 	return errors.New("not found")
 }
 
-func (m *mockRepositoryDAO) Delete(db *mongo.Database, id int64) error {
+func (m *mockRepositoryDAO) Patch(db *mongo.Database, repoList []*models.Repository) []error {
+	var errList []error
+	for _, repository := range repoList {
+		if err := m.Update(db, repository.Name, repository); err != nil {
+			errList = append(errList, err)
+		}
+	}
+	return errList
+}
+
+func (m *mockRepositoryDAO) Delete(db *mongo.Database, name string) error {
 	for i, record := range m.records {
-		if record.ID == id {
+		if record.Name == name {
 			m.records = append(m.records[:i], m.records[i+1:]...)
 			return nil
 		}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/errors"
+)
+
+// random returns a number in [0, 1) used to spread out retries, replaced in tests.
+var random = rand.Float64
+
+// Backoff returns how long to wait before the given attempt, counting from one. The delay doubles with every attempt
+// up to the configured maximum, and up to the jitter fraction of it is taken off at random so that jobs failing
+// together are not all retried together.
+func Backoff(attempt int) time.Duration {
+	config := app.Config.Retry
+	delay := float64(config.BaseDelay) * math.Pow(2, float64(attempt-1))
+
+	if config.MaxDelay > 0 && delay > float64(config.MaxDelay) {
+		delay = float64(config.MaxDelay)
+	}
+
+	return time.Duration(delay * (1 - config.Jitter*random()))
+}
+
+// QueryDeadLetters returns the jobs that ran out of attempts with the specified offset and limit.
+func (s *JobService) QueryDeadLetters(rs app.RequestScope, offset, limit int) ([]*models.Job, error) {
+	return s.dao.QueryByState(rs.DB(), models.DeadLetter, offset, limit)
+}
+
+// CountDeadLetters returns the number of jobs that ran out of attempts.
+func (s *JobService) CountDeadLetters(rs app.RequestScope) (int64, error) {
+	return s.dao.CountByState(rs.DB(), models.DeadLetter)
+}
+
+// Requeue puts a job that ran out of attempts back in the queue with a fresh set of attempts. The job is addressed by
+// its ID, as a repository can have several dead-lettered jobs.
+func (s *JobService) Requeue(rs app.RequestScope, id int64, actor string) (*models.Job, error) {
+	if err := validation.Validate(actor, validation.Required); err != nil {
+		return nil, validation.Errors{"actor": err}
+	}
+
+	job, err := s.dao.GetByID(rs.DB(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	if job == nil {
+		return nil, errors.NotFound(fmt.Sprintf("job %d", id))
+	}
+
+	if job.State != models.DeadLetter {
+		return nil, errors.Conflict("job "+job.Name, "only dead-lettered jobs can be requeued")
+	}
+
+	if err := s.checkRequeue(rs.DB(), job.Name); err != nil {
+		return nil, err
+	}
+
+	now := rs.Now()
+	transition := &models.Transition{From: models.DeadLetter, To: models.Queued, Actor: actor, Time: now}
+
+	requeued, err := s.dao.RescheduleByID(rs.DB(), id, now, transition)
+	if err != nil {
+		return nil, err
+	}
+
+	if !requeued {
+		return nil, errors.Conflict("job "+job.Name, "only dead-lettered jobs can be requeued")
+	}
+
+	return s.dao.GetByID(rs.DB(), id)
+}
+
+// checkRequeue rejects putting a dead-lettered job of a repository back in the queue while the repository has another
+// unfinished job. The jobs locked behind a dead-lettered job are released, so the requeued job would otherwise run at
+// the same time as the newer one.
+func (s *JobService) checkRequeue(db *mongo.Database, name string) error {
+	busy, err := s.hasJobIn(db, name, unfinishedStateList)
+	if err != nil {
+		return err
+	}
+
+	if busy {
+		return errors.Conflict("job "+name, "the repository has an unfinished job, publish the dependencies again instead")
+	}
+
+	return nil
+}
+
+// retry schedules another attempt of a failed job after a backoff delay, or dead-letters it when it is out of attempts.
+func (s *JobService) retry(db *mongo.Database, name string, now time.Time) (models.State, error) {
+	job, err := s.dao.GetByState(db, name, models.Failed)
+	if err != nil || job == nil {
+		return models.Failed, err
+	}
+
+	failures := countFailures(job)
+
+	if failures >= app.Config.Retry.MaxAttempts {
+		transition := &models.Transition{From: models.Failed, To: models.DeadLetter, Actor: SystemActor, Time: now}
+		if _, err := s.dao.Transition(db, name, transition); err != nil {
+			return models.Failed, err
+		}
+
+		return models.DeadLetter, s.unlock(db, name, now)
+	}
+
+	transition := &models.Transition{From: models.Failed, To: models.Queued, Actor: SystemActor, Time: now}
+	if _, err := s.dao.Reschedule(db, name, now.Add(Backoff(failures)), transition); err != nil {
+		return models.Failed, err
+	}
+
+	return models.Queued, nil
+}
+
+// countFailures returns how many times a job failed since it was last requeued by hand.
+func countFailures(job *models.Job) int {
+	failures := 0
+
+	for _, transition := range job.Transitions {
+		switch {
+		case transition.From == models.DeadLetter:
+			failures = 0
+		case transition.To == models.Failed:
+			failures++
+		}
+	}
+
+	return failures
+}
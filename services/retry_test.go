@@ -0,0 +1,162 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func setRetryConfig(maxAttempts int, jitter float64) func() {
+	config := app.Config.Retry
+	app.Config.Retry.MaxAttempts = maxAttempts
+	app.Config.Retry.BaseDelay = time.Second
+	app.Config.Retry.MaxDelay = 10 * time.Second
+	app.Config.Retry.Jitter = jitter
+
+	return func() {
+		app.Config.Retry = config
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	defer setRetryConfig(3, 0)()
+
+	tests := []struct {
+		attempt int
+		delay   time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.delay, Backoff(test.attempt), "attempt %d", test.attempt)
+	}
+
+	app.Config.Retry.Jitter = 0.5
+	defer func(original func() float64) { random = original }(random)
+	random = func() float64 { return 0.5 }
+
+	assert.Equal(t, 1500*time.Millisecond, Backoff(2))
+}
+
+func TestJobService_Fail_Retry(t *testing.T) {
+	defer setRetryConfig(2, 0)()
+
+	dao := newMockJobDAO().(*mockJobDAO)
+	dao.records[0].State = models.Queued
+	s := NewJobService(dao, newMockRepositoryDAO())
+	rs := new(MockRequestScope)
+
+	job, _ := s.Claim(rs, "worker-1")
+	job, err := s.Fail(rs, job.Name, "worker-1", "boom")
+	if assert.Nil(t, err) {
+		assert.Equal(t, models.Queued, job.State)
+		assert.Equal(t, rs.Now().Add(time.Second), job.NotBefore)
+	}
+
+	// not due yet
+	job, err = s.Claim(rs, "worker-2")
+	assert.Nil(t, err)
+	assert.Nil(t, job)
+
+	dao.records[0].NotBefore = time.Time{}
+	job, _ = s.Claim(rs, "worker-2")
+	job, err = s.Fail(rs, job.Name, "worker-2", "boom again")
+	if assert.Nil(t, err) {
+		assert.Equal(t, models.DeadLetter, job.State)
+		assert.Equal(t, 2, len(job.Attempts))
+		assert.Equal(t, "boom again", job.Attempts[1].Error)
+	}
+
+	count, _ := s.CountDeadLetters(rs)
+	assert.Equal(t, int64(1), count)
+
+	deadList, _ := s.QueryDeadLetters(rs, 0, 10)
+	if assert.Equal(t, 1, len(deadList)) {
+		assert.Equal(t, "aaa", deadList[0].Name)
+	}
+
+	job, err = s.Requeue(rs, deadList[0].ID, "someone")
+	if assert.Nil(t, err) {
+		assert.Equal(t, models.Queued, job.State)
+		assert.Equal(t, 0, countFailures(job))
+	}
+
+	_, err = s.Requeue(rs, deadList[0].ID, "someone")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusConflict, err.(*errors.APIError).Status)
+	}
+}
+
+func TestJobService_Transition_Failed(t *testing.T) {
+	defer setRetryConfig(1, 0)()
+
+	dao := newMockJobDAO().(*mockJobDAO)
+	dao.records[0].State = models.InProgress
+	locked := createJob("aaa", "test", "1.3.0")
+	locked.State = models.Locked
+	locked.BlockedBy = []string{"aaa"}
+	dao.records = append(dao.records, locked)
+	s := NewJobService(dao, newMockRepositoryDAO())
+
+	job, err := s.Transition(new(MockRequestScope), "aaa", 0, models.Failed, "someone")
+	if assert.Nil(t, err) {
+		assert.Equal(t, models.DeadLetter, job.State)
+		assert.Equal(t, models.Idle, locked.State)
+	}
+
+	// the released job is pending, so the dead-lettered one cannot run next to it
+	_, err = s.Requeue(new(MockRequestScope), 1, "someone")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusConflict, err.(*errors.APIError).Status)
+	}
+
+	locked.State = models.Succeeded
+	job, err = s.Requeue(new(MockRequestScope), 1, "someone")
+	if assert.Nil(t, err) {
+		assert.Equal(t, int64(1), job.ID)
+		assert.Equal(t, models.Queued, job.State)
+	}
+}
+
+func TestJobService_Requeue_ByID(t *testing.T) {
+	dao := newMockJobDAO().(*mockJobDAO)
+	dao.records[0].State = models.DeadLetter
+	// the successor released by the first dead letter ran out of attempts too
+	successor := createJob("aaa", "test", "1.3.0")
+	successor.ID = 5
+	successor.State = models.DeadLetter
+	dao.records = append(dao.records, successor)
+	s := NewJobService(dao, newMockRepositoryDAO())
+	rs := new(MockRequestScope)
+
+	job, err := s.Requeue(rs, 5, "someone")
+	if assert.Nil(t, err) {
+		assert.Equal(t, int64(5), job.ID)
+		assert.Equal(t, models.Queued, job.State)
+		assert.Equal(t, models.DeadLetter, dao.records[0].State)
+	}
+
+	_, err = s.Requeue(rs, 1, "someone")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusConflict, err.(*errors.APIError).Status)
+	}
+
+	_, err = s.Requeue(rs, 2, "someone")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusConflict, err.(*errors.APIError).Status, "not dead-lettered")
+	}
+
+	_, err = s.Requeue(rs, 42, "someone")
+	assert.NotNil(t, err)
+}
@@ -1,32 +1,103 @@
 package services
 
 import (
+	"fmt"
+
 	"github.com/quantumew/data-access/models"
-	"github.com/blang/semver"
 )
 
-// FilterByVersion filters out repositories that are out of range of dependency update.
-func FilterByVersion(repList []*models.Repository, hook *models.NpmHook) []*models.Repository {
-	var filteredList []*models.Repository
+// RootWorkspace is how the workspace at the root of a repository is listed among the workspaces a published
+// dependency updates.
+const RootWorkspace = "."
+
+// FilterByVersion filters out repositories whose declared range or update policy does not allow the published version.
+// Repositories that are filtered out are returned along with the reason they were skipped.
+func FilterByVersion(repList []*models.Repository, pub *models.PublishedDependency) ([]*models.Repository, []SkippedRepository) {
+	var (
+		filteredList []*models.Repository
+		skippedList  []SkippedRepository
+	)
 
 	for _, rep := range repList {
-		var desiredVersion semver.Version
-		pub, err := semver.Make(hook.Version)
-
-		for _, dep := range rep.Dependencies {
-			if dep.Name == hook.Name {
-				// Simply grab the first, there should never be two but if there is...
-				desiredVersion, err = semver.Make(dep.Semver)
-
-				// Ensure version is within the range of package version. Perhaps we will have a config to override
-				// that and always update or at least try...
-				if err == nil && pub.LTE(desiredVersion) {
-					filteredList = append(filteredList, rep)
-				}
-				break
-			}
+		if _, reason := MatchWorkspaces(rep, pub); reason == "" {
+			filteredList = append(filteredList, rep)
+		} else {
+			skippedList = append(skippedList, SkippedRepository{rep.Name, reason})
+		}
+	}
+
+	return filteredList, skippedList
+}
+
+// MatchWorkspaces returns the workspaces of a repository whose dependency on the published package should be updated
+// to the published version, or the reason none should be. Every workspace declaring the dependency is checked, a
+// repository that is not split into workspaces only having the root one.
+func MatchWorkspaces(rep *models.Repository, pub *models.PublishedDependency) ([]string, string) {
+	var (
+		workspaceList []string
+		reason        string
+	)
+
+	ecosystemName := normalizeEcosystem(pub.Ecosystem)
+
+	for _, dep := range rep.Dependencies {
+		if dep.Name != pub.Name || DependencyEcosystem(rep, dep) != ecosystemName {
+			continue
+		}
+
+		// Report why the first workspace is skipped when none is updated.
+		if depReason := checkDependency(rep, dep, pub); depReason == "" {
+			workspaceList = append(workspaceList, WorkspacePath(dep))
+		} else if reason == "" {
+			reason = depReason
 		}
 	}
 
-	return filteredList
+	if len(workspaceList) > 0 {
+		return workspaceList, ""
+	}
+
+	if reason == "" {
+		reason = fmt.Sprintf("does not depend on %s", pub.Name)
+	}
+
+	return nil, reason
+}
+
+// WorkspacePath returns the path of the workspace declaring a dependency, relative to the root of the repository.
+func WorkspacePath(dep models.Dependency) string {
+	if dep.Workspace == "" {
+		return RootWorkspace
+	}
+
+	return dep.Workspace
+}
+
+// checkDependency returns the reason a repository's dependency should not be updated to the published version,
+// or an empty string if it should be.
+func checkDependency(rep *models.Repository, dep models.Dependency, pub *models.PublishedDependency) string {
+	ecosystem, err := LookupEcosystem(pub.Ecosystem)
+	if err != nil {
+		return err.Error()
+	}
+
+	version, err := ecosystem.ParseVersion(pub.Version)
+	if err != nil {
+		return fmt.Sprintf("published version is invalid: %s", err)
+	}
+
+	desiredRange, err := ecosystem.ParseRange(dep.Semver)
+	if err != nil {
+		return err.Error()
+	}
+
+	if !desiredRange.Contains(version) {
+		return fmt.Sprintf("%s@%s is outside of the declared range %q", pub.Name, pub.Version, dep.Semver)
+	}
+
+	if err := CheckPolicy(ecosystem, EffectivePolicy(rep, dep), dep.Installed, version); err != nil {
+		return err.Error()
+	}
+
+	return ""
 }
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterByVersion(t *testing.T) {
+	pinned := createRepository("ddd", "test", "^1.0.0", "1.0.0")
+	pinned.Policy = models.UpdatePolicy{Level: PolicyPinned}
+
+	repList := []*models.Repository{
+		createRepository("aaa", "test", "^1.0.0", "1.0.0"),
+		createRepository("bbb", "test", "~2.0.0", "2.0.0"),
+		createRepository("ccc", "test", "not a range", "1.0.0"),
+		pinned,
+		createRepository("eee", "other", "^1.0.0", "1.0.0"),
+	}
+
+	filteredList, skippedList := FilterByVersion(repList, &models.PublishedDependency{Name: "test", Version: "1.4.0"})
+	if assert.Equal(t, 1, len(filteredList)) {
+		assert.Equal(t, "aaa", filteredList[0].Name)
+	}
+
+	if assert.Equal(t, 4, len(skippedList)) {
+		for i, name := range []string{"bbb", "ccc", "ddd", "eee"} {
+			assert.Equal(t, name, skippedList[i].Name)
+			assert.NotEmpty(t, skippedList[i].Reason)
+		}
+	}
+
+	filteredList, skippedList = FilterByVersion(repList, &models.PublishedDependency{Name: "test", Version: "latest"})
+	assert.Empty(t, filteredList)
+	assert.Equal(t, len(repList), len(skippedList))
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/app"
+)
+
+const (
+	// SimulateAppend is the action of a hook adding the published dependency to the pending job of a repository.
+	SimulateAppend = "append"
+	// SimulateCreate is the action of a hook creating a new job for a repository.
+	SimulateCreate = "create"
+)
+
+// SimulatedJob is what a hook would do to the job of a repository.
+type SimulatedJob struct {
+	Repository string `json:"repository"`
+	Action     string `json:"action"`
+	// Job is the job as it would be after the hook. A locked job lists the repositories it would wait for.
+	Job *models.Job `json:"job"`
+}
+
+// SimulationResult is the outcome a hook would have, the jobs it would touch and the repositories it would skip.
+type SimulationResult struct {
+	Jobs    []SimulatedJob      `json:"jobs"`
+	Skipped []SkippedRepository `json:"skipped"`
+}
+
+// Simulate returns what CreateJobsFromHook would do with the hook without writing anything.
+func (s *JobService) Simulate(rs app.RequestScope, hook *models.NpmHook) (*SimulationResult, error) {
+	pub := NpmPublish(hook)
+	targetList, skippedList, err := s.planHook(rs, pub, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SimulationResult{Jobs: []SimulatedJob{}, Skipped: skippedList}
+
+	for _, target := range targetList {
+		job, err := s.simulateDependency(rs, target, target.publish(pub))
+		if err != nil {
+			return nil, err
+		}
+
+		result.Jobs = append(result.Jobs, job)
+	}
+
+	if result.Skipped == nil {
+		result.Skipped = []SkippedRepository{}
+	}
+
+	return result, nil
+}
+
+// simulateDependency returns what queueDependency would do with the published dependency.
+func (s *JobService) simulateDependency(rs app.RequestScope, target hookTarget, dep *models.PublishedDependency) (SimulatedJob, error) {
+	db := rs.DB()
+	simulated := SimulatedJob{Repository: target.rep.Name, Action: SimulateAppend}
+
+	for _, state := range pendingStateList {
+		job, err := s.dao.GetByState(db, target.rep.Name, state)
+		if err != nil {
+			return simulated, err
+		}
+
+		if job != nil && !wasClaimed(job) {
+			preview := *job
+			if !listsDependency(job, dep) {
+				preview.Dependencies = append(append([]*models.PublishedDependency{}, job.Dependencies...), dep)
+			}
+			for _, blocker := range target.blockerList {
+				if !containsString(preview.BlockedBy, blocker) {
+					preview.BlockedBy = append(append([]string{}, preview.BlockedBy...), blocker)
+					preview.State = models.Locked
+				}
+			}
+			simulated.Job = &preview
+
+			return simulated, nil
+		}
+	}
+
+	active, err := s.isBlocking(rs, target.rep.Name, target.rep.Name)
+	if err != nil {
+		return simulated, err
+	}
+
+	simulated.Action = SimulateCreate
+	simulated.Job = newPendingJob(rs, target.rep, dep, target.blockerList, active)
+
+	return simulated, nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobService_Simulate(t *testing.T) {
+	jobDAO := newMockJobDAO().(*mockJobDAO)
+	jobDAO.records[1].State = models.InProgress
+	repDAO := &mockRepositoryDAO{records: []*models.Repository{
+		createRepository("aaa", "lib", "^1.0.0", "1.0.0"),
+		createRepository("bbb", "lib", "^1.0.0", "1.0.0"),
+		createRepository("ddd", "lib", "^1.0.0", "1.0.0"),
+		createRepository("eee", "lib", "^2.0.0", "2.0.0"),
+	}}
+	s := NewJobService(jobDAO, repDAO)
+
+	result, err := s.Simulate(new(MockRequestScope), &models.NpmHook{Name: "lib", Version: "1.1.0"})
+	if assert.Nil(t, err) && assert.Equal(t, 3, len(result.Jobs)) {
+		assert.Equal(t, SimulateAppend, result.Jobs[0].Action)
+		assert.Equal(t, 2, len(result.Jobs[0].Job.Dependencies))
+
+		assert.Equal(t, SimulateCreate, result.Jobs[1].Action)
+		assert.Equal(t, models.Locked, result.Jobs[1].Job.State)
+		assert.Equal(t, []string{"bbb"}, result.Jobs[1].Job.BlockedBy)
+
+		assert.Equal(t, SimulateCreate, result.Jobs[2].Action)
+		assert.Equal(t, models.Idle, result.Jobs[2].Job.State)
+	}
+
+	if assert.Equal(t, 1, len(result.Skipped)) {
+		assert.Equal(t, "eee", result.Skipped[0].Name)
+	}
+
+	// nothing is written
+	assert.Equal(t, 3, len(jobDAO.records))
+	assert.Equal(t, 1, len(jobDAO.records[0].Dependencies))
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/mongodb/mongo-go-driver/mongo"
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/app"
+	"github.com/quantumew/listener/errors"
+)
+
+// SystemActor is recorded as the actor of transitions the listener makes on its own.
+const SystemActor = "listener"
+
+// transitionTable lists the states a job may move to from each state.
+var transitionTable = map[models.State][]models.State{
+	models.Idle:       {models.Queued},
+	models.Queued:     {models.InProgress},
+	models.InProgress: {models.Succeeded, models.Failed, models.Queued},
+	models.Failed:     {models.Queued, models.DeadLetter},
+	models.DeadLetter: {models.Queued},
+	models.Locked:     {models.Idle},
+}
+
+// CanTransition returns whether the job state machine allows moving from one state to another.
+func CanTransition(from models.State, to models.State) bool {
+	for _, state := range transitionTable[from] {
+		if state == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsFinished returns whether a job in the state is done for good, either it succeeded or it ran out of attempts.
+func IsFinished(state models.State) bool {
+	return state == models.Succeeded || state == models.DeadLetter
+}
+
+// Transition moves a job of a repository to a new state, recording who made the move. The job is the one with the
+// ID when it is not zero, such as a job locked behind another one, else the one the repository name addresses, see
+// resolve. Moves the state machine does not allow are rejected with a conflict.
+func (s *JobService) Transition(rs app.RequestScope, name string, id int64, to models.State, actor string) (*models.Job, error) {
+	if err := validation.Validate(actor, validation.Required); err != nil {
+		return nil, validation.Errors{"actor": err}
+	}
+
+	job, err := s.resolveID(rs.DB(), name, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.transition(rs.DB(), job, to, actor, rs.Now()); err != nil {
+		return nil, err
+	}
+
+	if job, err = s.dao.GetByID(rs.DB(), job.ID); err != nil || to != models.Failed {
+		return job, err
+	}
+
+	s.notifyFailure(rs.DB(), job, rs.Now())
+
+	return job, nil
+}
+
+// transition atomically moves a job from the state it is in to another and returns the state the job ended up in,
+// which differs from the requested one when a failed job is retried or dead-lettered.
+func (s *JobService) transition(db *mongo.Database, job *models.Job, to models.State, actor string, now time.Time) (models.State, error) {
+	from := job.State
+	if !CanTransition(from, to) {
+		return from, errors.Conflict("job "+job.Name, fmt.Sprintf("cannot move from %v to %v", from, to))
+	}
+
+	if from == models.DeadLetter {
+		if err := s.checkRequeue(db, job.Name); err != nil {
+			return from, err
+		}
+	}
+
+	moved, err := s.dao.TransitionByID(db, job.ID, &models.Transition{From: from, To: to, Actor: actor, Time: now})
+	if err != nil {
+		return from, err
+	}
+
+	if !moved {
+		return from, errors.Conflict("job "+job.Name, fmt.Sprintf("job is no longer %v", from))
+	}
+
+	return s.afterTransition(db, job.Name, to, now)
+}
+
+// afterTransition applies the side effects of a job entering a state. Failed jobs are retried, and when a job is done
+// for good the jobs locked behind it are released so it can be picked up.
+func (s *JobService) afterTransition(db *mongo.Database, name string, state models.State, now time.Time) (models.State, error) {
+	if state == models.Failed {
+		return s.retry(db, name, now)
+	}
+
+	if IsFinished(state) {
+		return state, s.unlock(db, name, now)
+	}
+
+	return state, nil
+}
+
+// unlock releases the jobs locked behind a job that finished. The job of the same repository is released first, other
+// repositories wait until it is finished too since it may carry the same cascade. Jobs with no other blocker left move
+// to idle so they can be picked up.
+func (s *JobService) unlock(db *mongo.Database, name string, now time.Time) error {
+	transition := &models.Transition{From: models.Locked, To: models.Idle, Actor: SystemActor, Time: now}
+
+	if claimed, err := s.hasJobIn(db, name, claimedStateList); err != nil || claimed {
+		return err
+	}
+
+	if _, err := s.dao.UnblockJob(db, name, name, transition); err != nil {
+		return err
+	}
+
+	if unfinished, err := s.hasJobIn(db, name, unfinishedStateList); err != nil || unfinished {
+		return err
+	}
+
+	_, err := s.dao.Unblock(db, name, transition)
+	return err
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/quantumew/data-access/models"
+	"github.com/quantumew/listener/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to models.State
+		allowed  bool
+	}{
+		{models.Idle, models.Queued, true},
+		{models.Queued, models.InProgress, true},
+		{models.InProgress, models.Succeeded, true},
+		{models.InProgress, models.Failed, true},
+		{models.InProgress, models.Queued, true},
+		{models.Locked, models.Idle, true},
+		{models.Failed, models.Queued, true},
+		{models.Failed, models.DeadLetter, true},
+		{models.DeadLetter, models.Queued, true},
+		{models.Idle, models.InProgress, false},
+		{models.Idle, models.Succeeded, false},
+		{models.Queued, models.Idle, false},
+		{models.InProgress, models.Idle, false},
+		{models.Locked, models.InProgress, false},
+		{models.Succeeded, models.Idle, false},
+		{models.Failed, models.Idle, false},
+		{models.DeadLetter, models.InProgress, false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.allowed, CanTransition(test.from, test.to), "%v -> %v", test.from, test.to)
+	}
+}
+
+func TestJobService_Transition(t *testing.T) {
+	dao := newMockJobDAO().(*mockJobDAO)
+	s := NewJobService(dao, newMockRepositoryDAO())
+	rs := new(MockRequestScope)
+
+	job, err := s.Transition(rs, "aaa", 0, models.Queued, "worker-1")
+	if assert.Nil(t, err) && assert.Equal(t, models.Queued, job.State) && assert.Equal(t, 1, len(job.Transitions)) {
+		assert.Equal(t, models.Idle, job.Transitions[0].From)
+		assert.Equal(t, "worker-1", job.Transitions[0].Actor)
+		assert.Equal(t, rs.Now(), job.Transitions[0].Time)
+	}
+
+	// illegal move
+	_, err = s.Transition(rs, "aaa", 0, models.Succeeded, "worker-1")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusConflict, err.(*errors.APIError).Status)
+	}
+
+	// actor is required
+	_, err = s.Transition(rs, "aaa", 0, models.InProgress, "")
+	assert.IsType(t, validation.Errors{}, err)
+
+	_, err = s.Transition(rs, "zzz", 0, models.Queued, "worker-1")
+	assert.NotNil(t, err)
+}
+
+func TestJobService_Transition_Unlock(t *testing.T) {
+	dao := newMockJobDAO().(*mockJobDAO)
+	dao.records[0].State = models.InProgress
+	locked := createJob("aaa", "test", "1.3.0")
+	locked.State = models.Locked
+	locked.BlockedBy = []string{"aaa"}
+	dao.records = append(dao.records, locked)
+	s := NewJobService(dao, newMockRepositoryDAO())
+
+	job, err := s.Transition(new(MockRequestScope), "aaa", 0, models.Succeeded, "worker-1")
+	if assert.Nil(t, err) {
+		assert.Equal(t, models.Succeeded, job.State)
+		assert.Equal(t, models.Idle, locked.State)
+		assert.Equal(t, SystemActor, locked.Transitions[0].Actor)
+	}
+}
+
+func TestJobService_Transition_ByID(t *testing.T) {
+	dao := newMockJobDAO().(*mockJobDAO)
+	dao.records[0].State = models.InProgress
+	locked := createJob("aaa", "test", "1.3.0")
+	locked.ID = 4
+	locked.State = models.Locked
+	locked.BlockedBy = []string{"aaa"}
+	dao.records = append(dao.records, locked)
+	s := NewJobService(dao, newMockRepositoryDAO())
+	rs := new(MockRequestScope)
+
+	// the name addresses the job in progress, the ID the one locked behind it
+	job, err := s.Transition(rs, "aaa", 4, models.Idle, "someone")
+	if assert.Nil(t, err) {
+		assert.Equal(t, int64(4), job.ID)
+		assert.Equal(t, models.Idle, job.State)
+		assert.Equal(t, models.InProgress, dao.records[0].State)
+	}
+
+	_, err = s.Transition(rs, "bbb", 4, models.Queued, "someone")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusNotFound, err.(*errors.APIError).Status)
+	}
+}
+
+func TestJobService_Update_State(t *testing.T) {
+	s := NewJobService(newMockJobDAO(), newMockRepositoryDAO())
+	job := createJob("aaa", "test", "1.2.3")
+	job.State = models.Succeeded
+
+	_, err := s.Update(new(MockRequestScope), "aaa", job)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusConflict, err.(*errors.APIError).Status)
+	}
+}
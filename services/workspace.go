@@ -0,0 +1,127 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// workspaceGlobs are the patterns of the workspace paths of a repository, as the "workspaces" of its root
+// package.json or the "packages" of its pnpm-workspace.yaml list them. Patterns starting with "!" exclude paths.
+type workspaceGlobs []string
+
+// parseWorkspacesField reads the "workspaces" of a package.json, a list of patterns or, with Yarn, an object listing
+// them under "packages".
+func parseWorkspacesField(raw json.RawMessage) (workspaceGlobs, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	var globList []string
+	if err := json.Unmarshal(raw, &globList); err == nil {
+		return globList, nil
+	}
+
+	var yarnWorkspaces struct {
+		Packages []string `json:"packages"`
+	}
+	if err := json.Unmarshal(raw, &yarnWorkspaces); err != nil {
+		return nil, fmt.Errorf("workspaces must be a list of patterns: %s", err)
+	}
+
+	return yarnWorkspaces.Packages, nil
+}
+
+// parsePnpmWorkspace reads the patterns a pnpm-workspace.yaml lists under "packages". Only the block list form pnpm
+// documents is understood:
+//
+//	packages:
+//	  - 'packages/*'
+//	  - '!**/test/**'
+func parsePnpmWorkspace(data []byte) workspaceGlobs {
+	var globList workspaceGlobs
+	inPackages := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "-") {
+			inPackages = strings.TrimSpace(strings.TrimSuffix(trimmed, ":")) == "packages"
+			continue
+		}
+
+		if inPackages && strings.HasPrefix(trimmed, "-") {
+			glob := strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))
+			globList = append(globList, strings.Trim(glob, `'"`))
+		}
+	}
+
+	return globList
+}
+
+// Match returns whether a workspace path is matched by a pattern and not excluded by any.
+func (g workspaceGlobs) Match(workspace string) bool {
+	matched := false
+
+	for _, glob := range g {
+		if strings.HasPrefix(glob, "!") {
+			if matchWorkspaceGlob(strings.TrimPrefix(glob, "!"), workspace) {
+				return false
+			}
+		} else if matchWorkspaceGlob(glob, workspace) {
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+// matchWorkspaceGlob matches a path against a glob, where "*" matches within a path segment and "**" across them.
+func matchWorkspaceGlob(glob string, workspace string) bool {
+	glob = strings.TrimSuffix(path.Clean(strings.TrimPrefix(glob, "./")), "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			expr.WriteString("[^/]*")
+		case glob[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+
+	expr.WriteString("$")
+
+	matched, err := regexp.MatchString(expr.String(), workspace)
+	return err == nil && matched
+}
+
+// cleanWorkspacePath returns a workspace path relative to the root of the repository, or an error if it points
+// outside of it. The root itself is an empty path.
+func cleanWorkspacePath(workspace string) (string, error) {
+	if strings.HasPrefix(workspace, "/") || strings.Contains("/"+workspace+"/", "/../") {
+		return "", fmt.Errorf("workspace %q must be relative to the root of the repository", workspace)
+	}
+
+	return strings.TrimPrefix(path.Clean("/"+workspace), "/"), nil
+}
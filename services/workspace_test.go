@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/quantumew/data-access/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspaceGlobs_Match(t *testing.T) {
+	globs := parsePnpmWorkspace(readFixture(t, "manifest/monorepo/pnpm-workspace.yaml"))
+	assert.Equal(t, workspaceGlobs{"packages/*", "components/**", "!**/test/**"}, globs)
+
+	tests := map[string]bool{
+		"packages/ui":              true,
+		"packages/ui/nested":       false,
+		"components/buttons":       true,
+		"components/forms/inputs":  true,
+		"components/test/fixtures": false,
+		"packages":                 false,
+		"apps/web":                 false,
+	}
+
+	for workspace, matched := range tests {
+		assert.Equal(t, matched, globs.Match(workspace), workspace)
+	}
+
+	globList, err := parseWorkspacesField([]byte(`{"packages": ["./packages/*"], "nohoist": ["**/react-native"]}`))
+	if assert.Nil(t, err) {
+		assert.True(t, globList.Match("packages/ui"))
+	}
+
+	_, err = parseWorkspacesField([]byte(`"packages/*"`))
+	assert.NotNil(t, err)
+
+	for _, workspace := range []string{"/packages/ui", "../other", "packages/../../other"} {
+		_, err := cleanWorkspacePath(workspace)
+		assert.NotNil(t, err, workspace)
+	}
+}
+
+func monorepoManifest(t *testing.T) *Manifest {
+	return &Manifest{
+		PackageJSON: readFixture(t, "manifest/monorepo/package.json"),
+		PackageLock: readFixture(t, "manifest/monorepo/package-lock.json"),
+		Workspaces: map[string][]byte{
+			"packages/ui":   readFixture(t, "manifest/monorepo/ui.package.json"),
+			"packages/app/": readFixture(t, "manifest/monorepo/app.package.json"),
+		},
+	}
+}
+
+func TestParseManifest_Workspaces(t *testing.T) {
+	parsed, err := ParseManifest(monorepoManifest(t))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, []string{"octo-monorepo", "@octo-org/app", "@octo-org/ui"}, parsed.Packages)
+	assert.Equal(t, []models.Workspace{{Path: "packages/app", Package: "@octo-org/app"}, {Path: "packages/ui", Package: "@octo-org/ui"}}, parsed.Workspaces)
+	assert.Equal(t, []models.Dependency{
+		{Name: "jest", Semver: "~24.8.0", Installed: "24.8.0", Type: DependencyDev},
+		{Name: "lodash", Semver: "^3.10.0", Installed: "3.10.1", Type: DependencyProd, Workspace: "packages/app"},
+		{Name: "react", Semver: "^16.8.0", Installed: "16.8.6", Type: DependencyProd, Workspace: "packages/app"},
+		{Name: "lodash", Semver: "^4.17.0", Installed: "4.17.15", Type: DependencyProd, Workspace: "packages/ui"},
+		{Name: "react", Semver: "^16.8.0", Installed: "16.8.6", Type: DependencyDev, Workspace: "packages/ui"},
+	}, parsed.Dependencies)
+	assert.Equal(t, []SkippedDependency{{"@octo-org/ui", "packages/app", "package of the repository"}}, parsed.Skipped)
+}
+
+func TestJobService_Publish_Workspaces(t *testing.T) {
+	repDAO := newMockRepositoryDAO()
+	jobDAO := &mockJobDAO{}
+
+	result, err := NewRepositoryService(repDAO).RegisterManifest(new(MockRequestScope), "monorepo", monorepoManifest(t))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	monorepo := result.Repository
+	s := NewJobService(jobDAO, repDAO)
+
+	workspaceList, reason := MatchWorkspaces(monorepo, &models.PublishedDependency{Name: "lodash", Version: "5.0.0"})
+	assert.Empty(t, workspaceList)
+	assert.Equal(t, `lodash@5.0.0 is outside of the declared range "^3.10.0"`, reason)
+
+	for _, pub := range []*models.PublishedDependency{
+		{Ecosystem: EcosystemNpm, Name: "lodash", Version: "4.17.20"},
+		{Ecosystem: EcosystemNpm, Name: "react", Version: "16.9.0"},
+		{Ecosystem: EcosystemNpm, Name: "jest", Version: "24.8.1"},
+	} {
+		_, err := s.Publish(new(MockRequestScope), pub)
+		assert.Nil(t, err, pub.Name)
+	}
+
+	if assert.Equal(t, 1, len(jobDAO.records)) && assert.Equal(t, 3, len(jobDAO.records[0].Dependencies)) {
+		job := jobDAO.records[0]
+		assert.Equal(t, "monorepo", job.Name)
+		assert.Equal(t, []string{"packages/ui"}, job.Dependencies[0].Workspaces)
+		assert.Equal(t, []string{"packages/app", "packages/ui"}, job.Dependencies[1].Workspaces)
+		assert.Equal(t, []string{RootWorkspace}, job.Dependencies[2].Workspaces)
+	}
+
+	// repositories without workspaces are not told which ones to update
+	jobDAO = &mockJobDAO{}
+	s = NewJobService(jobDAO, newMockRepositoryDAO())
+
+	_, err = s.Publish(new(MockRequestScope), &models.PublishedDependency{Ecosystem: EcosystemNpm, Name: "test", Version: "1.2.3", Workspaces: []string{"forged"}})
+	if assert.Nil(t, err) && assert.Equal(t, 1, len(jobDAO.records)) {
+		assert.Nil(t, jobDAO.records[0].Dependencies[0].Workspaces)
+	}
+}
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+const signaturePrefix = "sha256="
+
+// Sign returns the signature header value of a payload for the given secret, in the form "sha256=<hex digest>".
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks an HMAC-SHA256 signature header against a payload.
+// Any of the given secrets may have produced the signature so that secrets can be rotated without downtime.
+func VerifySignature(secretList []string, signature string, payload []byte) error {
+	if signature == "" {
+		return fmt.Errorf("missing signature")
+	}
+
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return fmt.Errorf("unsupported signature format")
+	}
+
+	return verifyHMAC(sha256.New, secretList, strings.TrimPrefix(signature, signaturePrefix), payload)
+}
+
+// VerifySHA1Signature checks a bare hex HMAC-SHA1 signature header against a payload, as Nexus Repository Manager
+// signs its webhooks.
+func VerifySHA1Signature(secretList []string, signature string, payload []byte) error {
+	if signature == "" {
+		return fmt.Errorf("missing signature")
+	}
+
+	return verifyHMAC(sha1.New, secretList, signature, payload)
+}
+
+func verifyHMAC(newHash func() hash.Hash, secretList []string, digest string, payload []byte) error {
+	actual, err := hex.DecodeString(digest)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %s", err)
+	}
+
+	for _, secret := range secretList {
+		if secret == "" {
+			continue
+		}
+
+		mac := hmac.New(newHash, []byte(secret))
+		mac.Write(payload)
+
+		if hmac.Equal(actual, mac.Sum(nil)) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("signature does not match any configured secret")
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	// echo -n 'hello' | openssl dgst -sha256 -hmac 'secret'
+	assert.Equal(t, "sha256=88aab3ede8d3adf94d26ab90d3bafd4a2083070c3bcce9c014ee04a443847c0b", Sign("secret", []byte("hello")))
+}
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"event":"package:publish","name":"@aufait/test","version":"1.2.3"}`)
+	secretList := []string{"old", "current"}
+
+	tests := []struct {
+		tag       string
+		secrets   []string
+		signature string
+		payload   []byte
+		valid     bool
+	}{
+		{"signed", secretList, Sign("current", payload), payload, true},
+		{"signed with rotated secret", secretList, Sign("old", payload), payload, true},
+		{"unsigned", secretList, "", payload, false},
+		{"unknown secret", secretList, Sign("other", payload), payload, false},
+		{"tampered payload", secretList, Sign("current", payload), append(payload, ' '), false},
+		{"tampered signature", secretList, Sign("current", payload)[:20] + "0000", payload, false},
+		{"wrong algorithm", secretList, "sha1=abcdef", payload, false},
+		{"malformed digest", secretList, "sha256=zzzz", payload, false},
+		{"no secrets", []string{}, Sign("", payload), payload, false},
+		{"empty secret", []string{""}, Sign("", payload), payload, false},
+	}
+
+	for _, test := range tests {
+		err := VerifySignature(test.secrets, test.signature, test.payload)
+		if test.valid {
+			assert.Nil(t, err, test.tag)
+		} else {
+			assert.NotNil(t, err, test.tag)
+		}
+	}
+}
+
+func TestVerifySHA1Signature(t *testing.T) {
+	// echo -n 'hello' | openssl dgst -sha1 -hmac 'secret'
+	signature := "5112055c05f944f85755efc5cd8970e194e9f45b"
+	payload := []byte("hello")
+
+	assert.Nil(t, VerifySHA1Signature([]string{"old", "secret"}, signature, payload))
+	assert.NotNil(t, VerifySHA1Signature([]string{"other"}, signature, payload))
+	assert.NotNil(t, VerifySHA1Signature([]string{"secret"}, signature, append(payload, ' ')))
+	assert.NotNil(t, VerifySHA1Signature([]string{"secret"}, "", payload))
+	assert.NotNil(t, VerifySHA1Signature([]string{"secret"}, "sha1="+signature, payload))
+}
@@ -0,0 +1,26 @@
+package util
+
+import (
+	"crypto/subtle"
+	"fmt"
+)
+
+// VerifyToken checks a shared secret token sent along with a request, as GitLab does with its webhooks.
+// Any of the given tokens may match so that tokens can be rotated without downtime.
+func VerifyToken(tokenList []string, token string) error {
+	if token == "" {
+		return fmt.Errorf("missing token")
+	}
+
+	for _, expected := range tokenList {
+		if expected == "" {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1 {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("token does not match any configured token")
+}
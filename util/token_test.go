@@ -0,0 +1,17 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyToken(t *testing.T) {
+	tokenList := []string{"old", "current"}
+
+	assert.Nil(t, VerifyToken(tokenList, "current"))
+	assert.Nil(t, VerifyToken(tokenList, "old"))
+	assert.NotNil(t, VerifyToken(tokenList, ""))
+	assert.NotNil(t, VerifyToken(tokenList, "curren"))
+	assert.NotNil(t, VerifyToken([]string{""}, "anything"))
+}
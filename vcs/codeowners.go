@@ -0,0 +1,115 @@
+package vcs
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// CodeOwnersPaths are the places a CODEOWNERS file is looked for, GitHub and GitLab each having their own directory.
+var CodeOwnersPaths = []string{"CODEOWNERS", ".github/CODEOWNERS", ".gitlab/CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeOwners are the rules of a CODEOWNERS file, in the order they are written.
+type CodeOwners []codeOwnersRule
+
+type codeOwnersRule struct {
+	pattern   *regexp.Regexp
+	ownerList []string
+}
+
+// ReadCodeOwners reads the first CODEOWNERS file of a repository at a ref. A repository without one has no owners.
+func ReadCodeOwners(vc VersionControl, repo Repo, ref string) (CodeOwners, error) {
+	for _, path := range CodeOwnersPaths {
+		content, err := vc.ReadFile(repo, ref, path)
+		if err == ErrNotFound {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return ParseCodeOwners(content), nil
+	}
+
+	return nil, nil
+}
+
+// ParseCodeOwners parses a CODEOWNERS file. Owners are users and teams, "@jdoe" and "@org/team", given without the
+// leading @. Owners given by email cannot be requested as reviewers and are left out, and so are the section headers
+// of GitLab.
+func ParseCodeOwners(content []byte) CodeOwners {
+	var codeOwners CodeOwners
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		fieldList := strings.Fields(line)
+		if len(fieldList) == 0 || strings.HasPrefix(fieldList[0], "[") || strings.HasPrefix(fieldList[0], "^[") {
+			continue
+		}
+
+		rule := codeOwnersRule{pattern: compileCodeOwnersPattern(fieldList[0])}
+		for _, owner := range fieldList[1:] {
+			if strings.HasPrefix(owner, "@") {
+				rule.ownerList = append(rule.ownerList, owner[1:])
+			}
+		}
+
+		codeOwners = append(codeOwners, rule)
+	}
+
+	return codeOwners
+}
+
+// Owners returns the owners of a file, the ones of the last rule matching it.
+func (c CodeOwners) Owners(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+
+	for i := len(c) - 1; i >= 0; i-- {
+		if c[i].pattern.MatchString(path) {
+			return c[i].ownerList
+		}
+	}
+
+	return nil
+}
+
+// compileCodeOwnersPattern turns a gitignore style pattern into a regexp. Patterns without a slash match at any
+// depth, other patterns from the root, and patterns matching a directory match everything in it.
+func compileCodeOwnersPattern(pattern string) *regexp.Regexp {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	expr.WriteString("(?:/.*)?$")
+
+	return regexp.MustCompile(expr.String())
+}
@@ -0,0 +1,63 @@
+package vcs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCodeOwners(t *testing.T) {
+	codeOwners := ParseCodeOwners([]byte(`# Default owners
+*                       @octo-org/core
+
+[Frontend]
+packages/ui/            @jdoe @octo-org/ui ui@example.com
+*.md                    @docs-team # documentation
+/build/**/Makefile      @octo-org/build
+docs/**                 @docs-team
+/package.json           @jdoe
+`))
+
+	tests := []struct {
+		path   string
+		owners []string
+	}{
+		{"main.go", []string{"octo-org/core"}},
+		{"packages/ui/package.json", []string{"jdoe", "octo-org/ui"}},
+		{"packages/ui/src/index.js", []string{"jdoe", "octo-org/ui"}},
+		{"packages/ui-kit/package.json", []string{"octo-org/core"}},
+		{"packages/ui/README.md", []string{"docs-team"}},
+		{"build/Makefile", []string{"octo-org/build"}},
+		{"build/linux/arm/Makefile", []string{"octo-org/build"}},
+		{"tools/build/Makefile", []string{"octo-org/core"}},
+		{"docs/guides/setup.txt", []string{"docs-team"}},
+		{"/package.json", []string{"jdoe"}},
+		{"packages/api/package.json", []string{"octo-org/core"}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.owners, codeOwners.Owners(test.path), test.path)
+	}
+
+	assert.Nil(t, ParseCodeOwners(nil).Owners("package.json"))
+	assert.Nil(t, ParseCodeOwners([]byte("*.go @gophers\n")).Owners("package.json"))
+}
+
+func TestReadCodeOwners(t *testing.T) {
+	github, fake, closeServer := newTestGitHub()
+	defer closeServer()
+
+	codeOwners, err := ReadCodeOwners(github, octoWidgets, "master")
+	assert.Nil(t, err)
+	assert.Empty(t, codeOwners)
+
+	fake.trees[stashCommitID(0)][".github/CODEOWNERS"] = "* @octo-org/core\n"
+	fake.trees[stashCommitID(0)]["docs/CODEOWNERS"] = "* @docs-team\n"
+
+	codeOwners, err = ReadCodeOwners(github, octoWidgets, "master")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"octo-org/core"}, codeOwners.Owners("package.json"))
+
+	_, err = ReadCodeOwners(NewGitHub(github.client, github.baseURL, "wrong"), octoWidgets, "master")
+	assert.IsType(t, &GitHubError{}, err)
+}
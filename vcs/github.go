@@ -0,0 +1,366 @@
+package vcs
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitHub is a client of the REST API of GitHub or GitHub Enterprise. The owner of a repository is the user or the
+// organization it belongs to.
+type GitHub struct {
+	restClient
+}
+
+// GitHubError is an error response of the GitHub API.
+type GitHubError struct {
+	StatusCode int
+	Message    string
+}
+
+type gitHubContent struct {
+	Type     string `json:"type"`
+	Encoding string `json:"encoding"`
+	Content  string `json:"content"`
+	SHA      string `json:"sha"`
+}
+
+type gitHubBranch struct {
+	Ref string `json:"ref"`
+}
+
+type gitHubPull struct {
+	Number   int64        `json:"number"`
+	Title    string       `json:"title"`
+	Body     string       `json:"body"`
+	State    string       `json:"state"`
+	MergedAt *string      `json:"merged_at"`
+	HTMLURL  string       `json:"html_url"`
+	Head     gitHubBranch `json:"head"`
+	Base     gitHubBranch `json:"base"`
+	Labels   []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	RequestedReviewers []struct {
+		Login string `json:"login"`
+	} `json:"requested_reviewers"`
+	RequestedTeams []struct {
+		Slug string `json:"slug"`
+	} `json:"requested_teams"`
+}
+
+// NewGitHub returns a client of the GitHub API at a base URL, "https://api.github.com" or
+// "https://github.example.com/api/v3" for GitHub Enterprise, authenticated with an access token.
+func NewGitHub(client *http.Client, baseURL string, token string) *GitHub {
+	authorize := func(req *http.Request) {
+		req.Header.Set("Authorization", "token "+token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+
+	return &GitHub{newRESTClient(client, baseURL, authorize, newGitHubError)}
+}
+
+// CreateBranch creates a branch starting at another branch or a commit.
+func (g *GitHub) CreateBranch(repo Repo, branch string, startPoint string) error {
+	sha := startPoint
+
+	if !isCommitID(startPoint) {
+		ref := struct {
+			Object struct {
+				SHA string `json:"sha"`
+			} `json:"object"`
+		}{}
+
+		if err := g.doJSON("GET", g.repoPath(repo, "git/ref/heads", startPoint), nil, &ref); err != nil {
+			return err
+		}
+
+		sha = ref.Object.SHA
+	}
+
+	body := map[string]string{"ref": "refs/heads/" + branch, "sha": sha}
+
+	return g.doJSON("POST", g.repoPath(repo, "git/refs"), body, nil)
+}
+
+// DeleteBranch deletes a branch.
+func (g *GitHub) DeleteBranch(repo Repo, branch string) error {
+	err := g.doJSON("DELETE", g.repoPath(repo, "git/refs/heads", branch), nil, nil)
+
+	// GitHub rejects deleting a missing branch rather than not finding it.
+	if ghErr, ok := err.(*GitHubError); ok && ghErr.StatusCode == http.StatusUnprocessableEntity && ghErr.Message == "Reference does not exist" {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+// CommitFiles commits file changes through the contents API, one commit per file.
+func (g *GitHub) CommitFiles(repo Repo, commit Commit) (string, error) {
+	head := ""
+
+	for _, file := range commit.Files {
+		body := map[string]string{
+			"message": commit.Message,
+			"content": base64.StdEncoding.EncodeToString(file.Content),
+			"branch":  commit.Branch,
+		}
+
+		// Updates of existing files must name the blob they replace.
+		current, err := g.content(repo, commit.Branch, file.Path)
+		if err == nil {
+			body["sha"] = current.SHA
+		} else if err != ErrNotFound {
+			return "", err
+		}
+
+		created := struct {
+			Commit struct {
+				SHA string `json:"sha"`
+			} `json:"commit"`
+		}{}
+
+		if err := g.doJSON("PUT", g.repoPath(repo, "contents", file.Path), body, &created); err != nil {
+			return "", err
+		}
+
+		head = created.Commit.SHA
+	}
+
+	return head, nil
+}
+
+// ReadFile returns the content of a file at a branch or a commit.
+func (g *GitHub) ReadFile(repo Repo, ref string, path string) ([]byte, error) {
+	content, err := g.content(repo, ref, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(strings.Replace(content.Content, "\n", "", -1))
+}
+
+// FindPullRequest returns the open pull request from a branch, or nil when there is none.
+func (g *GitHub) FindPullRequest(repo Repo, sourceBranch string) (*PullRequest, error) {
+	query := url.Values{"head": {repo.Owner + ":" + sourceBranch}, "state": {"open"}}
+	var pullList []gitHubPull
+
+	if err := g.doJSON("GET", g.repoPath(repo, "pulls")+"?"+query.Encode(), nil, &pullList); err != nil {
+		return nil, err
+	}
+
+	if len(pullList) == 0 {
+		return nil, nil
+	}
+
+	return pullList[0].pullRequest(repo), nil
+}
+
+// OpenPullRequest opens a pull request with its labels and reviewers.
+func (g *GitHub) OpenPullRequest(repo Repo, pr PullRequest) (*PullRequest, error) {
+	body := map[string]string{"title": pr.Title, "body": pr.Description, "head": pr.SourceBranch, "base": pr.TargetBranch}
+	created := gitHubPull{}
+
+	if err := g.doJSON("POST", g.repoPath(repo, "pulls"), body, &created); err != nil {
+		return nil, err
+	}
+
+	return g.addLabelsAndReviewers(repo, created, pr)
+}
+
+// UpdatePullRequest updates the title and description of an open pull request, and adds its labels and reviewers.
+func (g *GitHub) UpdatePullRequest(repo Repo, pr PullRequest) (*PullRequest, error) {
+	body := map[string]string{"title": pr.Title, "body": pr.Description}
+	updated := gitHubPull{}
+
+	if err := g.doJSON("PATCH", g.repoPath(repo, "pulls", fmt.Sprint(pr.ID)), body, &updated); err != nil {
+		return nil, err
+	}
+
+	return g.addLabelsAndReviewers(repo, updated, pr)
+}
+
+// ClosePullRequest closes a pull request.
+func (g *GitHub) ClosePullRequest(repo Repo, pr PullRequest) error {
+	return g.doJSON("PATCH", g.repoPath(repo, "pulls", fmt.Sprint(pr.ID)), map[string]string{"state": "closed"}, nil)
+}
+
+// addLabelsAndReviewers adds the labels and requests the reviews of a pull request on the one GitHub returned, labels
+// belonging to the issue of the pull request.
+func (g *GitHub) addLabelsAndReviewers(repo Repo, pull gitHubPull, pr PullRequest) (*PullRequest, error) {
+	converted := pull.pullRequest(repo)
+	number := fmt.Sprint(pull.Number)
+
+	if len(pr.Labels) > 0 {
+		var labelList []struct {
+			Name string `json:"name"`
+		}
+
+		if err := g.doJSON("POST", g.repoPath(repo, "issues", number, "labels"), map[string][]string{"labels": pr.Labels}, &labelList); err != nil {
+			return nil, err
+		}
+
+		converted.Labels = nil
+		for _, label := range labelList {
+			converted.Labels = append(converted.Labels, label.Name)
+		}
+	}
+
+	if len(pr.Reviewers) > 0 {
+		requested, err := g.requestReviewers(repo, number, pr.Reviewers)
+		if err != nil {
+			return nil, err
+		}
+
+		if requested != nil {
+			converted.Reviewers = requested.pullRequest(repo).Reviewers
+		}
+	}
+
+	return converted, nil
+}
+
+// requestReviewers requests the reviews of users and teams on a pull request. GitHub rejects the whole request with a
+// 422 when one of them cannot review the pull request, such as a user who is not a collaborator, so they are then
+// requested one at a time and the ones GitHub rejects are skipped. Nil is returned when none of them could be requested.
+func (g *GitHub) requestReviewers(repo Repo, number string, reviewerList []string) (*gitHubPull, error) {
+	path := g.repoPath(repo, "pulls", number, "requested_reviewers")
+	requested := &gitHubPull{}
+
+	if err := g.doJSON("POST", path, gitHubReviewers(reviewerList), requested); err == nil {
+		return requested, nil
+	} else if !isUnprocessable(err) {
+		return nil, err
+	}
+
+	if len(reviewerList) == 1 {
+		return nil, nil
+	}
+
+	var last *gitHubPull
+
+	for _, reviewer := range reviewerList {
+		requested := &gitHubPull{}
+
+		if err := g.doJSON("POST", path, gitHubReviewers([]string{reviewer}), requested); isUnprocessable(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		last = requested
+	}
+
+	return last, nil
+}
+
+// gitHubReviewers returns the body requesting the reviews of users and teams. Teams are requested by their slug,
+// within the organization of the repository.
+func gitHubReviewers(reviewerList []string) map[string][]string {
+	body := map[string][]string{"reviewers": {}, "team_reviewers": {}}
+
+	for _, reviewer := range reviewerList {
+		if i := strings.LastIndex(reviewer, "/"); i >= 0 {
+			body["team_reviewers"] = append(body["team_reviewers"], reviewer[i+1:])
+		} else {
+			body["reviewers"] = append(body["reviewers"], reviewer)
+		}
+	}
+
+	return body
+}
+
+// isUnprocessable returns whether GitHub rejected a request as invalid.
+func isUnprocessable(err error) bool {
+	ghErr, ok := err.(*GitHubError)
+
+	return ok && ghErr.StatusCode == http.StatusUnprocessableEntity
+}
+
+// content returns a file of the contents API.
+func (g *GitHub) content(repo Repo, ref string, path string) (*gitHubContent, error) {
+	query := url.Values{"ref": {ref}}
+	content := &gitHubContent{}
+
+	if err := g.doJSON("GET", g.repoPath(repo, "contents", path)+"?"+query.Encode(), nil, content); err != nil {
+		return nil, err
+	}
+
+	// Directories are listed rather than read.
+	if content.Type != "file" {
+		return nil, ErrNotFound
+	}
+
+	return content, nil
+}
+
+// repoPath returns the path of a resource of a repository.
+func (g *GitHub) repoPath(repo Repo, elemList ...string) string {
+	path := fmt.Sprintf("/repos/%s/%s", url.PathEscape(repo.Owner), url.PathEscape(repo.Name))
+
+	for _, elem := range elemList {
+		for _, segment := range strings.Split(elem, "/") {
+			path += "/" + url.PathEscape(segment)
+		}
+	}
+
+	return path
+}
+
+// newGitHubError reads the message of an error response.
+func newGitHubError(res *http.Response) error {
+	apiErr := &GitHubError{StatusCode: res.StatusCode}
+	errorBody := struct {
+		Message string `json:"message"`
+	}{}
+
+	if json.NewDecoder(res.Body).Decode(&errorBody) == nil {
+		apiErr.Message = errorBody.Message
+	}
+
+	return apiErr
+}
+
+func (e *GitHubError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("GitHub API responded with %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("GitHub API responded with %d: %s", e.StatusCode, e.Message)
+}
+
+func (pull gitHubPull) pullRequest(repo Repo) *PullRequest {
+	pr := &PullRequest{
+		ID:           pull.Number,
+		Title:        pull.Title,
+		Description:  pull.Body,
+		SourceBranch: pull.Head.Ref,
+		TargetBranch: pull.Base.Ref,
+		URL:          pull.HTMLURL,
+		State:        StateOpen,
+	}
+
+	if pull.State == "closed" {
+		pr.State = StateDeclined
+		if pull.MergedAt != nil {
+			pr.State = StateMerged
+		}
+	}
+
+	for _, label := range pull.Labels {
+		pr.Labels = append(pr.Labels, label.Name)
+	}
+
+	for _, reviewer := range pull.RequestedReviewers {
+		pr.Reviewers = append(pr.Reviewers, reviewer.Login)
+	}
+
+	for _, team := range pull.RequestedTeams {
+		pr.Reviewers = append(pr.Reviewers, repo.Owner+"/"+team.Slug)
+	}
+
+	return pr
+}
@@ -0,0 +1,426 @@
+package vcs
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const gitHubRepoPath = "/api/v3/repos/octo-org/widgets/"
+
+// fakeGitHub is an in-memory GitHub Enterprise holding the octo-org/widgets repository.
+type fakeGitHub struct {
+	mu sync.Mutex
+	// branches are the heads of the branches by name.
+	branches map[string]string
+	// trees are the files of every commit.
+	trees       map[string]map[string]string
+	commitCount int
+	pulls       []*gitHubPull
+	// outsiders are the users reviews cannot be requested from.
+	outsiders []string
+}
+
+func newFakeGitHub() *fakeGitHub {
+	return &fakeGitHub{
+		branches: map[string]string{"master": stashCommitID(0)},
+		trees:    map[string]map[string]string{stashCommitID(0): {"package.json": `{"name":"widgets"}`}},
+	}
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "token secret" {
+		writeGitHubError(w, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, gitHubRepoPath) {
+		writeGitHubError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	resource := strings.TrimPrefix(r.URL.Path, gitHubRepoPath)
+	segmentList := strings.Split(resource, "/")
+
+	switch {
+	case strings.HasPrefix(resource, "git/ref/heads/") && r.Method == "GET":
+		f.getRef(w, strings.TrimPrefix(resource, "git/ref/heads/"))
+	case resource == "git/refs" && r.Method == "POST":
+		f.createRef(w, r)
+	case strings.HasPrefix(resource, "git/refs/heads/") && r.Method == "DELETE":
+		f.deleteRef(w, strings.TrimPrefix(resource, "git/refs/heads/"))
+	case segmentList[0] == "contents" && r.Method == "GET":
+		f.getContent(w, r, strings.TrimPrefix(resource, "contents/"))
+	case segmentList[0] == "contents" && r.Method == "PUT":
+		f.putContent(w, r, strings.TrimPrefix(resource, "contents/"))
+	case resource == "pulls" && r.Method == "GET":
+		f.listPulls(w, r)
+	case resource == "pulls" && r.Method == "POST":
+		f.createPull(w, r)
+	case segmentList[0] == "pulls" && len(segmentList) == 2 && r.Method == "PATCH":
+		f.updatePull(w, r, segmentList[1])
+	case segmentList[0] == "issues" && len(segmentList) == 3 && segmentList[2] == "labels" && r.Method == "POST":
+		f.addLabels(w, r, segmentList[1])
+	case segmentList[0] == "pulls" && len(segmentList) == 3 && segmentList[2] == "requested_reviewers" && r.Method == "POST":
+		f.requestReviewers(w, r, segmentList[1])
+	default:
+		writeGitHubError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (f *fakeGitHub) getRef(w http.ResponseWriter, branch string) {
+	head, ok := f.branches[branch]
+	if !ok {
+		writeGitHubError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	fmt.Fprintf(w, `{"ref":"refs/heads/%s","object":{"sha":%q,"type":"commit"}}`, branch, head)
+}
+
+func (f *fakeGitHub) createRef(w http.ResponseWriter, r *http.Request) {
+	body := map[string]string{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	branch := strings.TrimPrefix(body["ref"], "refs/heads/")
+	if _, ok := f.branches[branch]; ok {
+		writeGitHubError(w, http.StatusUnprocessableEntity, "Reference already exists")
+		return
+	}
+
+	if _, ok := f.trees[body["sha"]]; !ok {
+		writeGitHubError(w, http.StatusUnprocessableEntity, "Object does not exist")
+		return
+	}
+
+	f.branches[branch] = body["sha"]
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintf(w, `{"ref":%q,"object":{"sha":%q}}`, body["ref"], body["sha"])
+}
+
+func (f *fakeGitHub) deleteRef(w http.ResponseWriter, branch string) {
+	if _, ok := f.branches[branch]; !ok {
+		writeGitHubError(w, http.StatusUnprocessableEntity, "Reference does not exist")
+		return
+	}
+
+	delete(f.branches, branch)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeGitHub) getContent(w http.ResponseWriter, r *http.Request, path string) {
+	ref := r.URL.Query().Get("ref")
+	if head, ok := f.branches[ref]; ok {
+		ref = head
+	}
+
+	content, ok := f.trees[ref][path]
+	if !ok {
+		writeGitHubError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	// The content is wrapped like GitHub does.
+	encoded := base64.StdEncoding.EncodeToString([]byte(content))
+	for i := 60; i < len(encoded); i += 61 {
+		encoded = encoded[:i] + "\n" + encoded[i:]
+	}
+
+	json.NewEncoder(w).Encode(gitHubContent{Type: "file", Encoding: "base64", Content: encoded, SHA: blobSHA(content)})
+}
+
+func (f *fakeGitHub) putContent(w http.ResponseWriter, r *http.Request, path string) {
+	body := map[string]string{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	head, ok := f.branches[body["branch"]]
+	if !ok {
+		writeGitHubError(w, http.StatusNotFound, "Branch not found")
+		return
+	}
+
+	if current, exists := f.trees[head][path]; exists && body["sha"] != blobSHA(current) {
+		writeGitHubError(w, http.StatusConflict, fmt.Sprintf("%s does not match %s", path, body["sha"]))
+		return
+	}
+
+	content, err := base64.StdEncoding.DecodeString(body["content"])
+	if err != nil || body["message"] == "" {
+		writeGitHubError(w, http.StatusUnprocessableEntity, "Invalid request")
+		return
+	}
+
+	f.commitCount++
+	commit := stashCommitID(f.commitCount)
+	f.trees[commit] = map[string]string{path: string(content)}
+	for name, old := range f.trees[head] {
+		if name != path {
+			f.trees[commit][name] = old
+		}
+	}
+
+	f.branches[body["branch"]] = commit
+	fmt.Fprintf(w, `{"content":{"path":%q},"commit":{"sha":%q}}`, path, commit)
+}
+
+func (f *fakeGitHub) listPulls(w http.ResponseWriter, r *http.Request) {
+	pullList := []*gitHubPull{}
+
+	for _, pull := range f.pulls {
+		if "octo-org:"+pull.Head.Ref == r.URL.Query().Get("head") && pull.State == r.URL.Query().Get("state") {
+			pullList = append(pullList, pull)
+		}
+	}
+
+	json.NewEncoder(w).Encode(pullList)
+}
+
+func (f *fakeGitHub) createPull(w http.ResponseWriter, r *http.Request) {
+	body := map[string]string{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	if _, ok := f.branches[body["head"]]; !ok {
+		writeGitHubError(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	}
+
+	pull := &gitHubPull{
+		Number: int64(len(f.pulls) + 1),
+		Title:  body["title"],
+		Body:   body["body"],
+		State:  "open",
+		Head:   gitHubBranch{body["head"]},
+		Base:   gitHubBranch{body["base"]},
+	}
+	pull.HTMLURL = fmt.Sprintf("https://github.example.com/octo-org/widgets/pull/%d", pull.Number)
+
+	f.pulls = append(f.pulls, pull)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(pull)
+}
+
+func (f *fakeGitHub) updatePull(w http.ResponseWriter, r *http.Request, number string) {
+	pull := f.pull(w, number)
+	if pull == nil {
+		return
+	}
+
+	body := map[string]string{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	for field, value := range body {
+		switch field {
+		case "title":
+			pull.Title = value
+		case "body":
+			pull.Body = value
+		case "state":
+			pull.State = value
+		}
+	}
+
+	json.NewEncoder(w).Encode(pull)
+}
+
+func (f *fakeGitHub) addLabels(w http.ResponseWriter, r *http.Request, number string) {
+	pull := f.pull(w, number)
+	if pull == nil {
+		return
+	}
+
+	body := map[string][]string{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	for _, label := range body["labels"] {
+		pull.Labels = append(pull.Labels, struct {
+			Name string `json:"name"`
+		}{label})
+	}
+
+	json.NewEncoder(w).Encode(pull.Labels)
+}
+
+func (f *fakeGitHub) requestReviewers(w http.ResponseWriter, r *http.Request, number string) {
+	pull := f.pull(w, number)
+	if pull == nil {
+		return
+	}
+
+	body := map[string][]string{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	for _, login := range body["reviewers"] {
+		for _, outsider := range f.outsiders {
+			if login == outsider {
+				writeGitHubError(w, http.StatusUnprocessableEntity, "Reviews may only be requested from collaborators.")
+				return
+			}
+		}
+	}
+
+	for _, login := range body["reviewers"] {
+		pull.RequestedReviewers = append(pull.RequestedReviewers, struct {
+			Login string `json:"login"`
+		}{login})
+	}
+
+	for _, slug := range body["team_reviewers"] {
+		pull.RequestedTeams = append(pull.RequestedTeams, struct {
+			Slug string `json:"slug"`
+		}{slug})
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(pull)
+}
+
+func (f *fakeGitHub) pull(w http.ResponseWriter, number string) *gitHubPull {
+	for _, pull := range f.pulls {
+		if fmt.Sprint(pull.Number) == number {
+			return pull
+		}
+	}
+
+	writeGitHubError(w, http.StatusNotFound, "Not Found")
+
+	return nil
+}
+
+func blobSHA(content string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(content)))
+}
+
+func writeGitHubError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"message":%q}`, message)
+}
+
+func newTestGitHub() (*GitHub, *fakeGitHub, func()) {
+	fake := newFakeGitHub()
+	server := httptest.NewServer(fake)
+
+	return NewGitHub(server.Client(), server.URL+"/api/v3", "secret"), fake, server.Close
+}
+
+var octoWidgets = Repo{Owner: "octo-org", Name: "widgets"}
+
+func TestGitHub_CommitFiles(t *testing.T) {
+	github, fake, closeServer := newTestGitHub()
+	defer closeServer()
+
+	assert.Nil(t, github.CreateBranch(octoWidgets, "aufait/widgets", "master"))
+	assert.IsType(t, &GitHubError{}, github.CreateBranch(octoWidgets, "aufait/widgets", "master"), "branch exists")
+	assert.Equal(t, ErrNotFound, github.CreateBranch(octoWidgets, "other", "missing"))
+
+	content := `{"name":"widgets","description":"A long enough description to wrap the base64 content GitHub returns"}`
+	head, err := github.CommitFiles(octoWidgets, Commit{
+		Branch:  "aufait/widgets",
+		Message: "Update lib to 1.2.0",
+		Files: []FileChange{
+			{Path: "package.json", Content: []byte(content)},
+			{Path: "packages/ui/package.json", Content: []byte(`{"name":"ui"}`)},
+		},
+	})
+	if assert.Nil(t, err) {
+		assert.Equal(t, stashCommitID(2), head)
+		assert.Equal(t, stashCommitID(0), fake.branches["master"])
+	}
+
+	read, err := github.ReadFile(octoWidgets, "aufait/widgets", "package.json")
+	assert.Nil(t, err)
+	assert.Equal(t, content, string(read))
+
+	read, err = github.ReadFile(octoWidgets, "aufait/widgets", "packages/ui/package.json")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name":"ui"}`, string(read))
+
+	_, err = github.ReadFile(octoWidgets, "master", "packages/ui/package.json")
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.Nil(t, github.DeleteBranch(octoWidgets, "aufait/widgets"))
+	assert.Equal(t, ErrNotFound, github.DeleteBranch(octoWidgets, "aufait/widgets"))
+}
+
+func TestGitHub_PullRequests(t *testing.T) {
+	github, fake, closeServer := newTestGitHub()
+	defer closeServer()
+
+	assert.Nil(t, github.CreateBranch(octoWidgets, "aufait/widgets", "master"))
+
+	pr, err := github.FindPullRequest(octoWidgets, "aufait/widgets")
+	assert.Nil(t, err)
+	assert.Nil(t, pr)
+
+	opened, err := github.OpenPullRequest(octoWidgets, PullRequest{
+		Title:        "Update lib to 1.2.0",
+		Description:  "lib 1.2.0 was published",
+		SourceBranch: "aufait/widgets",
+		TargetBranch: "master",
+		Reviewers:    []string{"jdoe", "octo-org/ui"},
+		Labels:       []string{"dependencies"},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, &PullRequest{
+		ID:           1,
+		Title:        "Update lib to 1.2.0",
+		Description:  "lib 1.2.0 was published",
+		SourceBranch: "aufait/widgets",
+		TargetBranch: "master",
+		Reviewers:    []string{"jdoe", "octo-org/ui"},
+		Labels:       []string{"dependencies"},
+		State:        StateOpen,
+		URL:          "https://github.example.com/octo-org/widgets/pull/1",
+	}, opened)
+
+	found, err := github.FindPullRequest(octoWidgets, "aufait/widgets")
+	assert.Nil(t, err)
+	assert.Equal(t, opened, found)
+
+	found.Title = "Update lib to 1.3.0"
+	found.Labels, found.Reviewers = nil, nil
+	updated, err := github.UpdatePullRequest(octoWidgets, *found)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "Update lib to 1.3.0", updated.Title)
+		assert.Equal(t, []string{"dependencies"}, updated.Labels)
+	}
+
+	// users who cannot review the pull request are skipped
+	fake.outsiders = []string{"outsider"}
+	found.Reviewers = []string{"outsider", "jane"}
+	updated, err = github.UpdatePullRequest(octoWidgets, *found)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"jdoe", "jane", "octo-org/ui"}, updated.Reviewers)
+	}
+
+	found.Reviewers = []string{"outsider"}
+	_, err = github.UpdatePullRequest(octoWidgets, *found)
+	assert.Nil(t, err)
+
+	assert.Nil(t, github.ClosePullRequest(octoWidgets, *updated))
+
+	pr, err = github.FindPullRequest(octoWidgets, "aufait/widgets")
+	assert.Nil(t, err)
+	assert.Nil(t, pr)
+
+	_, err = github.UpdatePullRequest(octoWidgets, PullRequest{ID: 42})
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = github.OpenPullRequest(octoWidgets, PullRequest{SourceBranch: "missing", TargetBranch: "master"})
+	if assert.IsType(t, &GitHubError{}, err) {
+		assert.Equal(t, "GitHub API responded with 422: Validation Failed", err.Error())
+	}
+}
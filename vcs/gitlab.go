@@ -0,0 +1,265 @@
+package vcs
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitLab is a client of the REST API of GitLab. The owner of a repository is the namespace of its project, groups
+// nesting as "group/subgroup", and pull requests are merge requests.
+type GitLab struct {
+	restClient
+}
+
+// GitLabError is an error response of the GitLab API.
+type GitLabError struct {
+	StatusCode int
+	Message    string
+}
+
+type gitLabMergeRequest struct {
+	IID          int64    `json:"iid"`
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	State        string   `json:"state"`
+	WebURL       string   `json:"web_url"`
+	SourceBranch string   `json:"source_branch"`
+	TargetBranch string   `json:"target_branch"`
+	Labels       []string `json:"labels"`
+	Reviewers    []struct {
+		Username string `json:"username"`
+	} `json:"reviewers"`
+}
+
+// NewGitLab returns a client of the GitLab API of an instance at a base URL such as "https://gitlab.example.com",
+// authenticated with an access token.
+func NewGitLab(client *http.Client, baseURL string, token string) *GitLab {
+	authorize := func(req *http.Request) {
+		req.Header.Set("PRIVATE-TOKEN", token)
+	}
+
+	return &GitLab{newRESTClient(client, baseURL, authorize, newGitLabError)}
+}
+
+// CreateBranch creates a branch starting at another branch or a commit.
+func (g *GitLab) CreateBranch(repo Repo, branch string, startPoint string) error {
+	body := map[string]string{"branch": branch, "ref": startPoint}
+
+	return g.doJSON("POST", g.projectPath(repo, "repository/branches"), body, nil)
+}
+
+// DeleteBranch deletes a branch.
+func (g *GitLab) DeleteBranch(repo Repo, branch string) error {
+	return g.doJSON("DELETE", g.projectPath(repo, "repository/branches")+"/"+url.PathEscape(branch), nil, nil)
+}
+
+// CommitFiles commits file changes in a single commit through the commits API.
+func (g *GitLab) CommitFiles(repo Repo, commit Commit) (string, error) {
+	var actionList []map[string]string
+
+	for _, file := range commit.Files {
+		action := "update"
+		if _, err := g.ReadFile(repo, commit.Branch, file.Path); err == ErrNotFound {
+			action = "create"
+		} else if err != nil {
+			return "", err
+		}
+
+		actionList = append(actionList, map[string]string{
+			"action":    action,
+			"file_path": file.Path,
+			"content":   base64.StdEncoding.EncodeToString(file.Content),
+			"encoding":  "base64",
+		})
+	}
+
+	body := map[string]interface{}{"branch": commit.Branch, "commit_message": commit.Message, "actions": actionList}
+	created := struct {
+		ID string `json:"id"`
+	}{}
+
+	if err := g.doJSON("POST", g.projectPath(repo, "repository/commits"), body, &created); err != nil {
+		return "", err
+	}
+
+	return created.ID, nil
+}
+
+// ReadFile returns the raw content of a file at a branch or a commit.
+func (g *GitLab) ReadFile(repo Repo, ref string, path string) ([]byte, error) {
+	query := url.Values{"ref": {ref}}
+	filePath := g.projectPath(repo, "repository/files") + "/" + url.PathEscape(path) + "/raw?" + query.Encode()
+
+	res, err := g.do("GET", filePath, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	return ioutil.ReadAll(res.Body)
+}
+
+// FindPullRequest returns the open merge request from a branch, or nil when there is none.
+func (g *GitLab) FindPullRequest(repo Repo, sourceBranch string) (*PullRequest, error) {
+	query := url.Values{"source_branch": {sourceBranch}, "state": {"opened"}}
+	var mergeRequestList []gitLabMergeRequest
+
+	if err := g.doJSON("GET", g.projectPath(repo, "merge_requests")+"?"+query.Encode(), nil, &mergeRequestList); err != nil {
+		return nil, err
+	}
+
+	if len(mergeRequestList) == 0 {
+		return nil, nil
+	}
+
+	return mergeRequestList[0].pullRequest(), nil
+}
+
+// OpenPullRequest opens a merge request with its labels and reviewers.
+func (g *GitLab) OpenPullRequest(repo Repo, pr PullRequest) (*PullRequest, error) {
+	reviewerIDList, err := g.userIDs(pr.Reviewers)
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"source_branch": pr.SourceBranch,
+		"target_branch": pr.TargetBranch,
+		"title":         pr.Title,
+		"description":   pr.Description,
+		"labels":        strings.Join(pr.Labels, ","),
+		"reviewer_ids":  reviewerIDList,
+	}
+	created := gitLabMergeRequest{}
+
+	if err := g.doJSON("POST", g.projectPath(repo, "merge_requests"), body, &created); err != nil {
+		return nil, err
+	}
+
+	return created.pullRequest(), nil
+}
+
+// UpdatePullRequest updates the title and description of an open merge request, and adds its labels. Its reviewers
+// are replaced, so the reviewers of the merge request must include the current ones.
+func (g *GitLab) UpdatePullRequest(repo Repo, pr PullRequest) (*PullRequest, error) {
+	reviewerIDList, err := g.userIDs(pr.Reviewers)
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"title":        pr.Title,
+		"description":  pr.Description,
+		"add_labels":   strings.Join(pr.Labels, ","),
+		"reviewer_ids": reviewerIDList,
+	}
+	updated := gitLabMergeRequest{}
+
+	if err := g.doJSON("PUT", g.projectPath(repo, "merge_requests", fmt.Sprint(pr.ID)), body, &updated); err != nil {
+		return nil, err
+	}
+
+	return updated.pullRequest(), nil
+}
+
+// ClosePullRequest closes a merge request.
+func (g *GitLab) ClosePullRequest(repo Repo, pr PullRequest) error {
+	body := map[string]string{"state_event": "close"}
+
+	return g.doJSON("PUT", g.projectPath(repo, "merge_requests", fmt.Sprint(pr.ID)), body, nil)
+}
+
+// userIDs returns the IDs of the users with the given names. Reviews cannot be requested from groups, and unknown
+// users are left out rather than failing the merge request.
+func (g *GitLab) userIDs(nameList []string) ([]int64, error) {
+	idList := []int64{}
+
+	for _, name := range nameList {
+		if strings.Contains(name, "/") {
+			continue
+		}
+
+		var userList []struct {
+			ID int64 `json:"id"`
+		}
+
+		if err := g.doJSON("GET", "/api/v4/users?"+url.Values{"username": {name}}.Encode(), nil, &userList); err != nil {
+			return nil, err
+		}
+
+		if len(userList) > 0 {
+			idList = append(idList, userList[0].ID)
+		}
+	}
+
+	return idList, nil
+}
+
+// projectPath returns the path of a resource of a project, the project being identified by its escaped full path.
+func (g *GitLab) projectPath(repo Repo, elemList ...string) string {
+	path := "/api/v4/projects/" + url.PathEscape(repo.Owner+"/"+repo.Name)
+
+	for _, elem := range elemList {
+		path += "/" + elem
+	}
+
+	return path
+}
+
+// newGitLabError reads the message of an error response, which is either a string or the errors of every field.
+func newGitLabError(res *http.Response) error {
+	apiErr := &GitLabError{StatusCode: res.StatusCode}
+	errorBody := struct {
+		Message json.RawMessage `json:"message"`
+		Error   string          `json:"error"`
+	}{}
+
+	if json.NewDecoder(res.Body).Decode(&errorBody) == nil {
+		apiErr.Message = errorBody.Error
+		if len(errorBody.Message) > 0 && json.Unmarshal(errorBody.Message, &apiErr.Message) != nil {
+			apiErr.Message = string(errorBody.Message)
+		}
+	}
+
+	return apiErr
+}
+
+func (e *GitLabError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("GitLab API responded with %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("GitLab API responded with %d: %s", e.StatusCode, e.Message)
+}
+
+func (mr gitLabMergeRequest) pullRequest() *PullRequest {
+	pr := &PullRequest{
+		ID:           mr.IID,
+		Title:        mr.Title,
+		Description:  mr.Description,
+		SourceBranch: mr.SourceBranch,
+		TargetBranch: mr.TargetBranch,
+		Labels:       mr.Labels,
+		URL:          mr.WebURL,
+	}
+
+	switch mr.State {
+	case "merged":
+		pr.State = StateMerged
+	case "closed":
+		pr.State = StateDeclined
+	default:
+		pr.State = StateOpen
+	}
+
+	for _, reviewer := range mr.Reviewers {
+		pr.Reviewers = append(pr.Reviewers, reviewer.Username)
+	}
+
+	return pr
+}
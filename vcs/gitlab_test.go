@@ -0,0 +1,423 @@
+package vcs
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const gitLabProjectPath = "/api/v4/projects/platform%2Fweb%2Fwidgets/"
+
+// fakeGitLab is an in-memory GitLab holding the platform/web/widgets project.
+type fakeGitLab struct {
+	mu sync.Mutex
+	// branches are the heads of the branches by name.
+	branches map[string]string
+	// trees are the files of every commit.
+	trees         map[string]map[string]string
+	commitCount   int
+	mergeRequests []*gitLabMergeRequest
+	users         map[string]int64
+}
+
+func newFakeGitLab() *fakeGitLab {
+	return &fakeGitLab{
+		branches: map[string]string{"main": stashCommitID(0)},
+		trees:    map[string]map[string]string{stashCommitID(0): {"package.json": `{"name":"widgets"}`}},
+		users:    map[string]int64{"jdoe": 7},
+	}
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+		writeGitLabError(w, http.StatusUnauthorized, `"401 Unauthorized"`)
+		return
+	}
+
+	// The project and the files are identified by escaped paths.
+	path := r.URL.EscapedPath()
+
+	if path == "/api/v4/users" && r.Method == "GET" {
+		f.listUsers(w, r)
+		return
+	}
+
+	if !strings.HasPrefix(path, gitLabProjectPath) {
+		writeGitLabError(w, http.StatusNotFound, `"404 Project Not Found"`)
+		return
+	}
+
+	resource := strings.TrimPrefix(path, gitLabProjectPath)
+	segmentList := strings.Split(resource, "/")
+
+	switch {
+	case resource == "repository/branches" && r.Method == "POST":
+		f.createBranch(w, r)
+	case strings.HasPrefix(resource, "repository/branches/") && r.Method == "DELETE":
+		branch, _ := url.PathUnescape(strings.TrimPrefix(resource, "repository/branches/"))
+		f.deleteBranch(w, branch)
+	case strings.HasPrefix(resource, "repository/files/") && strings.HasSuffix(resource, "/raw") && r.Method == "GET":
+		filePath, _ := url.PathUnescape(strings.TrimSuffix(strings.TrimPrefix(resource, "repository/files/"), "/raw"))
+		f.readFile(w, r, filePath)
+	case resource == "repository/commits" && r.Method == "POST":
+		f.createCommit(w, r)
+	case resource == "merge_requests" && r.Method == "GET":
+		f.listMergeRequests(w, r)
+	case resource == "merge_requests" && r.Method == "POST":
+		f.createMergeRequest(w, r)
+	case segmentList[0] == "merge_requests" && len(segmentList) == 2 && r.Method == "PUT":
+		f.updateMergeRequest(w, r, segmentList[1])
+	default:
+		writeGitLabError(w, http.StatusNotFound, `"404 Not Found"`)
+	}
+}
+
+func (f *fakeGitLab) listUsers(w http.ResponseWriter, r *http.Request) {
+	userList := []map[string]interface{}{}
+
+	if id, ok := f.users[r.URL.Query().Get("username")]; ok {
+		userList = append(userList, map[string]interface{}{"id": id, "username": r.URL.Query().Get("username")})
+	}
+
+	json.NewEncoder(w).Encode(userList)
+}
+
+func (f *fakeGitLab) createBranch(w http.ResponseWriter, r *http.Request) {
+	body := map[string]string{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	if _, ok := f.branches[body["branch"]]; ok {
+		writeGitLabError(w, http.StatusBadRequest, `"Branch already exists"`)
+		return
+	}
+
+	head, ok := f.branches[body["ref"]]
+	if !ok {
+		if _, ok = f.trees[body["ref"]]; !ok {
+			writeGitLabError(w, http.StatusBadRequest, `"Invalid reference name"`)
+			return
+		}
+
+		head = body["ref"]
+	}
+
+	f.branches[body["branch"]] = head
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintf(w, `{"name":%q,"commit":{"id":%q}}`, body["branch"], head)
+}
+
+func (f *fakeGitLab) deleteBranch(w http.ResponseWriter, branch string) {
+	if _, ok := f.branches[branch]; !ok {
+		writeGitLabError(w, http.StatusNotFound, `"404 Branch Not Found"`)
+		return
+	}
+
+	delete(f.branches, branch)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeGitLab) readFile(w http.ResponseWriter, r *http.Request, filePath string) {
+	ref := r.URL.Query().Get("ref")
+	if head, ok := f.branches[ref]; ok {
+		ref = head
+	}
+
+	content, ok := f.trees[ref][filePath]
+	if !ok {
+		writeGitLabError(w, http.StatusNotFound, `"404 File Not Found"`)
+		return
+	}
+
+	w.Write([]byte(content))
+}
+
+func (f *fakeGitLab) createCommit(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		Branch        string              `json:"branch"`
+		CommitMessage string              `json:"commit_message"`
+		Actions       []map[string]string `json:"actions"`
+	}{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	head, ok := f.branches[body.Branch]
+	if !ok {
+		writeGitLabError(w, http.StatusBadRequest, `"You can only create or edit files when you are on a branch"`)
+		return
+	}
+
+	tree := map[string]string{}
+	for name, content := range f.trees[head] {
+		tree[name] = content
+	}
+
+	for _, action := range body.Actions {
+		_, exists := tree[action["file_path"]]
+		if (action["action"] == "create") == exists {
+			writeGitLabError(w, http.StatusBadRequest, fmt.Sprintf(`"A file with this name %s"`, map[bool]string{true: "already exists", false: "doesn't exist"}[exists]))
+			return
+		}
+
+		content, err := base64.StdEncoding.DecodeString(action["content"])
+		if err != nil || action["encoding"] != "base64" {
+			writeGitLabError(w, http.StatusBadRequest, `"Invalid content"`)
+			return
+		}
+
+		tree[action["file_path"]] = string(content)
+	}
+
+	f.commitCount++
+	commit := stashCommitID(f.commitCount)
+	f.trees[commit] = tree
+	f.branches[body.Branch] = commit
+
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintf(w, `{"id":%q,"message":%q}`, commit, body.CommitMessage)
+}
+
+func (f *fakeGitLab) listMergeRequests(w http.ResponseWriter, r *http.Request) {
+	mergeRequestList := []*gitLabMergeRequest{}
+
+	for _, mr := range f.mergeRequests {
+		if mr.SourceBranch == r.URL.Query().Get("source_branch") && mr.State == r.URL.Query().Get("state") {
+			mergeRequestList = append(mergeRequestList, mr)
+		}
+	}
+
+	json.NewEncoder(w).Encode(mergeRequestList)
+}
+
+func (f *fakeGitLab) createMergeRequest(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		SourceBranch string  `json:"source_branch"`
+		TargetBranch string  `json:"target_branch"`
+		Title        string  `json:"title"`
+		Description  string  `json:"description"`
+		Labels       string  `json:"labels"`
+		ReviewerIDs  []int64 `json:"reviewer_ids"`
+	}{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	if _, ok := f.branches[body.SourceBranch]; !ok {
+		writeGitLabError(w, http.StatusBadRequest, `{"source_branch":["is invalid"]}`)
+		return
+	}
+
+	mr := &gitLabMergeRequest{
+		IID:          int64(len(f.mergeRequests) + 1),
+		Title:        body.Title,
+		Description:  body.Description,
+		State:        "opened",
+		SourceBranch: body.SourceBranch,
+		TargetBranch: body.TargetBranch,
+	}
+	mr.WebURL = fmt.Sprintf("https://gitlab.example.com/platform/web/widgets/-/merge_requests/%d", mr.IID)
+	f.addLabels(mr, body.Labels)
+	f.setReviewers(mr, body.ReviewerIDs)
+
+	f.mergeRequests = append(f.mergeRequests, mr)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(mr)
+}
+
+func (f *fakeGitLab) updateMergeRequest(w http.ResponseWriter, r *http.Request, iid string) {
+	var mr *gitLabMergeRequest
+
+	for _, existing := range f.mergeRequests {
+		if fmt.Sprint(existing.IID) == iid {
+			mr = existing
+		}
+	}
+
+	if mr == nil {
+		writeGitLabError(w, http.StatusNotFound, `"404 Not found"`)
+		return
+	}
+
+	body := struct {
+		Title       *string `json:"title"`
+		Description *string `json:"description"`
+		AddLabels   string  `json:"add_labels"`
+		ReviewerIDs []int64 `json:"reviewer_ids"`
+		StateEvent  string  `json:"state_event"`
+	}{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	if body.Title != nil {
+		mr.Title = *body.Title
+	}
+
+	if body.Description != nil {
+		mr.Description = *body.Description
+	}
+
+	if body.StateEvent == "close" {
+		mr.State = "closed"
+	}
+
+	f.addLabels(mr, body.AddLabels)
+	if body.ReviewerIDs != nil {
+		f.setReviewers(mr, body.ReviewerIDs)
+	}
+
+	json.NewEncoder(w).Encode(mr)
+}
+
+func (f *fakeGitLab) addLabels(mr *gitLabMergeRequest, labels string) {
+	for _, label := range strings.Split(labels, ",") {
+		if label != "" && !containsLabel(mr.Labels, label) {
+			mr.Labels = append(mr.Labels, label)
+		}
+	}
+}
+
+func (f *fakeGitLab) setReviewers(mr *gitLabMergeRequest, idList []int64) {
+	mr.Reviewers = nil
+
+	for username, id := range f.users {
+		for _, reviewerID := range idList {
+			if id == reviewerID {
+				mr.Reviewers = append(mr.Reviewers, struct {
+					Username string `json:"username"`
+				}{username})
+			}
+		}
+	}
+}
+
+func containsLabel(labelList []string, label string) bool {
+	for _, existing := range labelList {
+		if existing == label {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeGitLabError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"message":%s}`, message)
+}
+
+func newTestGitLab() (*GitLab, *fakeGitLab, func()) {
+	fake := newFakeGitLab()
+	server := httptest.NewServer(fake)
+
+	return NewGitLab(server.Client(), server.URL, "secret"), fake, server.Close
+}
+
+var webWidgets = Repo{Owner: "platform/web", Name: "widgets"}
+
+func TestGitLab_CommitFiles(t *testing.T) {
+	gitlab, fake, closeServer := newTestGitLab()
+	defer closeServer()
+
+	assert.Nil(t, gitlab.CreateBranch(webWidgets, "aufait/widgets", "main"))
+	if err := gitlab.CreateBranch(webWidgets, "aufait/widgets", "main"); assert.IsType(t, &GitLabError{}, err) {
+		assert.Equal(t, "GitLab API responded with 400: Branch already exists", err.Error())
+	}
+
+	head, err := gitlab.CommitFiles(webWidgets, Commit{
+		Branch:  "aufait/widgets",
+		Message: "Update lib to 1.2.0",
+		Files: []FileChange{
+			{Path: "package.json", Content: []byte(`{"name":"widgets","version":"1.0.0"}`)},
+			{Path: "packages/ui/package.json", Content: []byte(`{"name":"ui"}`)},
+		},
+	})
+	if assert.Nil(t, err) {
+		assert.Equal(t, stashCommitID(1), head, "a single commit")
+		assert.Equal(t, stashCommitID(0), fake.branches["main"])
+	}
+
+	read, err := gitlab.ReadFile(webWidgets, "aufait/widgets", "packages/ui/package.json")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name":"ui"}`, string(read))
+
+	read, err = gitlab.ReadFile(webWidgets, stashCommitID(0), "package.json")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name":"widgets"}`, string(read))
+
+	_, err = gitlab.ReadFile(webWidgets, "main", "packages/ui/package.json")
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.Nil(t, gitlab.DeleteBranch(webWidgets, "aufait/widgets"))
+	assert.Equal(t, ErrNotFound, gitlab.DeleteBranch(webWidgets, "aufait/widgets"))
+}
+
+func TestGitLab_PullRequests(t *testing.T) {
+	gitlab, _, closeServer := newTestGitLab()
+	defer closeServer()
+
+	assert.Nil(t, gitlab.CreateBranch(webWidgets, "aufait/widgets", "main"))
+
+	pr, err := gitlab.FindPullRequest(webWidgets, "aufait/widgets")
+	assert.Nil(t, err)
+	assert.Nil(t, pr)
+
+	opened, err := gitlab.OpenPullRequest(webWidgets, PullRequest{
+		Title:        "Update lib to 1.2.0",
+		Description:  "lib 1.2.0 was published",
+		SourceBranch: "aufait/widgets",
+		TargetBranch: "main",
+		Reviewers:    []string{"jdoe", "platform/web", "unknown"},
+		Labels:       []string{"dependencies", "automated"},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, &PullRequest{
+		ID:           1,
+		Title:        "Update lib to 1.2.0",
+		Description:  "lib 1.2.0 was published",
+		SourceBranch: "aufait/widgets",
+		TargetBranch: "main",
+		Reviewers:    []string{"jdoe"},
+		Labels:       []string{"dependencies", "automated"},
+		State:        StateOpen,
+		URL:          "https://gitlab.example.com/platform/web/widgets/-/merge_requests/1",
+	}, opened)
+
+	found, err := gitlab.FindPullRequest(webWidgets, "aufait/widgets")
+	assert.Nil(t, err)
+	assert.Equal(t, opened, found)
+
+	found.Title = "Update lib to 1.3.0"
+	found.Labels = []string{"dependencies"}
+	updated, err := gitlab.UpdatePullRequest(webWidgets, *found)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "Update lib to 1.3.0", updated.Title)
+		assert.Equal(t, []string{"dependencies", "automated"}, updated.Labels, "labels are only added")
+		assert.Equal(t, []string{"jdoe"}, updated.Reviewers)
+	}
+
+	assert.Nil(t, gitlab.ClosePullRequest(webWidgets, *updated))
+
+	pr, err = gitlab.FindPullRequest(webWidgets, "aufait/widgets")
+	assert.Nil(t, err)
+	assert.Nil(t, pr)
+
+	_, err = gitlab.UpdatePullRequest(webWidgets, PullRequest{ID: 42})
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = gitlab.OpenPullRequest(webWidgets, PullRequest{SourceBranch: "missing", TargetBranch: "main"})
+	if assert.IsType(t, &GitLabError{}, err) {
+		assert.Equal(t, `GitLab API responded with 400: {"source_branch":["is invalid"]}`, err.Error())
+	}
+
+	_, err = NewGitLab(http.DefaultClient, "http://127.0.0.1:1", "secret").FindPullRequest(webWidgets, "aufait/widgets")
+	assert.NotNil(t, err, "unreachable")
+}
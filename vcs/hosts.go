@@ -0,0 +1,68 @@
+package vcs
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Hosts are the configured version control services, the one of a repository being selected by name or by the host
+// of its remote.
+type Hosts struct {
+	hostList []host
+}
+
+type host struct {
+	name    string
+	host    string
+	service VersionControl
+}
+
+// Add adds a service reachable at a base URL under a name.
+func (h *Hosts) Add(name string, baseURL string, service VersionControl) {
+	hostName := ""
+	if u, err := url.Parse(baseURL); err == nil {
+		// The API of github.com lives on its own host.
+		hostName = strings.TrimPrefix(strings.ToLower(u.Hostname()), "api.")
+	}
+
+	h.hostList = append(h.hostList, host{name, hostName, service})
+}
+
+// Len returns the number of services.
+func (h *Hosts) Len() int {
+	return len(h.hostList)
+}
+
+// Lookup returns the service a repository is hosted on and the repository on it. A repository naming a service is
+// hosted on that service, otherwise on the service at the host of its remote. When a single service is configured,
+// every repository is hosted on it.
+func (h *Hosts) Lookup(name string, remote string) (VersionControl, Repo, error) {
+	repo, err := ParseRemote(remote)
+	if err != nil {
+		return nil, repo, err
+	}
+
+	if name != "" {
+		for _, host := range h.hostList {
+			if host.name == name {
+				return host.service, repo, nil
+			}
+		}
+
+		return nil, repo, fmt.Errorf("unknown version control service %q", name)
+	}
+
+	remoteHost := RemoteHost(remote)
+	for _, host := range h.hostList {
+		if host.host != "" && host.host == remoteHost {
+			return host.service, repo, nil
+		}
+	}
+
+	if len(h.hostList) == 1 {
+		return h.hostList[0].service, repo, nil
+	}
+
+	return nil, repo, fmt.Errorf("no version control service is configured for %s", remoteHost)
+}
@@ -0,0 +1,56 @@
+package vcs
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHosts_Lookup(t *testing.T) {
+	stash := NewStash(http.DefaultClient, "https://stash.example.com", "aufait", "secret")
+	github := NewGitHub(http.DefaultClient, "https://api.github.com", "secret")
+	gitlab := NewGitLab(http.DefaultClient, "https://gitlab.example.com", "secret")
+
+	hosts := &Hosts{}
+	hosts.Add("stash", "https://stash.example.com", stash)
+	hosts.Add("github", "https://api.github.com", github)
+	hosts.Add("gitlab", "https://gitlab.example.com", gitlab)
+	assert.Equal(t, 3, hosts.Len())
+
+	tests := []struct {
+		name    string
+		remote  string
+		service VersionControl
+		repo    Repo
+	}{
+		{"", "ssh://git@stash.example.com:7999/proj/app.git", stash, Repo{"proj", "app"}},
+		{"", "git@github.com:octo-org/widgets.git", github, Repo{"octo-org", "widgets"}},
+		{"", "https://GitLab.example.com/platform/web/widgets.git", gitlab, Repo{"platform/web", "widgets"}},
+		{"gitlab", "git@gitlab-mirror.example.com:platform/widgets.git", gitlab, Repo{"platform", "widgets"}},
+	}
+
+	for _, test := range tests {
+		service, repo, err := hosts.Lookup(test.name, test.remote)
+		if assert.Nil(t, err, test.remote) {
+			assert.True(t, service == test.service, test.remote)
+			assert.Equal(t, test.repo, repo, test.remote)
+		}
+	}
+
+	_, _, err := hosts.Lookup("bitbucket", "git@github.com:octo-org/widgets.git")
+	assert.NotNil(t, err, "unknown service")
+
+	_, _, err = hosts.Lookup("", "git@git.example.com:octo-org/widgets.git")
+	assert.NotNil(t, err, "unknown host")
+
+	_, _, err = hosts.Lookup("github", "stuff")
+	assert.NotNil(t, err, "invalid remote")
+
+	// a single service hosts every repository
+	single := &Hosts{}
+	single.Add("stash", "https://stash.example.com", stash)
+	service, _, err := single.Lookup("", "git@git.example.com:octo-org/widgets.git")
+	assert.Nil(t, err)
+	assert.True(t, service == stash)
+}
@@ -0,0 +1,81 @@
+package vcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// restClient sends requests to the REST API of a service.
+type restClient struct {
+	client  *http.Client
+	baseURL string
+	// authorize adds the credentials and the headers the service requires to a request.
+	authorize func(req *http.Request)
+	// newError turns an error response other than a 404 into an error.
+	newError func(res *http.Response) error
+}
+
+func newRESTClient(client *http.Client, baseURL string, authorize func(*http.Request), newError func(*http.Response) error) restClient {
+	return restClient{client, strings.TrimSuffix(baseURL, "/"), authorize, newError}
+}
+
+// doJSON sends a request with a JSON body, if any, and decodes the JSON response into out, if any.
+func (c *restClient) doJSON(method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	contentType := ""
+
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(payload)
+		contentType = "application/json"
+	}
+
+	res, err := c.do(method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// do sends an authenticated request and turns error responses into errors, a 404 into ErrNotFound.
+func (c *restClient) do(method string, path string, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	c.authorize(req)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	return nil, c.newError(res)
+}
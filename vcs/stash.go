@@ -0,0 +1,362 @@
+package vcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Base paths of the Stash APIs, branches being deleted through the branch utilities rather than the core API.
+const (
+	stashCoreAPI   = "/rest/api/1.0"
+	stashBranchAPI = "/rest/branch-utils/1.0"
+)
+
+// Stash is a client of the REST API of Bitbucket Server, formerly Stash. The owner of a repository is the key of its
+// project and the name is its slug.
+type Stash struct {
+	restClient
+}
+
+// StashError is an error response of the Stash API.
+type StashError struct {
+	StatusCode int
+	Messages   []string
+}
+
+type stashRef struct {
+	ID           string `json:"id"`
+	DisplayID    string `json:"displayId,omitempty"`
+	LatestCommit string `json:"latestCommit,omitempty"`
+}
+
+type stashUser struct {
+	Name string `json:"name"`
+}
+
+type stashReviewer struct {
+	User stashUser `json:"user"`
+}
+
+type stashPullRequest struct {
+	ID          int64           `json:"id,omitempty"`
+	Version     int             `json:"version"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	State       string          `json:"state,omitempty"`
+	FromRef     *stashRef       `json:"fromRef,omitempty"`
+	ToRef       *stashRef       `json:"toRef,omitempty"`
+	Reviewers   []stashReviewer `json:"reviewers"`
+	Links       struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+// NewStash returns a client of the Stash API at a base URL such as "https://stash.example.com", authenticated as the
+// user clientID with clientSecret, its password or a personal access token.
+func NewStash(client *http.Client, baseURL string, clientID string, clientSecret string) *Stash {
+	authorize := func(req *http.Request) {
+		req.SetBasicAuth(clientID, clientSecret)
+		// Stash refuses requests that could come from a browser form unless they opt out of its XSRF check.
+		req.Header.Set("X-Atlassian-Token", "no-check")
+	}
+
+	return &Stash{newRESTClient(client, baseURL, authorize, newStashError)}
+}
+
+// CreateBranch creates a branch starting at another branch or a commit.
+func (s *Stash) CreateBranch(repo Repo, branch string, startPoint string) error {
+	body := map[string]string{"name": branch, "startPoint": stashRefID(startPoint)}
+
+	return s.doJSON("POST", s.repoPath(stashCoreAPI, repo, "branches"), body, nil)
+}
+
+// DeleteBranch deletes a branch.
+func (s *Stash) DeleteBranch(repo Repo, branch string) error {
+	body := map[string]interface{}{"name": stashRefID(branch), "dryRun": false}
+
+	return s.doJSON("DELETE", s.repoPath(stashBranchAPI, repo, "branches"), body, nil)
+}
+
+// CommitFiles commits file changes on top of a branch. The Stash API edits one file per commit, so every file is
+// committed on top of the previous one, and the branch moving in between fails the commit with a 409.
+func (s *Stash) CommitFiles(repo Repo, commit Commit) (string, error) {
+	head, err := s.branchHead(repo, commit.Branch)
+	if err != nil {
+		return "", err
+	}
+
+	for _, file := range commit.Files {
+		// Edits of existing files must name the commit they are based on, new files must not.
+		sourceCommit := head
+		if _, err := s.ReadFile(repo, head, file.Path); err == ErrNotFound {
+			sourceCommit = ""
+		} else if err != nil {
+			return "", err
+		}
+
+		head, err = s.editFile(repo, commit, file, sourceCommit)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return head, nil
+}
+
+// ReadFile returns the raw content of a file at a branch or a commit.
+func (s *Stash) ReadFile(repo Repo, ref string, path string) ([]byte, error) {
+	query := url.Values{"at": {stashRefID(ref)}}
+	res, err := s.do("GET", s.repoPath(stashCoreAPI, repo, "raw", path)+"?"+query.Encode(), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	return ioutil.ReadAll(res.Body)
+}
+
+// FindPullRequest returns the open pull request from a branch, or nil when there is none.
+func (s *Stash) FindPullRequest(repo Repo, sourceBranch string) (*PullRequest, error) {
+	query := url.Values{"at": {stashRefID(sourceBranch)}, "direction": {"OUTGOING"}, "state": {"OPEN"}}
+	page := struct {
+		Values []stashPullRequest `json:"values"`
+	}{}
+
+	if err := s.doJSON("GET", s.repoPath(stashCoreAPI, repo, "pull-requests")+"?"+query.Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+
+	if len(page.Values) == 0 {
+		return nil, nil
+	}
+
+	return page.Values[0].pullRequest(), nil
+}
+
+// OpenPullRequest opens a pull request.
+func (s *Stash) OpenPullRequest(repo Repo, pr PullRequest) (*PullRequest, error) {
+	body := newStashPullRequest(pr)
+	body.FromRef = &stashRef{ID: stashRefID(pr.SourceBranch)}
+	body.ToRef = &stashRef{ID: stashRefID(pr.TargetBranch)}
+
+	created := stashPullRequest{}
+	if err := s.doJSON("POST", s.repoPath(stashCoreAPI, repo, "pull-requests"), body, &created); err != nil {
+		return nil, err
+	}
+
+	return created.pullRequest(), nil
+}
+
+// UpdatePullRequest updates the title, description and reviewers of an open pull request. Stash has no labels. The version of the pull
+// request must be the current one, Stash rejects updates of outdated versions with a 409.
+func (s *Stash) UpdatePullRequest(repo Repo, pr PullRequest) (*PullRequest, error) {
+	updated := stashPullRequest{}
+	path := s.repoPath(stashCoreAPI, repo, "pull-requests", fmt.Sprint(pr.ID))
+
+	if err := s.doJSON("PUT", path, newStashPullRequest(pr), &updated); err != nil {
+		return nil, err
+	}
+
+	return updated.pullRequest(), nil
+}
+
+// ClosePullRequest declines a pull request.
+func (s *Stash) ClosePullRequest(repo Repo, pr PullRequest) error {
+	query := url.Values{"version": {fmt.Sprint(pr.Version)}}
+	path := s.repoPath(stashCoreAPI, repo, "pull-requests", fmt.Sprint(pr.ID), "decline") + "?" + query.Encode()
+
+	return s.doJSON("POST", path, struct{}{}, nil)
+}
+
+// branchHead returns the commit a branch points to.
+func (s *Stash) branchHead(repo Repo, branch string) (string, error) {
+	query := url.Values{"filterText": {branch}, "start": {"0"}}
+
+	for {
+		page := struct {
+			Values        []stashRef `json:"values"`
+			IsLastPage    bool       `json:"isLastPage"`
+			NextPageStart int        `json:"nextPageStart"`
+		}{}
+
+		if err := s.doJSON("GET", s.repoPath(stashCoreAPI, repo, "branches")+"?"+query.Encode(), nil, &page); err != nil {
+			return "", err
+		}
+
+		// The filter matches parts of branch names, so "main" also finds "maintenance" and the branch may be on a
+		// later page when many branches share its name.
+		for _, ref := range page.Values {
+			if ref.ID == stashRefID(branch) {
+				return ref.LatestCommit, nil
+			}
+		}
+
+		if page.IsLastPage || len(page.Values) == 0 {
+			return "", ErrNotFound
+		}
+
+		query.Set("start", fmt.Sprint(page.NextPageStart))
+	}
+}
+
+// editFile commits the new content of a file and returns the ID of the commit.
+func (s *Stash) editFile(repo Repo, commit Commit, file FileChange, sourceCommit string) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	fieldList := [][2]string{{"branch", commit.Branch}, {"message", commit.Message}, {"sourceCommitId", sourceCommit}}
+	for _, field := range fieldList {
+		if field[1] == "" {
+			continue
+		}
+
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return "", err
+		}
+	}
+
+	content, err := form.CreateFormFile("content", file.Path)
+	if err == nil {
+		_, err = content.Write(file.Content)
+	}
+	if err == nil {
+		err = form.Close()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	res, err := s.do("PUT", s.repoPath(stashCoreAPI, repo, "browse", file.Path), form.FormDataContentType(), &body)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	created := struct {
+		ID string `json:"id"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&created); err != nil {
+		return "", err
+	}
+
+	return created.ID, nil
+}
+
+// repoPath returns the path of a resource of a repository in one of the APIs.
+func (s *Stash) repoPath(api string, repo Repo, elemList ...string) string {
+	path := fmt.Sprintf("%s/projects/%s/repos/%s", api, url.PathEscape(repo.Owner), url.PathEscape(repo.Name))
+
+	for _, elem := range elemList {
+		for _, segment := range strings.Split(elem, "/") {
+			path += "/" + url.PathEscape(segment)
+		}
+	}
+
+	return path
+}
+
+// newStashError reads the messages of an error response.
+func newStashError(res *http.Response) error {
+	apiErr := &StashError{StatusCode: res.StatusCode}
+	errorBody := struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+
+	if json.NewDecoder(res.Body).Decode(&errorBody) == nil {
+		for _, e := range errorBody.Errors {
+			apiErr.Messages = append(apiErr.Messages, e.Message)
+		}
+	}
+
+	return apiErr
+}
+
+func (e *StashError) Error() string {
+	if len(e.Messages) == 0 {
+		return fmt.Sprintf("Stash API responded with %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("Stash API responded with %d: %s", e.StatusCode, strings.Join(e.Messages, "; "))
+}
+
+func newStashPullRequest(pr PullRequest) stashPullRequest {
+	body := stashPullRequest{
+		Version:     pr.Version,
+		Title:       pr.Title,
+		Description: pr.Description,
+		Reviewers:   []stashReviewer{},
+	}
+
+	for _, name := range pr.Reviewers {
+		// Stash has no teams to request reviews from.
+		if strings.Contains(name, "/") {
+			continue
+		}
+
+		body.Reviewers = append(body.Reviewers, stashReviewer{User: stashUser{Name: name}})
+	}
+
+	return body
+}
+
+func (pr stashPullRequest) pullRequest() *PullRequest {
+	converted := &PullRequest{
+		ID:          pr.ID,
+		Version:     pr.Version,
+		Title:       pr.Title,
+		Description: pr.Description,
+		State:       strings.ToLower(pr.State),
+	}
+
+	if pr.FromRef != nil {
+		converted.SourceBranch = pr.FromRef.DisplayID
+	}
+
+	if pr.ToRef != nil {
+		converted.TargetBranch = pr.ToRef.DisplayID
+	}
+
+	for _, reviewer := range pr.Reviewers {
+		converted.Reviewers = append(converted.Reviewers, reviewer.User.Name)
+	}
+
+	if len(pr.Links.Self) > 0 {
+		converted.URL = pr.Links.Self[0].Href
+	}
+
+	return converted
+}
+
+// stashRefID returns the full name of a branch, leaving commits and full names alone.
+func stashRefID(ref string) string {
+	if strings.HasPrefix(ref, "refs/") || isCommitID(ref) {
+		return ref
+	}
+
+	return "refs/heads/" + ref
+}
+
+// isCommitID returns whether a ref is a full commit hash rather than a branch.
+func isCommitID(ref string) bool {
+	if len(ref) != 40 {
+		return false
+	}
+
+	for _, c := range ref {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,481 @@
+package vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	stashRepoPath       = "/rest/api/1.0/projects/PROJ/repos/widgets/"
+	stashBranchRepoPath = "/rest/branch-utils/1.0/projects/PROJ/repos/widgets/"
+)
+
+// fakeStash is an in-memory Bitbucket Server holding the PROJ/widgets repository.
+type fakeStash struct {
+	mu sync.Mutex
+	// branches are the heads of the branches by full name.
+	branches map[string]string
+	// trees are the files of every commit.
+	trees        map[string]map[string]string
+	commitCount  int
+	pullRequests []*stashPullRequest
+}
+
+func newFakeStash() *fakeStash {
+	return &fakeStash{
+		branches: map[string]string{"refs/heads/master": stashCommitID(0)},
+		trees:    map[string]map[string]string{stashCommitID(0): {"package.json": `{"name":"widgets"}`}},
+	}
+}
+
+func (f *fakeStash) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if user, password, _ := r.BasicAuth(); user != "listener" || password != "secret" {
+		writeStashError(w, http.StatusUnauthorized, "Authentication failed")
+		return
+	}
+
+	if r.Method != "GET" && r.Header.Get("X-Atlassian-Token") != "no-check" {
+		writeStashError(w, http.StatusForbidden, "XSRF check failed")
+		return
+	}
+
+	if r.URL.Path == stashBranchRepoPath+"branches" && r.Method == "DELETE" {
+		f.deleteBranch(w, r)
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, stashRepoPath) {
+		writeStashError(w, http.StatusNotFound, "Repository does not exist")
+		return
+	}
+
+	resource := strings.Split(strings.TrimPrefix(r.URL.Path, stashRepoPath), "/")
+
+	switch {
+	case resource[0] == "branches" && r.Method == "GET":
+		f.listBranches(w, r)
+	case resource[0] == "branches" && r.Method == "POST":
+		f.createBranch(w, r)
+	case resource[0] == "raw" && r.Method == "GET":
+		f.readFile(w, r, strings.Join(resource[1:], "/"))
+	case resource[0] == "browse" && r.Method == "PUT":
+		f.editFile(w, r, strings.Join(resource[1:], "/"))
+	case resource[0] == "pull-requests" && len(resource) == 1 && r.Method == "GET":
+		f.listPullRequests(w, r)
+	case resource[0] == "pull-requests" && len(resource) == 1 && r.Method == "POST":
+		f.createPullRequest(w, r)
+	case resource[0] == "pull-requests" && len(resource) == 2 && r.Method == "PUT":
+		f.updatePullRequest(w, r, resource[1])
+	case resource[0] == "pull-requests" && len(resource) == 3 && resource[2] == "decline" && r.Method == "POST":
+		f.declinePullRequest(w, r, resource[1])
+	default:
+		writeStashError(w, http.StatusNotFound, "Unknown resource")
+	}
+}
+
+// listBranches pages through the matching branches in the order of their names, 25 at a time like Bitbucket Server.
+func (f *fakeStash) listBranches(w http.ResponseWriter, r *http.Request) {
+	valueList := []stashRef{}
+
+	for id, head := range f.branches {
+		name := strings.TrimPrefix(id, "refs/heads/")
+		if strings.Contains(name, r.URL.Query().Get("filterText")) {
+			valueList = append(valueList, stashRef{ID: id, DisplayID: name, LatestCommit: head})
+		}
+	}
+
+	sort.Slice(valueList, func(i, j int) bool { return valueList[i].ID < valueList[j].ID })
+
+	start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+	end := start + 25
+	if end >= len(valueList) {
+		end = len(valueList)
+	}
+	if start > end {
+		start = end
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"values":        valueList[start:end],
+		"isLastPage":    end == len(valueList),
+		"nextPageStart": end,
+	})
+}
+
+func (f *fakeStash) createBranch(w http.ResponseWriter, r *http.Request) {
+	body := map[string]string{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	id := "refs/heads/" + body["name"]
+	if _, ok := f.branches[id]; ok {
+		writeStashError(w, http.StatusConflict, "Branch already exists")
+		return
+	}
+
+	head, ok := f.branches[body["startPoint"]]
+	if !ok {
+		head = body["startPoint"]
+	}
+
+	f.branches[id] = head
+	json.NewEncoder(w).Encode(stashRef{ID: id, DisplayID: body["name"], LatestCommit: head})
+}
+
+func (f *fakeStash) deleteBranch(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		Name   string `json:"name"`
+		DryRun bool   `json:"dryRun"`
+	}{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	if _, ok := f.branches[body.Name]; !ok {
+		writeStashError(w, http.StatusNotFound, "Branch does not exist")
+		return
+	}
+
+	if !body.DryRun {
+		delete(f.branches, body.Name)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeStash) readFile(w http.ResponseWriter, r *http.Request, path string) {
+	at := r.URL.Query().Get("at")
+	if head, ok := f.branches[at]; ok {
+		at = head
+	}
+
+	content, ok := f.trees[at][path]
+	if !ok {
+		writeStashError(w, http.StatusNotFound, "The path does not exist")
+		return
+	}
+
+	fmt.Fprint(w, content)
+}
+
+func (f *fakeStash) editFile(w http.ResponseWriter, r *http.Request, path string) {
+	branch := "refs/heads/" + r.FormValue("branch")
+	head, ok := f.branches[branch]
+	if !ok {
+		writeStashError(w, http.StatusNotFound, "Branch does not exist")
+		return
+	}
+
+	_, exists := f.trees[head][path]
+	if source := r.FormValue("sourceCommitId"); (exists && source != head) || (!exists && source != "") {
+		writeStashError(w, http.StatusConflict, "The file has been modified since "+source)
+		return
+	}
+
+	file, _, err := r.FormFile("content")
+	if err != nil || r.FormValue("message") == "" {
+		writeStashError(w, http.StatusBadRequest, "Content and message are required")
+		return
+	}
+	content, _ := ioutil.ReadAll(file)
+
+	f.commitCount++
+	commit := stashCommitID(f.commitCount)
+	f.trees[commit] = map[string]string{path: string(content)}
+	for name, old := range f.trees[head] {
+		if name != path {
+			f.trees[commit][name] = old
+		}
+	}
+
+	f.branches[branch] = commit
+	json.NewEncoder(w).Encode(map[string]string{"id": commit})
+}
+
+func (f *fakeStash) listPullRequests(w http.ResponseWriter, r *http.Request) {
+	valueList := []*stashPullRequest{}
+
+	for _, pr := range f.pullRequests {
+		if pr.FromRef.ID == r.URL.Query().Get("at") && pr.State == r.URL.Query().Get("state") {
+			valueList = append(valueList, pr)
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"values": valueList})
+}
+
+func (f *fakeStash) createPullRequest(w http.ResponseWriter, r *http.Request) {
+	pr := &stashPullRequest{}
+	json.NewDecoder(r.Body).Decode(pr)
+
+	for _, other := range f.pullRequests {
+		if other.State == "OPEN" && other.FromRef.ID == pr.FromRef.ID && other.ToRef.ID == pr.ToRef.ID {
+			writeStashError(w, http.StatusConflict, "Only one pull request may be open for a given source and target branch")
+			return
+		}
+	}
+
+	pr.ID = int64(len(f.pullRequests) + 1)
+	pr.State = "OPEN"
+	pr.FromRef.DisplayID = strings.TrimPrefix(pr.FromRef.ID, "refs/heads/")
+	pr.ToRef.DisplayID = strings.TrimPrefix(pr.ToRef.ID, "refs/heads/")
+	pr.Links.Self = append(pr.Links.Self, struct {
+		Href string `json:"href"`
+	}{fmt.Sprintf("https://stash.example.com/projects/PROJ/repos/widgets/pull-requests/%d", pr.ID)})
+
+	f.pullRequests = append(f.pullRequests, pr)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(pr)
+}
+
+func (f *fakeStash) updatePullRequest(w http.ResponseWriter, r *http.Request, id string) {
+	update := stashPullRequest{}
+	json.NewDecoder(r.Body).Decode(&update)
+
+	pr := f.pullRequest(w, id, update.Version)
+	if pr == nil {
+		return
+	}
+
+	pr.Version++
+	pr.Title, pr.Description, pr.Reviewers = update.Title, update.Description, update.Reviewers
+	json.NewEncoder(w).Encode(pr)
+}
+
+func (f *fakeStash) declinePullRequest(w http.ResponseWriter, r *http.Request, id string) {
+	version, _ := strconv.Atoi(r.URL.Query().Get("version"))
+
+	pr := f.pullRequest(w, id, version)
+	if pr == nil {
+		return
+	}
+
+	pr.Version++
+	pr.State = "DECLINED"
+	json.NewEncoder(w).Encode(pr)
+}
+
+// pullRequest returns the pull request with an ID, writing an error when it does not exist or is at another version.
+func (f *fakeStash) pullRequest(w http.ResponseWriter, id string, version int) *stashPullRequest {
+	for _, pr := range f.pullRequests {
+		if fmt.Sprint(pr.ID) != id {
+			continue
+		}
+
+		if pr.Version != version {
+			writeStashError(w, http.StatusConflict, "You are attempting to modify a pull request based on out-of-date information")
+			return nil
+		}
+
+		return pr
+	}
+
+	writeStashError(w, http.StatusNotFound, "Pull request does not exist")
+
+	return nil
+}
+
+// stashCommitID returns the ID of the nth commit of the fake, a 40 digit hash like real commit IDs.
+func stashCommitID(n int) string {
+	return fmt.Sprintf("%040x", n)
+}
+
+func writeStashError(w http.ResponseWriter, status int, message string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"errors":[{"message":%q}]}`, message)
+}
+
+func newTestStash() (*Stash, *fakeStash, func()) {
+	fake := newFakeStash()
+	server := httptest.NewServer(fake)
+
+	return NewStash(server.Client(), server.URL+"/", "listener", "secret"), fake, server.Close
+}
+
+var widgets = Repo{Owner: "PROJ", Name: "widgets"}
+
+func TestStash_CommitFiles(t *testing.T) {
+	stash, fake, closeServer := newTestStash()
+	defer closeServer()
+
+	assert.Nil(t, stash.CreateBranch(widgets, "aufait/lib-1.2.0", "master"))
+	assert.NotNil(t, stash.CreateBranch(widgets, "aufait/lib-1.2.0", "master"), "branch exists")
+
+	commit := Commit{
+		Branch:  "aufait/lib-1.2.0",
+		Message: "Update lib to 1.2.0",
+		Files: []FileChange{
+			{Path: "package.json", Content: []byte(`{"name":"widgets","dependencies":{"lib":"^1.2.0"}}`)},
+			{Path: "packages/ui/package.json", Content: []byte(`{"name":"ui"}`)},
+		},
+	}
+
+	head, err := stash.CommitFiles(widgets, commit)
+	if assert.Nil(t, err) {
+		assert.Equal(t, stashCommitID(2), head)
+		assert.Equal(t, stashCommitID(2), fake.branches["refs/heads/aufait/lib-1.2.0"])
+		assert.Equal(t, stashCommitID(0), fake.branches["refs/heads/master"])
+	}
+
+	content, err := stash.ReadFile(widgets, "aufait/lib-1.2.0", "package.json")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name":"widgets","dependencies":{"lib":"^1.2.0"}}`, string(content))
+
+	content, err = stash.ReadFile(widgets, "aufait/lib-1.2.0", "packages/ui/package.json")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name":"ui"}`, string(content))
+
+	content, err = stash.ReadFile(widgets, "master", "package.json")
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name":"widgets"}`, string(content))
+
+	_, err = stash.ReadFile(widgets, "master", "packages/ui/package.json")
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = stash.CommitFiles(widgets, Commit{Branch: "missing", Message: "m", Files: commit.Files})
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.Nil(t, stash.DeleteBranch(widgets, "aufait/lib-1.2.0"))
+	assert.Equal(t, ErrNotFound, stash.DeleteBranch(widgets, "aufait/lib-1.2.0"))
+	assert.NotContains(t, fake.branches, "refs/heads/aufait/lib-1.2.0")
+}
+
+func TestStash_CommitFiles_Paginated(t *testing.T) {
+	stash, fake, closeServer := newTestStash()
+	defer closeServer()
+
+	// The branches of other updates match the filter too and come first.
+	for i := 0; i < 30; i++ {
+		fake.branches[fmt.Sprintf("refs/heads/aufait/a-lib-%02d", i)] = stashCommitID(0)
+	}
+	assert.Nil(t, stash.CreateBranch(widgets, "lib", "master"))
+
+	commit := Commit{Branch: "lib", Message: "Update lib", Files: []FileChange{{Path: "package.json", Content: []byte(`{}`)}}}
+
+	head, err := stash.CommitFiles(widgets, commit)
+	if assert.Nil(t, err) {
+		assert.Equal(t, head, fake.branches["refs/heads/lib"])
+	}
+}
+
+func TestStash_PullRequests(t *testing.T) {
+	stash, _, closeServer := newTestStash()
+	defer closeServer()
+
+	assert.Nil(t, stash.CreateBranch(widgets, "aufait/lib-1.2.0", "master"))
+
+	pr, err := stash.FindPullRequest(widgets, "aufait/lib-1.2.0")
+	assert.Nil(t, err)
+	assert.Nil(t, pr)
+
+	opened, err := stash.OpenPullRequest(widgets, PullRequest{
+		Title:        "Update lib to 1.2.0",
+		Description:  "lib 1.2.0 was published",
+		SourceBranch: "aufait/lib-1.2.0",
+		TargetBranch: "master",
+		Reviewers:    []string{"jdoe"},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, int64(1), opened.ID)
+	assert.Equal(t, StateOpen, opened.State)
+	assert.Equal(t, "aufait/lib-1.2.0", opened.SourceBranch)
+	assert.Equal(t, "master", opened.TargetBranch)
+	assert.Equal(t, []string{"jdoe"}, opened.Reviewers)
+	assert.Equal(t, "https://stash.example.com/projects/PROJ/repos/widgets/pull-requests/1", opened.URL)
+
+	_, err = stash.OpenPullRequest(widgets, PullRequest{Title: "Again", SourceBranch: "aufait/lib-1.2.0", TargetBranch: "master"})
+	if assert.IsType(t, &StashError{}, err) {
+		assert.Equal(t, http.StatusConflict, err.(*StashError).StatusCode)
+		assert.Contains(t, err.Error(), "Only one pull request may be open")
+	}
+
+	found, err := stash.FindPullRequest(widgets, "aufait/lib-1.2.0")
+	assert.Nil(t, err)
+	assert.Equal(t, opened, found)
+
+	found.Title = "Update lib to 1.3.0"
+	updated, err := stash.UpdatePullRequest(widgets, *found)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "Update lib to 1.3.0", updated.Title)
+		assert.Equal(t, 1, updated.Version)
+	}
+
+	_, err = stash.UpdatePullRequest(widgets, *found)
+	assert.IsType(t, &StashError{}, err, "outdated version")
+
+	assert.Nil(t, stash.ClosePullRequest(widgets, *updated))
+
+	pr, err = stash.FindPullRequest(widgets, "aufait/lib-1.2.0")
+	assert.Nil(t, err)
+	assert.Nil(t, pr)
+
+	assert.Equal(t, ErrNotFound, stash.ClosePullRequest(widgets, PullRequest{ID: 42}))
+}
+
+func TestStash_Unauthorized(t *testing.T) {
+	fake := newFakeStash()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	stash := NewStash(server.Client(), server.URL, "listener", "wrong")
+
+	_, err := stash.ReadFile(widgets, "master", "package.json")
+	if assert.IsType(t, &StashError{}, err) {
+		assert.Equal(t, http.StatusUnauthorized, err.(*StashError).StatusCode)
+		assert.Equal(t, []string{"Authentication failed"}, err.(*StashError).Messages)
+	}
+
+	_, err = stash.ReadFile(Repo{Owner: "OTHER", Name: "widgets"}, "master", "package.json")
+	assert.NotNil(t, err)
+}
+
+func TestNew(t *testing.T) {
+	client, err := New("stash", "https://stash.example.com", "listener", "secret")
+	assert.Nil(t, err)
+	assert.IsType(t, &Stash{}, client)
+
+	_, err = New("hipchat", "https://stash.example.com", "listener", "secret")
+	assert.NotNil(t, err)
+
+	_, err = New("stash", "", "listener", "secret")
+	assert.NotNil(t, err)
+}
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		remote string
+		repo   Repo
+		valid  bool
+	}{
+		{"ssh://git@stash.example.com:7999/proj/widgets.git", Repo{"proj", "widgets"}, true},
+		{"https://stash.example.com/scm/proj/widgets.git", Repo{"proj", "widgets"}, true},
+		{"git@github.com:octo-org/widgets.git", Repo{"octo-org", "widgets"}, true},
+		{"https://github.com/octo-org/widgets/", Repo{"octo-org", "widgets"}, true},
+		{"https://gitlab.com/group/subgroup/widgets.git", Repo{"group/subgroup", "widgets"}, true},
+		{"https://github.com/widgets", Repo{}, false},
+		{"", Repo{}, false},
+	}
+
+	for _, test := range tests {
+		repo, err := ParseRemote(test.remote)
+		if test.valid {
+			assert.Nil(t, err, test.remote)
+			assert.Equal(t, test.repo, repo, test.remote)
+		} else {
+			assert.NotNil(t, err, test.remote)
+		}
+	}
+}
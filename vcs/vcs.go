@@ -0,0 +1,163 @@
+// Package vcs talks to the code hosts the registered repositories live on, to push the changes of update jobs and open
+// pull requests with them.
+package vcs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Service names of the supported version control services.
+const (
+	// ServiceStash is Bitbucket Server, formerly Stash.
+	ServiceStash = "stash"
+	// ServiceGitHub is GitHub or GitHub Enterprise.
+	ServiceGitHub = "github"
+	// ServiceGitLab is GitLab.
+	ServiceGitLab = "gitlab"
+)
+
+// Pull request states, shared by every service.
+const (
+	StateOpen     = "open"
+	StateMerged   = "merged"
+	StateDeclined = "declined"
+)
+
+// ErrNotFound is returned when a repository, a branch, a file or a pull request does not exist.
+var ErrNotFound = errors.New("not found")
+
+// VersionControl is a code host the listener pushes changes to.
+type VersionControl interface {
+	// CreateBranch creates a branch starting at another branch or a commit.
+	CreateBranch(repo Repo, branch string, startPoint string) error
+	// DeleteBranch deletes a branch, or returns ErrNotFound when it does not exist.
+	DeleteBranch(repo Repo, branch string) error
+	// CommitFiles commits file changes on top of a branch and returns the ID of the new head of the branch.
+	CommitFiles(repo Repo, commit Commit) (string, error)
+	// ReadFile returns the content of a file at a branch or a commit, or ErrNotFound when the file does not exist.
+	ReadFile(repo Repo, ref string, path string) ([]byte, error)
+	// FindPullRequest returns the open pull request from a branch, or nil when there is none.
+	FindPullRequest(repo Repo, sourceBranch string) (*PullRequest, error)
+	// OpenPullRequest opens a pull request and returns it as the service created it.
+	OpenPullRequest(repo Repo, pr PullRequest) (*PullRequest, error)
+	// UpdatePullRequest updates the title and description of an open pull request, and adds its labels and reviewers.
+	UpdatePullRequest(repo Repo, pr PullRequest) (*PullRequest, error)
+	// ClosePullRequest closes a pull request without merging it.
+	ClosePullRequest(repo Repo, pr PullRequest) error
+}
+
+// Repo identifies a repository on a code host, by the project or owner it belongs to and its name.
+type Repo struct {
+	Owner string
+	Name  string
+}
+
+// Commit is a set of file changes to commit on a branch.
+type Commit struct {
+	Branch  string
+	Message string
+	Files   []FileChange
+}
+
+// FileChange is the new content of a file, created when it does not exist.
+type FileChange struct {
+	Path    string
+	Content []byte
+}
+
+// PullRequest is a pull request between two branches of a repository.
+type PullRequest struct {
+	ID           int64
+	Title        string
+	Description  string
+	SourceBranch string
+	TargetBranch string
+	// Reviewers are users, or teams as "org/team" on services that have them.
+	Reviewers []string
+	// Labels are only supported by services that have them.
+	Labels []string
+	State  string
+	URL    string
+	// Version is the revision of the pull request services such as Stash use to reject concurrent updates.
+	Version int
+}
+
+// Timeout is how long the clients New returns wait for a response, pull requests being opened while a worker waits
+// for its job to complete.
+const Timeout = 30 * time.Second
+
+// New returns the client of a version control service as the versionControl section of the config describes it.
+func New(serviceName string, baseURL string, clientID string, clientSecret string) (VersionControl, error) {
+	if _, err := url.Parse(baseURL); err != nil || baseURL == "" {
+		return nil, fmt.Errorf("invalid %s URL %q", serviceName, baseURL)
+	}
+
+	client := &http.Client{Timeout: Timeout}
+
+	switch serviceName {
+	case ServiceStash:
+		return NewStash(client, baseURL, clientID, clientSecret), nil
+	case ServiceGitHub:
+		return NewGitHub(client, baseURL, clientSecret), nil
+	case ServiceGitLab:
+		return NewGitLab(client, baseURL, clientSecret), nil
+	}
+
+	return nil, fmt.Errorf("unknown version control service %q", serviceName)
+}
+
+// RemoteHost returns the host name of a git remote, whether it is cloned over SSH or HTTPS.
+func RemoteHost(remote string) string {
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" {
+		return strings.ToLower(u.Hostname())
+	}
+
+	// scp-like syntax, "git@host:owner/name".
+	host := remote
+	if i := strings.Index(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+
+	return strings.ToLower(host)
+}
+
+// ParseRemote returns the repository a git remote points to, whether it is cloned over SSH or HTTPS. The "/scm" prefix
+// of Stash HTTPS remotes is skipped, so "ssh://git@stash:7999/proj/widgets.git" and
+// "https://stash/scm/proj/widgets.git" both point to widgets of proj.
+func ParseRemote(remote string) (Repo, error) {
+	path := strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+
+	if u, err := url.Parse(path); err == nil && u.Scheme != "" {
+		path = u.Path
+	} else if i := strings.Index(path, ":"); i >= 0 {
+		// scp-like syntax, "git@host:owner/name".
+		path = path[i+1:]
+	}
+
+	partList := strings.Split(strings.Trim(path, "/"), "/")
+	if len(partList) > 2 && partList[0] == "scm" {
+		partList = partList[1:]
+	}
+
+	last := len(partList) - 1
+	if last < 1 || partList[0] == "" || partList[last] == "" {
+		return Repo{}, fmt.Errorf("remote %q does not point to a repository", remote)
+	}
+
+	// Groups nest on GitLab, so the owner is everything before the name.
+	return Repo{Owner: strings.Join(partList[:last], "/"), Name: partList[last]}, nil
+}
+
+// String returns the repository as "owner/name".
+func (r Repo) String() string {
+	return r.Owner + "/" + r.Name
+}